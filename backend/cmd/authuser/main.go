@@ -0,0 +1,96 @@
+// authuser administra los usuarios del dashboard (AUTH_USERS_FILE) y genera API keys para
+// AUTH_API_KEYS. Se ejecuta desde backend/; la contraseña se lee de stdin para que no quede
+// en el historial de la shell:
+//
+//	go run ./cmd/authuser -user ana -role specialist -specialist sp-ana   # pide la contraseña
+//	go run ./cmd/authuser -user ana -disable                              # bloquea el usuario
+//	go run ./cmd/authuser -user admin -token                              # JWT para pruebas con curl
+//	go run ./cmd/authuser -apikey whatsapp-bot -role channel              # entrada para AUTH_API_KEYS
+package main
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	user := flag.String("user", "", "usuario del dashboard")
+	role := flag.String("role", "", "rol: specialist o admin (channel para -apikey)")
+	specialistID := flag.String("specialist", "", "id del especialista asociado al usuario")
+	disable := flag.Bool("disable", false, "bloquear el usuario")
+	enable := flag.Bool("enable", false, "desbloquear el usuario")
+	token := flag.Bool("token", false, "imprimir un JWT para el usuario (requiere AUTH_JWT_SECRET)")
+	apiKey := flag.String("apikey", "", "generar una API key con este nombre")
+	flag.Parse()
+
+	log.SetOutput(os.Stderr)
+	config.LoadOfflineConfig()
+
+	if *apiKey != "" {
+		if *role == "" {
+			*role = services.AuthRoleChannel
+		}
+		key, err := services.NewAPIKey()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("%s:%s:%s\n", *apiKey, *role, key)
+		log.Printf("🔑 Agregar la línea a AUTH_API_KEYS (separadas por coma)")
+		return
+	}
+
+	if *user == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	auth := services.GetAuthService()
+
+	if *token {
+		jwt, expiresAt, err := auth.IssueToken(strings.ToLower(*user))
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Println(jwt)
+		log.Printf("🔑 Vence %s", expiresAt.Format("2006-01-02 15:04"))
+		return
+	}
+
+	existing := auth.User(*user)
+	saved := models.AuthUser{Username: *user, Role: *role, SpecialistID: *specialistID}
+	if existing != nil {
+		saved = *existing
+		if *role != "" {
+			saved.Role = *role
+		}
+		if *specialistID != "" {
+			saved.SpecialistID = *specialistID
+		}
+	}
+	if *disable {
+		saved.Disabled = true
+	}
+	if *enable {
+		saved.Disabled = false
+	}
+
+	password := ""
+	if existing == nil || (!*disable && !*enable && *role == "" && *specialistID == "") {
+		fmt.Fprint(os.Stderr, "Contraseña: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		password = strings.TrimRight(line, "\r\n")
+		if len(password) < 8 {
+			log.Fatalf("❌ La contraseña debe tener al menos 8 caracteres")
+		}
+	}
+
+	if err := auth.SaveUser(saved, password); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
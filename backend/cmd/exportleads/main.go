@@ -0,0 +1,77 @@
+// exportleads exporta los leads guardados en data/leads.json sin levantar el servidor.
+// Se ejecuta desde backend/:
+//
+//	go run ./cmd/exportleads -format xlsx -from 2025-01-01 -category hot -o leads.xlsx
+//	go run ./cmd/exportleads -contacts -o leads.csv    # con dni, teléfono y email de data/lead_contacts.json
+package main
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"bufio"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	format := flag.String("format", services.ExportCSV, "formato: csv, xlsx o ndjson")
+	output := flag.String("o", "", "archivo de salida (por defecto stdout)")
+	from := flag.String("from", "", "creados desde (AAAA-MM-DD o RFC3339)")
+	to := flag.String("to", "", "creados hasta, inclusive (AAAA-MM-DD o RFC3339)")
+	category := flag.String("category", "", "filtrar por categoría (hot, warm, cold)")
+	channel := flag.String("channel", "", "filtrar por canal")
+	stage := flag.String("stage", "", "filtrar por etapa del embudo")
+	status := flag.String("status", "", "filtrar por estado comercial")
+	withContacts := flag.Bool("contacts", false, "incluir los datos de contacto extraídos de las conversaciones")
+	flag.Parse()
+
+	// Los logs van a stderr para no mezclarse con la exportación en stdout
+	log.SetOutput(os.Stderr)
+
+	fromTime, err := services.ParseExportDate(*from, false)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	toTime, err := services.ParseExportDate(*to, true)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("❌ No se pudo crear %s: %v", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	var contacts map[string]*models.LeadContact
+	if *withContacts {
+		config.LoadOfflineConfig()
+		contacts = services.GetPIIService().Contacts()
+	}
+
+	count, err := services.GetSessionService().ExportLeads(buffered, strings.ToLower(*format), models.LeadFilter{
+		Category: *category,
+		Channel:  *channel,
+		Stage:    *stage,
+		Status:   *status,
+		From:     fromTime,
+		To:       toTime,
+	}, contacts)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		log.Fatalf("❌ Error exportando leads: %v", err)
+	}
+
+	log.Printf("📤 %d leads exportados en %s", count, *format)
+}
@@ -0,0 +1,181 @@
+// mockcrm es un CRM local para probar la sincronización de leads sin sistemas externos.
+// Expone el webhook genérico y la API de contactos estilo HubSpot que usan los conectores.
+//
+//	go run ./cmd/mockcrm -port 4000 -fail-rate 0.2
+//
+// Con el backend:
+//
+//	CRM_CONNECTORS=webhook,hubspot CRM_WEBHOOK_URL=http://localhost:4000/webhook \
+//	CRM_HUBSPOT_URL=http://localhost:4000 CRM_HUBSPOT_TOKEN=dev
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type store struct {
+	webhooks   []json.RawMessage
+	contacts   map[string]map[string]string // bob_session_id -> propiedades
+	seenKeys   map[string]bool
+	duplicates int
+	failures   int
+	mu         sync.Mutex
+}
+
+func newStore() *store {
+	return &store{
+		contacts: make(map[string]map[string]string),
+		seenKeys: make(map[string]bool),
+	}
+}
+
+// seen registra la Idempotency-Key y dice si ya se había procesado
+func (s *store) seen(key string) bool {
+	if key == "" {
+		return false
+	}
+	if s.seenKeys[key] {
+		s.duplicates++
+		return true
+	}
+	s.seenKeys[key] = true
+	return false
+}
+
+func main() {
+	port := flag.Int("port", 4000, "puerto del mock")
+	failRate := flag.Float64("fail-rate", 0, "fracción de requests que responden 503 (para probar reintentos)")
+	token := flag.String("token", "", "token Bearer requerido en la API de contactos (vacío = no se valida)")
+	flag.Parse()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var rngMu sync.Mutex
+	db := newStore()
+
+	router := gin.Default()
+
+	// Falla aleatoria para ejercitar el backoff del outbox
+	flaky := func(ctx *gin.Context) {
+		rngMu.Lock()
+		fail := rng.Float64() < *failRate
+		rngMu.Unlock()
+		if fail {
+			db.mu.Lock()
+			db.failures++
+			db.mu.Unlock()
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "falla simulada"})
+		}
+	}
+
+	auth := func(ctx *gin.Context) {
+		if *token != "" && ctx.GetHeader("Authorization") != "Bearer "+*token {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token inválido"})
+		}
+	}
+
+	router.POST("/webhook", flaky, func(ctx *gin.Context) {
+		var event json.RawMessage
+		if err := ctx.ShouldBindJSON(&event); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		if db.seen(ctx.GetHeader("Idempotency-Key")) {
+			ctx.JSON(http.StatusOK, gin.H{"duplicate": true})
+			return
+		}
+		db.webhooks = append(db.webhooks, event)
+		log.Printf("webhook %s recibido (%d en total)", ctx.GetHeader("X-BOB-Event"), len(db.webhooks))
+		ctx.JSON(http.StatusOK, gin.H{"received": true})
+	})
+
+	contacts := router.Group("/crm/v3/objects/contacts", flaky, auth)
+	{
+		contacts.POST("", func(ctx *gin.Context) {
+			var body struct {
+				Properties map[string]string `json:"properties"`
+			}
+			if err := ctx.ShouldBindJSON(&body); err != nil || body.Properties["bob_session_id"] == "" {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "properties.bob_session_id es requerido"})
+				return
+			}
+
+			id := body.Properties["bob_session_id"]
+			db.mu.Lock()
+			defer db.mu.Unlock()
+			db.seen(ctx.GetHeader("Idempotency-Key"))
+			if _, exists := db.contacts[id]; exists {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "contacto ya existe", "id": id})
+				return
+			}
+			db.contacts[id] = body.Properties
+			log.Printf("contacto creado: %s", id)
+			ctx.JSON(http.StatusCreated, gin.H{"id": id, "properties": body.Properties})
+		})
+
+		contacts.PATCH("/:id", func(ctx *gin.Context) {
+			if ctx.Query("idProperty") != "bob_session_id" {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "idProperty=bob_session_id es requerido"})
+				return
+			}
+			var body struct {
+				Properties map[string]string `json:"properties"`
+			}
+			if err := ctx.ShouldBindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			id := ctx.Param("id")
+			db.mu.Lock()
+			defer db.mu.Unlock()
+			db.seen(ctx.GetHeader("Idempotency-Key"))
+			contact, exists := db.contacts[id]
+			if !exists {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "contacto no encontrado", "id": id})
+				return
+			}
+			for k, v := range body.Properties {
+				contact[k] = v
+			}
+			log.Printf("contacto actualizado: %s (status=%s, score=%s)", id, contact["bob_status"], contact["bob_score"])
+			ctx.JSON(http.StatusOK, gin.H{"id": id, "properties": contact})
+		})
+	}
+
+	// Inspección del estado del mock
+	router.GET("/records", func(ctx *gin.Context) {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		ctx.JSON(http.StatusOK, gin.H{
+			"webhooks":   db.webhooks,
+			"contacts":   db.contacts,
+			"duplicates": db.duplicates,
+			"failures":   db.failures,
+		})
+	})
+	router.DELETE("/records", func(ctx *gin.Context) {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		fresh := newStore()
+		db.webhooks, db.contacts, db.seenKeys = fresh.webhooks, fresh.contacts, fresh.seenKeys
+		db.duplicates, db.failures = 0, 0
+		ctx.JSON(http.StatusOK, gin.H{"success": true})
+	})
+
+	log.Printf("Mock CRM en http://localhost:%d (fail-rate %.2f)", *port, *failRate)
+	if err := router.Run(fmt.Sprintf(":%d", *port)); err != nil {
+		log.Fatalf("❌ Error al iniciar mock CRM: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+// scorebatch puntúa offline leads o conversaciones de un XLSX, CSV o JSONL con el ScoringAgent
+// y escribe los resultados más un reporte resumen. Se ejecuta desde backend/ (usa .env y data/prompts):
+//
+//	go run ./cmd/scorebatch -in ../somos-bob-hackathon/datos_ficticios_completo_datos_de_leads.xlsx \
+//	    -out resultados.csv -workers 4 -rps 2
+//
+// Columnas reconocidas: id, channel/canal, conversation/mensajes ("cliente: ..." / "bob: ..." por línea),
+// expected_category/label y expected_score. El resto se usa como datos del lead.
+package main
+
+import (
+	"bob-hackathon/internal/agents"
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	in := flag.String("in", "", "dataset de entrada (.xlsx, .csv o .jsonl)")
+	out := flag.String("out", "scorebatch-results.csv", "resultados (.csv o .jsonl)")
+	report := flag.String("report", "", "reporte resumen JSON (por defecto <out>.summary.json)")
+	workers := flag.Int("workers", 4, "llamadas al modelo en paralelo")
+	rps := flag.Float64("rps", 2, "máximo de llamadas por segundo (0 = sin límite)")
+	limit := flag.Int("limit", 0, "puntuar solo los primeros N registros (0 = todos)")
+	channel := flag.String("channel", "web", "canal para registros sin canal")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *report == "" {
+		*report = strings.TrimSuffix(*out, filepath.Ext(*out)) + ".summary.json"
+	}
+
+	config.LoadConfig()
+
+	records, err := services.LoadLeadDataset(*in)
+	if err != nil {
+		log.Fatalf("❌ Error leyendo dataset: %v", err)
+	}
+	if *limit > 0 && *limit < len(records) {
+		records = records[:*limit]
+	}
+	log.Printf("📂 %d registros cargados desde %s", len(records), *in)
+
+	scoringAgent, err := agents.NewScoringAgent()
+	if err != nil {
+		log.Fatalf("❌ Error creando ScoringAgent: %v", err)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("❌ No se pudo crear %s: %v", *out, err)
+	}
+	defer file.Close()
+	writer := newResultWriter(file, strings.ToLower(filepath.Ext(*out)) == ".jsonl")
+
+	// Ctrl+C corta la corrida y deja escrito lo que ya se puntuó
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var mu sync.Mutex
+	done := 0
+	scorer := &agents.BatchScorer{Agent: scoringAgent, Workers: *workers, RPS: *rps, Channel: *channel}
+
+	start := time.Now()
+	results := scorer.Run(ctx, records, func(r models.BatchScoreResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		done++
+		if err := writer.Write(r); err != nil {
+			log.Printf("⚠️ Error escribiendo resultado %s: %v", r.ID, err)
+		}
+		if r.Error != "" {
+			log.Printf("[%d/%d] ❌ %s: %s", done, len(records), r.ID, r.Error)
+		} else {
+			log.Printf("[%d/%d] %s: %d (%s)", done, len(records), r.ID, r.Score, r.Category)
+		}
+	})
+	summary := agents.SummarizeBatch(results, time.Since(start))
+
+	if err := writer.Close(); err != nil {
+		log.Fatalf("❌ Error escribiendo %s: %v", *out, err)
+	}
+	data, _ := json.MarshalIndent(summary, "", "  ")
+	if err := os.WriteFile(*report, data, 0644); err != nil {
+		log.Fatalf("❌ Error escribiendo %s: %v", *report, err)
+	}
+
+	printSummary(os.Stderr, summary)
+	log.Printf("✅ Resultados en %s, resumen en %s", *out, *report)
+}
+
+// resultWriter escribe cada resultado apenas termina, para no perder la corrida si se corta
+type resultWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newResultWriter(w io.Writer, jsonl bool) *resultWriter {
+	if jsonl {
+		return &resultWriter{json: json.NewEncoder(w)}
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{"id", "expected_category", "category", "match", "expected_score", "score"}
+	for _, dim := range services.ScoringDimensions {
+		header = append(header, dim.Key)
+	}
+	header = append(header, "prompt_version", "error", "duration_ms")
+	cw.Write(header)
+	return &resultWriter{csv: cw}
+}
+
+func (w *resultWriter) Write(r models.BatchScoreResult) error {
+	if w.json != nil {
+		return w.json.Encode(r)
+	}
+
+	match, expectedScore, prompt := "", "", ""
+	if r.Match != nil {
+		match = strconv.FormatBool(*r.Match)
+	}
+	if r.ExpectedScore != nil {
+		expectedScore = strconv.Itoa(*r.ExpectedScore)
+	}
+	if r.Prompt != nil {
+		prompt = r.Prompt.Version
+	}
+
+	row := []string{r.ID, r.ExpectedCategory, r.Category, match, expectedScore, strconv.Itoa(r.Score)}
+	for _, dim := range services.ScoringDimensions {
+		if v, ok := r.DimensionScores[dim.Key]; ok {
+			row = append(row, strconv.Itoa(v))
+		} else {
+			row = append(row, "")
+		}
+	}
+	row = append(row, prompt, r.Error, strconv.FormatInt(r.DurationMs, 10))
+
+	w.csv.Write(row)
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *resultWriter) Close() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
+
+func printSummary(w io.Writer, s models.BatchScoreSummary) {
+	fmt.Fprintf(w, "\n📊 Resumen: %d registros, %d puntuados, %d con error (%.1fs)\n", s.Total, s.Scored, s.Failed, s.DurationSeconds)
+	fmt.Fprintf(w, "   Score promedio: %.2f\n", s.AvgScore)
+	for _, cat := range sortedKeys(s.ByCategory) {
+		fmt.Fprintf(w, "   %-10s %d\n", cat, s.ByCategory[cat])
+	}
+
+	if s.Labeled == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n🎯 Precisión contra etiquetas: %.0f%% (%d etiquetados)\n", s.Accuracy*100, s.Labeled)
+	if s.ScoreMAE > 0 {
+		fmt.Fprintf(w, "   Error absoluto medio del score: %.2f\n", s.ScoreMAE)
+	}
+	fmt.Fprintln(w, "   Matriz de confusión (esperada -> obtenida):")
+	for _, expected := range sortedKeys(s.Confusion) {
+		row := s.Confusion[expected]
+		var cells []string
+		for _, got := range sortedKeys(row) {
+			cells = append(cells, fmt.Sprintf("%s=%d", got, row[got]))
+		}
+		fmt.Fprintf(w, "   %-10s %s\n", expected, strings.Join(cells, " "))
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
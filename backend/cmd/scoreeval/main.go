@@ -0,0 +1,167 @@
+// scoreeval evalúa el ScoringAgent contra conversaciones etiquetadas y compara con la corrida anterior.
+// Se ejecuta desde backend/:
+//
+//	go run ./cmd/scoreeval                      # LLM fake: respuestas grabadas en modelResponse
+//	go run ./cmd/scoreeval -mode real -rps 1    # Gemini (requiere GEMINI_API_KEY)
+//	go run ./cmd/scoreeval -min-accuracy 0.8    # sale con código 1 si no cumple (CI)
+package main
+
+import (
+	"bob-hackathon/internal/agents"
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	casesPath := flag.String("cases", filepath.Join("data", "eval", "scoring_cases.jsonl"), "set etiquetado (.jsonl, .csv o .xlsx)")
+	mode := flag.String("mode", agents.EvalModeFake, "real (Gemini) o fake (respuestas grabadas)")
+	out := flag.String("out", filepath.Join("data", "eval", "scoring_report.json"), "reporte JSON de esta corrida")
+	baselinePath := flag.String("baseline", filepath.Join("data", "eval", "scoring_baseline.json"), "corrida anterior para medir drift (se ignora si no existe)")
+	updateBaseline := flag.Bool("update-baseline", false, "guardar esta corrida como nueva línea base")
+	workers := flag.Int("workers", 4, "llamadas al modelo en paralelo")
+	rps := flag.Float64("rps", 0, "máximo de llamadas por segundo (0 = sin límite)")
+
+	var th agents.ScoringEvalThresholds
+	flag.Float64Var(&th.MinAccuracy, "min-accuracy", 0, "accuracy mínima")
+	flag.Float64Var(&th.MinScoreInRange, "min-in-range", 0, "fracción mínima de scores en rango")
+	flag.Float64Var(&th.MaxDimensionMAE, "max-dimension-mae", 0, "MAE máximo por dimensión")
+	flag.Float64Var(&th.MaxAccuracyDrop, "max-accuracy-drop", 0, "caída máxima de accuracy contra la línea base")
+	flag.Float64Var(&th.MaxFailedPercent, "max-failed", 0, "fracción máxima de casos con error")
+	flag.Parse()
+
+	if *mode == agents.EvalModeReal {
+		config.LoadConfig()
+	} else {
+		config.LoadOfflineConfig()
+	}
+
+	cases, err := services.LoadLeadDataset(*casesPath)
+	if err != nil {
+		log.Fatalf("❌ Error leyendo casos: %v", err)
+	}
+
+	var scoringAgent *agents.ScoringAgent
+	switch *mode {
+	case agents.EvalModeFake:
+		scoringAgent = agents.NewScoringAgentWithGenerator(agents.NewReplayGenerator(cases))
+	case agents.EvalModeReal:
+		if scoringAgent, err = agents.NewScoringAgent(); err != nil {
+			log.Fatalf("❌ Error creando ScoringAgent: %v", err)
+		}
+	default:
+		log.Fatalf("❌ Modo inválido: %s (real o fake)", *mode)
+	}
+
+	log.Printf("🧪 Evaluando %d casos (modo %s)", len(cases), *mode)
+	report := agents.EvaluateScoring(context.Background(), &agents.BatchScorer{
+		Agent:   scoringAgent,
+		Workers: *workers,
+		RPS:     *rps,
+	}, cases)
+	report.Mode = *mode
+	report.Dataset = *casesPath
+
+	if data, err := os.ReadFile(*baselinePath); err == nil {
+		var baseline models.ScoringEvalReport
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			log.Printf("⚠️ Línea base inválida (%s): %v", *baselinePath, err)
+		} else {
+			agents.CompareScoringEval(report, &baseline)
+		}
+	}
+
+	writeReport(*out, report)
+	if *updateBaseline {
+		writeReport(*baselinePath, report)
+		log.Printf("📌 Línea base actualizada: %s", *baselinePath)
+	}
+
+	printReport(os.Stdout, report)
+
+	if err := agents.CheckScoringEval(report, th); err != nil {
+		log.Printf("❌ %v", err)
+		os.Exit(1)
+	}
+}
+
+func writeReport(path string, report *models.ScoringEvalReport) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatalf("❌ Error creando %s: %v", filepath.Dir(path), err)
+	}
+	data, _ := json.MarshalIndent(report, "", "  ")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Fatalf("❌ Error escribiendo %s: %v", path, err)
+	}
+}
+
+func printReport(w io.Writer, r *models.ScoringEvalReport) {
+	fmt.Fprintf(w, "\n📊 %d casos: %d evaluados, %d con error\n", r.Total, r.Evaluated, r.Failed)
+	fmt.Fprintf(w, "   Accuracy de categoría: %.0f%%\n", r.Accuracy*100)
+	fmt.Fprintf(w, "   Score dentro del rango esperado: %.0f%%\n", r.ScoreInRange*100)
+
+	fmt.Fprintf(w, "\n   Matriz de confusión (fila = esperada, columna = obtenida)\n   %-10s", "")
+	for _, got := range agents.ScoringCategories {
+		fmt.Fprintf(w, "%10s", got)
+	}
+	fmt.Fprintln(w)
+	for _, expected := range agents.ScoringCategories {
+		fmt.Fprintf(w, "   %-10s", expected)
+		for _, got := range agents.ScoringCategories {
+			fmt.Fprintf(w, "%10d", r.Confusion[expected][got])
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.DimensionMAE) > 0 {
+		fmt.Fprintln(w, "\n   Error absoluto medio por dimensión")
+		for _, dim := range services.ScoringDimensions {
+			if mae, ok := r.DimensionMAE[dim.Key]; ok {
+				line := fmt.Sprintf("   %-24s %5.2f", dim.Label, mae)
+				if r.Drift != nil {
+					if delta, ok := r.Drift.DimensionMAEDelta[dim.Key]; ok && delta != 0 {
+						line += fmt.Sprintf("  (%+.2f)", delta)
+					}
+				}
+				fmt.Fprintln(w, line)
+			}
+		}
+	}
+
+	var misses []string
+	for _, c := range r.Cases {
+		switch {
+		case c.Error != "":
+			misses = append(misses, fmt.Sprintf("%s: error: %s", c.ID, c.Error))
+		case c.ExpectedCategory != "" && !c.CategoryMatch:
+			misses = append(misses, fmt.Sprintf("%s: esperada %s, obtenida %s (%d)", c.ID, c.ExpectedCategory, c.Category, c.Score))
+		case c.ScoreInRange != nil && !*c.ScoreInRange:
+			misses = append(misses, fmt.Sprintf("%s: score %d fuera de rango", c.ID, c.Score))
+		}
+	}
+	if len(misses) > 0 {
+		sort.Strings(misses)
+		fmt.Fprintf(w, "\n   Desacuerdos\n   %s\n", strings.Join(misses, "\n   "))
+	}
+
+	if d := r.Drift; d != nil {
+		fmt.Fprintf(w, "\n📈 Drift contra la corrida del %s\n", d.BaselineRunAt.Format("2006-01-02 15:04"))
+		fmt.Fprintf(w, "   Accuracy %+.0f pp, score en rango %+.0f pp\n", d.AccuracyDelta*100, d.ScoreInRangeDelta*100)
+		for _, c := range d.Changed {
+			fmt.Fprintf(w, "   %s: %s -> %s (score %+d)\n", c.ID, c.FromCategory, c.ToCategory, c.ScoreDelta)
+		}
+		if len(d.Changed) == 0 {
+			fmt.Fprintln(w, "   Sin cambios de categoría ni de score relevantes")
+		}
+	}
+}
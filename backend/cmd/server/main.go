@@ -5,6 +5,8 @@ import (
 	"bob-hackathon/internal/controllers"
 	"bob-hackathon/internal/models"
 	"bob-hackathon/internal/services"
+	"bob-hackathon/internal/telemetry"
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -12,36 +14,79 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
 	// Cargar configuración
 	config.LoadConfig()
 
+	// Trazas distribuidas (OTEL_TRACES_EXPORTER: none, otlp o stdout)
+	shutdownTelemetry, err := telemetry.Init(context.Background(), config.AppConfig.OTelServiceName, config.AppConfig.OTelTracesExporter)
+	if err != nil {
+		log.Fatalf("❌ Error iniciando OpenTelemetry: %v", err)
+	}
+	defer shutdownTelemetry(context.Background())
+
 	// Inicializar servicios
 	log.Println("Inicializando servicios...")
 	services.GetFAQService()
 	services.GetBOBAPIService()
 	services.GetSessionService()
+	services.GetPIIService().RedactStoredSessions(services.GetSessionService())
 	services.GetGeminiService()
+	services.GetPromptService()
+	services.GetEscalationService()
+	services.GetSpecialistService()
+	services.GetHandoffService()
+	services.GetUsageService()
+	services.GetResponseCacheService()
+	services.GetFollowUpService().Start()
+	services.GetCRMService().Start()
+	services.GetAuthService()
+	services.GetRateLimitService()
 
 	// Crear router
 	router := gin.Default()
 
+	// ClientIP solo usa X-Forwarded-For si el request viene de un proxy confiable
+	if err := router.SetTrustedProxies(splitNonEmpty(config.AppConfig.TrustedProxies)); err != nil {
+		log.Fatalf("❌ TRUSTED_PROXIES inválido: %v", err)
+	}
+
 	// Configurar CORS
 	corsOrigins := strings.Split(config.AppConfig.CORSOrigins, ",")
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     corsOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-PII-Key", "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-RateLimit-Remaining"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Un span por request; continúa la traza que venga en traceparent (ej. desde el bot)
+	router.Use(otelgin.Middleware(config.AppConfig.OTelServiceName))
+
+	// Métricas Prometheus por ruta; sesiones y leads se calculan en cada scrape
+	router.Use(telemetry.MetricsMiddleware())
+	telemetry.Registry.MustRegister(services.NewSessionMetricsCollector())
+
 	// Crear controllers
+	authController := controllers.NewAuthController()
+	rateLimitController := controllers.NewRateLimitController()
 	chatController := controllers.NewChatController()
 	leadController := controllers.NewLeadController()
+	promptController := controllers.NewPromptController()
+	handoffController := controllers.NewHandoffController()
+	escalationController := controllers.NewEscalationController()
+	followUpController := controllers.NewFollowUpController()
+	specialistController := controllers.NewSpecialistController()
+	crmController := controllers.NewCRMController()
+	analyticsController := controllers.NewAnalyticsController()
+	usageController := controllers.NewUsageController()
+	faqController := controllers.NewFAQController()
+	guardrailController := controllers.NewGuardrailController()
 
 	// Health check
 	router.GET("/health", func(ctx *gin.Context) {
@@ -52,6 +97,9 @@ func main() {
 		})
 	})
 
+	// Métricas en formato Prometheus
+	router.GET("/metrics", telemetry.MetricsHandler())
+
 	// Ruta raíz con documentación
 	router.GET("/", func(ctx *gin.Context) {
 		ctx.JSON(200, gin.H{
@@ -59,49 +107,223 @@ func main() {
 			"version": "2.0.0",
 			"status":  "running",
 			"endpoints": gin.H{
-				"health": "GET /health",
+				"health":  "GET /health",
+				"metrics": "GET /metrics",
+				"auth": gin.H{
+					"login": "POST /api/auth/login",
+					"me":    "GET /api/auth/me",
+				},
 				"chat": gin.H{
 					"message": "POST /api/chat/message",
 					"score":   "POST /api/chat/score",
 					"history": "GET /api/chat/history/:sessionId",
+					"trace":   "GET /api/chat/trace/:sessionId?limit=&redact=true",
 					"delete":  "DELETE /api/chat/session/:sessionId",
 				},
 				"leads": gin.H{
-					"list":   "GET /api/leads",
-					"get":    "GET /api/leads/:sessionId",
-					"stats":  "GET /api/leads/stats",
+					"list":    "GET /api/leads",
+					"get":     "GET /api/leads/:sessionId",
+					"stats":   "GET /api/leads/stats",
+					"export":  "GET /api/leads/export?format=csv|xlsx|ndjson",
+					"assign":  "POST /api/leads/:sessionId/assign",
+					"update":  "PATCH /api/leads/:sessionId",
+					"audit":   "GET /api/leads/:sessionId/audit",
+					"contact": "GET|DELETE /api/leads/:sessionId/contact?actor= (header X-PII-Key)",
 				},
 				"resources": gin.H{
-					"faqs":     "GET /api/faqs",
-					"vehicles": "GET /api/vehicles",
-					"vehicle":  "GET /api/vehicles/:id",
+					"faqs":       "GET /api/faqs",
+					"faqsReload": "POST /api/faqs/reload",
+					"faqsCache":  "GET|DELETE /api/faqs/cache",
+					"vehicles":   "GET /api/vehicles",
+					"vehicle":    "GET /api/vehicles/:id",
+				},
+				"prompts": gin.H{
+					"list":    "GET /api/prompts",
+					"preview": "POST /api/prompts/:name/preview",
+					"reload":  "POST /api/prompts/reload",
+				},
+				"handoff": gin.H{
+					"list":    "GET /api/handoff",
+					"claim":   "POST /api/handoff/:sessionId/claim",
+					"reply":   "POST /api/handoff/:sessionId/reply",
+					"release": "POST /api/handoff/:sessionId/release",
+				},
+				"escalations": gin.H{
+					"list":    "GET /api/escalations",
+					"stats":   "GET /api/escalations/stats",
+					"rules":   "GET /api/escalations/rules",
+					"resolve": "POST /api/escalations/:id/resolve",
+				},
+				"followups": gin.H{
+					"list":   "GET /api/followups",
+					"cancel": "POST /api/followups/:id/cancel",
+					"run":    "POST /api/followups/run",
+				},
+				"specialists": gin.H{
+					"list":   "GET /api/specialists",
+					"create": "POST /api/specialists",
+					"update": "PUT /api/specialists/:id",
+					"queue":  "GET /api/specialists/:id/queue",
+				},
+				"crm": gin.H{
+					"connectors": "GET /api/crm/connectors",
+					"outbox":     "GET /api/crm/outbox",
+					"sync":       "POST /api/crm/sync",
+					"retry":      "POST /api/crm/outbox/:id/retry",
+				},
+				"analytics": gin.H{
+					"summary":    "GET /api/analytics?from=&to=&channel=&bucket=day|week|month",
+					"timeseries": "GET /api/analytics/timeseries",
+					"categories": "GET /api/analytics/categories",
+					"dimensions": "GET /api/analytics/dimensions",
+					"funnel":     "GET /api/analytics/funnel",
+					"timeToHot":  "GET /api/analytics/time-to-hot",
+					"intents":    "GET /api/analytics/intents?threshold=0.6&top=20",
+					"decisions":  "GET /api/analytics/decisions?intent=&sessionId=&limit=100",
+				},
+				"usage": gin.H{
+					"summary": "GET /api/usage?from=&to=&channel=&agent=&sessionId=&top=20",
+					"session": "GET /api/usage/session/:sessionId",
+					"prices":  "GET /api/usage/prices",
+				},
+				"guardrails": gin.H{
+					"events": "GET /api/guardrails/events?sessionId=&rule=&action=&limit=100",
+					"stats":  "GET /api/guardrails/stats",
+					"rules":  "GET /api/guardrails/rules",
 				},
 			},
 		})
 	})
 
-	// Rutas de Chat
-	chatRoutes := router.Group("/api/chat")
+	// Roles (AUTH_ENABLED=true): channel para integraciones como el bot de WhatsApp,
+	// specialist para el dashboard y admin para configuración; admin pasa en todas
+	channelOrSpecialist := authController.Require(services.AuthRoleChannel, services.AuthRoleSpecialist)
+	specialist := authController.Require(services.AuthRoleSpecialist)
+	admin := authController.Require(services.AuthRoleAdmin)
+
+	// Rutas de autenticación
+	authRoutes := router.Group("/api/auth")
 	{
-		chatRoutes.POST("/message", chatController.SendMessage)
-		chatRoutes.POST("/score", chatController.GetScore)
+		authRoutes.POST("/login", authController.Login)
+		authRoutes.GET("/me", channelOrSpecialist, authController.Me)
+	}
+
+	// Rutas de Chat: mensaje e historial quedan abiertos para el widget web; las sesiones
+	// de otros canales exigen la API key de la integración. Mensaje y score llaman al LLM
+	// y pasan por el límite de mensajes (429 con Retry-After).
+	rateLimit := rateLimitController.Limit()
+	chatRoutes := router.Group("/api/chat", authController.Identify())
+	{
+		chatRoutes.POST("/message", rateLimit, chatController.SendMessage)
+		chatRoutes.POST("/score", channelOrSpecialist, rateLimit, chatController.GetScore)
 		chatRoutes.GET("/history/:sessionId", chatController.GetHistory)
-		chatRoutes.DELETE("/session/:sessionId", chatController.DeleteSession)
+		chatRoutes.GET("/trace/:sessionId", specialist, chatController.GetTrace)
+		chatRoutes.DELETE("/session/:sessionId", admin, chatController.DeleteSession)
 	}
 
 	// Rutas de Leads
-	leadRoutes := router.Group("/api/leads")
+	leadRoutes := router.Group("/api/leads", specialist)
 	{
 		leadRoutes.GET("", leadController.GetAllLeads)
 		leadRoutes.GET("/stats", leadController.GetLeadsStats)
+		leadRoutes.GET("/export", leadController.ExportLeads)
 		leadRoutes.GET("/:sessionId", leadController.GetLead)
+		leadRoutes.PATCH("/:sessionId", leadController.UpdateLead)
+		leadRoutes.GET("/:sessionId/audit", leadController.GetLeadAudit)
+		leadRoutes.GET("/:sessionId/contact", admin, leadController.GetLeadContact)
+		leadRoutes.DELETE("/:sessionId/contact", admin, leadController.DeleteLeadContact)
+		leadRoutes.POST("/:sessionId/assign", leadController.AssignLead)
 	}
 
 	// Rutas de Recursos
 	router.GET("/api/faqs", leadController.GetFAQs)
+	router.POST("/api/faqs/reload", admin, faqController.ReloadFAQs)
+	router.GET("/api/faqs/cache", specialist, faqController.GetCacheStats)
+	router.DELETE("/api/faqs/cache", admin, faqController.ClearCache)
 	router.GET("/api/vehicles", leadController.GetVehicles)
 	router.GET("/api/vehicles/:id", leadController.GetVehicleByID)
 
+	// Rutas de Prompts
+	promptRoutes := router.Group("/api/prompts", admin)
+	{
+		promptRoutes.GET("", promptController.GetPrompts)
+		promptRoutes.POST("/reload", promptController.ReloadPrompts)
+		promptRoutes.POST("/:name/preview", promptController.PreviewPrompt)
+	}
+
+	// Rutas de atención humana
+	handoffRoutes := router.Group("/api/handoff", specialist)
+	{
+		handoffRoutes.GET("", handoffController.GetHandoffs)
+		handoffRoutes.POST("/:sessionId/claim", handoffController.ClaimSession)
+		handoffRoutes.POST("/:sessionId/reply", handoffController.SendReply)
+		handoffRoutes.POST("/:sessionId/release", handoffController.ReleaseSession)
+	}
+
+	// Rutas de Escalaciones
+	escalationRoutes := router.Group("/api/escalations", specialist)
+	{
+		escalationRoutes.GET("", escalationController.GetEscalations)
+		escalationRoutes.GET("/stats", escalationController.GetEscalationStats)
+		escalationRoutes.GET("/rules", escalationController.GetRules)
+		escalationRoutes.POST("/:id/resolve", escalationController.ResolveEscalation)
+	}
+
+	// Rutas de Seguimientos
+	followUpRoutes := router.Group("/api/followups", specialist)
+	{
+		followUpRoutes.GET("", followUpController.GetFollowUps)
+		followUpRoutes.POST("/run", admin, followUpController.RunDue)
+		followUpRoutes.POST("/:id/cancel", followUpController.CancelFollowUp)
+	}
+
+	// Rutas de Especialistas
+	specialistRoutes := router.Group("/api/specialists", specialist)
+	{
+		specialistRoutes.GET("", specialistController.GetSpecialists)
+		specialistRoutes.POST("", admin, specialistController.SaveSpecialist)
+		specialistRoutes.PUT("/:id", admin, specialistController.SaveSpecialist)
+		specialistRoutes.GET("/:id/queue", specialistController.GetQueue)
+	}
+
+	// Rutas de CRM
+	crmRoutes := router.Group("/api/crm", admin)
+	{
+		crmRoutes.GET("/connectors", crmController.GetConnectors)
+		crmRoutes.GET("/outbox", crmController.GetOutbox)
+		crmRoutes.POST("/sync", crmController.Sync)
+		crmRoutes.POST("/outbox/:id/retry", crmController.RetryEntry)
+	}
+
+	// Rutas de Analítica
+	analyticsRoutes := router.Group("/api/analytics", specialist)
+	{
+		analyticsRoutes.GET("", analyticsController.GetSummary)
+		analyticsRoutes.GET("/timeseries", analyticsController.GetTimeSeries)
+		analyticsRoutes.GET("/categories", analyticsController.GetCategories)
+		analyticsRoutes.GET("/dimensions", analyticsController.GetDimensions)
+		analyticsRoutes.GET("/funnel", analyticsController.GetFunnel)
+		analyticsRoutes.GET("/time-to-hot", analyticsController.GetTimeToHot)
+		analyticsRoutes.GET("/intents", analyticsController.GetIntents)
+		analyticsRoutes.GET("/decisions", analyticsController.GetDecisions)
+	}
+
+	// Rutas de consumo de LLM
+	usageRoutes := router.Group("/api/usage", admin)
+	{
+		usageRoutes.GET("", usageController.GetUsage)
+		usageRoutes.GET("/prices", usageController.GetPrices)
+		usageRoutes.GET("/session/:sessionId", usageController.GetSessionUsage)
+	}
+
+	// Rutas de Guardrails
+	guardrailRoutes := router.Group("/api/guardrails", specialist)
+	{
+		guardrailRoutes.GET("/events", guardrailController.GetEvents)
+		guardrailRoutes.GET("/stats", guardrailController.GetStats)
+		guardrailRoutes.GET("/rules", guardrailController.GetRules)
+	}
+
 	// Iniciar servidor
 	port := config.AppConfig.Port
 	log.Printf("Servidor corriendo en puerto %s", port)
@@ -112,3 +334,14 @@ func main() {
 		log.Fatalf("❌ Error al iniciar servidor: %v", err)
 	}
 }
+
+// splitNonEmpty separa una lista por comas descartando los vacíos
+func splitNonEmpty(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
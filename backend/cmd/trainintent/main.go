@@ -0,0 +1,156 @@
+// trainintent entrena el clasificador local de intenciones (naive Bayes) con las decisiones
+// que tomó el LLM orchestrator, guardadas en data/orchestrator_decisions.jsonl. Se ejecuta
+// desde backend/ y el servidor carga el modelo al arrancar:
+//
+//	go run ./cmd/trainintent                          # entrena y guarda data/intent_model.json
+//	go run ./cmd/trainintent -extra data/eval/intent_examples.jsonl -min-confidence 0.8
+//	go run ./cmd/trainintent -dry-run                 # solo evalúa, no guarda
+package main
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// intentsRoutedLocally son las intenciones que el fast path resuelve solo con el modelo
+var intentsRoutedLocally = map[string]bool{"faq": true, "subasta": true, services.IntentSpam: true}
+
+func main() {
+	out := flag.String("out", "", "modelo entrenado (por defecto INTENT_MODEL_FILE)")
+	extra := flag.String("extra", "", "ejemplos etiquetados a mano (.jsonl con message e intent)")
+	minConfidence := flag.Float64("min-confidence", 0.7, "confianza mínima del LLM para usar una decisión")
+	holdout := flag.Int("holdout", 5, "evaluar con 1 de cada N ejemplos (0 = sin evaluación)")
+	threshold := flag.Float64("threshold", 0, "umbral del fast path para la evaluación (por defecto INTENT_FASTPATH_THRESHOLD)")
+	dryRun := flag.Bool("dry-run", false, "evaluar sin guardar el modelo")
+	flag.Parse()
+
+	config.LoadOfflineConfig()
+	if *out == "" {
+		*out = config.AppConfig.IntentModelFile
+	}
+	if *threshold <= 0 {
+		*threshold = config.AppConfig.IntentFastPathThreshold
+	}
+
+	decisions := services.GetDecisionLogService().List(models.DecisionFilter{})
+	examples := services.IntentExamplesFromDecisions(decisions, *minConfidence)
+	log.Printf("📂 %d decisiones, %d usables para entrenar", len(decisions), len(examples))
+
+	if *extra != "" {
+		extraExamples, err := loadExamples(*extra)
+		if err != nil {
+			log.Fatalf("❌ Error leyendo %s: %v", *extra, err)
+		}
+		examples = append(examples, extraExamples...)
+		log.Printf("📂 %d ejemplos adicionales desde %s", len(extraExamples), *extra)
+	}
+	if len(examples) == 0 {
+		log.Fatalf("❌ No hay ejemplos para entrenar")
+	}
+
+	printDistribution(examples)
+	if *holdout > 1 && len(examples) >= *holdout {
+		evaluate(examples, *holdout, *threshold)
+	}
+
+	model := services.TrainIntentModel(examples)
+	if *dryRun {
+		return
+	}
+	if err := services.SaveIntentModel(*out, model); err != nil {
+		log.Fatalf("❌ Error guardando %s: %v", *out, err)
+	}
+	log.Printf("✅ Modelo guardado en %s (%d ejemplos, %d términos). Reinicia el servidor para usarlo", *out, model.Examples, model.Vocabulary)
+}
+
+func loadExamples(path string) ([]models.IntentExample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var examples []models.IntentExample
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var ex models.IntentExample
+		if err := json.Unmarshal([]byte(line), &ex); err != nil {
+			return nil, err
+		}
+		if ex.Message != "" && ex.Intent != "" {
+			ex.Intent = services.CanonicalIntent(ex.Intent)
+			examples = append(examples, ex)
+		}
+	}
+	return examples, scanner.Err()
+}
+
+func printDistribution(examples []models.IntentExample) {
+	counts := make(map[string]int)
+	for _, ex := range examples {
+		counts[ex.Intent]++
+	}
+	intents := make([]string, 0, len(counts))
+	for intent := range counts {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+
+	fmt.Println("\n📊 Ejemplos por intención")
+	for _, intent := range intents {
+		fmt.Printf("   %-12s %6d\n", intent, counts[intent])
+	}
+}
+
+// evaluate entrena sin 1 de cada n ejemplos y mide con esos: accuracy general y, sobre el
+// umbral, qué fracción resolvería el fast path y con qué precisión
+func evaluate(examples []models.IntentExample, n int, threshold float64) {
+	var train, test []models.IntentExample
+	for i, ex := range examples {
+		if i%n == 0 {
+			test = append(test, ex)
+		} else {
+			train = append(train, ex)
+		}
+	}
+	model := services.TrainIntentModel(train)
+
+	correct, covered, coveredCorrect := 0, 0, 0
+	for _, ex := range test {
+		intent, confidence := services.PredictIntent(model, ex.Message)
+		if intent == ex.Intent {
+			correct++
+		}
+		if confidence >= threshold && intentsRoutedLocally[intent] {
+			covered++
+			if intent == ex.Intent {
+				coveredCorrect++
+			}
+		}
+	}
+
+	fmt.Printf("\n🧪 Evaluación con %d ejemplos (entrenado con %d)\n", len(test), len(train))
+	fmt.Printf("   Accuracy: %.0f%%\n", percent(correct, len(test)))
+	fmt.Printf("   Resueltos sin LLM con umbral %.2f: %.0f%%, precisión %.0f%%\n",
+		threshold, percent(covered, len(test)), percent(coveredCorrect, covered))
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
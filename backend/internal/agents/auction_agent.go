@@ -2,9 +2,12 @@ package agents
 
 import (
 	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
 	"bob-hackathon/internal/services"
+	"bob-hackathon/internal/telemetry"
 	"context"
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
@@ -12,9 +15,10 @@ import (
 )
 
 type AuctionAgent struct {
-	client         *genai.Client
-	model          *genai.GenerativeModel
-	bobAPIService  *services.BOBAPIService
+	client        *genai.Client
+	model         *genai.GenerativeModel
+	bobAPIService *services.BOBAPIService
+	promptService *services.PromptService
 }
 
 func NewAuctionAgent() (*AuctionAgent, error) {
@@ -28,6 +32,7 @@ func NewAuctionAgent() (*AuctionAgent, error) {
 		client:        client,
 		model:         client.GenerativeModel(config.AppConfig.GeminiModel),
 		bobAPIService: services.GetBOBAPIService(),
+		promptService: services.GetPromptService(),
 	}, nil
 }
 
@@ -48,40 +53,68 @@ func (a *AuctionAgent) Process(ctx context.Context, input *AgentInput) (*AgentOu
 		vehicles = vehicles[:10]
 	}
 
-	prompt := a.buildPrompt(input, vehicles)
-
-	resp, err := a.model.GenerateContent(ctx, genai.Text(prompt))
+	prompt, promptRef, err := a.buildPrompt(input, vehicles)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from model")
+	retrieved := make([]string, len(vehicles))
+	for i, v := range vehicles {
+		retrieved[i] = fmt.Sprintf("%s: %s %s %s", v.ID, v.Marca, v.Modelo, v.Ano)
 	}
 
-	responseText := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
+	responseText, usage, err := callModel(ctx, a.client, a.model, a.Name(), prompt)
+	if err != nil {
+		// Sin LLM se listan las unidades disponibles sin redactar
+		log.Printf("⚠️ Auction Agent en modo degradado: %v", err)
+		telemetry.CountLLMDegraded(a.Name())
+		return &AgentOutput{
+			Response:   vehicleList(vehicles),
+			Prompt:     &promptRef,
+			PromptText: prompt,
+			Retrieved:  retrieved,
+			Degraded:   true,
+			Usage:      usage,
+		}, nil
+	}
 
 	return &AgentOutput{
-		Response: strings.TrimSpace(responseText),
+		Response:   strings.TrimSpace(responseText),
+		Prompt:     &promptRef,
+		PromptText: prompt,
+		RawOutput:  responseText,
+		Retrieved:  retrieved,
+		Usage:      usage,
 	}, nil
 }
 
-func (a *AuctionAgent) buildPrompt(input *AgentInput, vehicles interface{}) string {
-	return fmt.Sprintf(`Eres el Agente de Subastas de BOB. Tu especialidad es ayudar a encontrar vehículos en subasta.
-
-MENSAJE DEL USUARIO: "%s"
-
-VEHÍCULOS DISPONIBLES:
-%v
+func (a *AuctionAgent) buildPrompt(input *AgentInput, vehicles []models.Vehicle) (string, models.PromptRef, error) {
+	return a.promptService.Render("auction_agent", input.Channel, map[string]any{
+		"Message":  input.Message,
+		"Channel":  input.Channel,
+		"Vehicles": vehicles,
+		"Stage":    input.Stage,
+		"Slots":    input.Slots,
+	})
+}
 
-INSTRUCCIONES:
-1. Analiza qué tipo de vehículo busca el usuario (marca, modelo, año, tipo)
-2. Recomienda vehículos que coincidan con sus necesidades
-3. Menciona precios iniciales y estado
-4. Sé específico con los detalles de cada vehículo
-5. Si no hay coincidencias exactas, sugiere alternativas similares
-6. Invita a ver más en https://www.somosbob.com/subastas
-7. Pregunta sobre presupuesto, urgencia y uso previsto para afinarlo scoring
+// vehicleList arma la respuesta del modo degradado con las primeras unidades en subasta
+func vehicleList(vehicles []models.Vehicle) string {
+	if len(vehicles) == 0 {
+		return "En este momento no encontré unidades disponibles. ¿Te aviso cuando ingresen nuevas?"
+	}
+	if len(vehicles) > 5 {
+		vehicles = vehicles[:5]
+	}
 
-Responde de manera útil y orientada a cerrar la venta.`, input.Message, vehicles)
+	var sb strings.Builder
+	sb.WriteString("Estas son algunas unidades en subasta:")
+	for _, v := range vehicles {
+		sb.WriteString(fmt.Sprintf("\n- %s %s %s", v.Marca, v.Modelo, v.Ano))
+		if v.PrecioInicio > 0 {
+			sb.WriteString(fmt.Sprintf(" - precio inicial $%.0f", v.PrecioInicio))
+		}
+	}
+	sb.WriteString("\n¿Alguna te interesa?")
+	return sb.String()
 }
@@ -11,11 +11,13 @@ type Agent interface {
 }
 
 type AgentInput struct {
-	Message        string
-	SessionID      string
-	Channel        string
+	Message             string
+	SessionID           string
+	Channel             string
 	ConversationHistory []models.Message
-	LeadData       *models.LeadData
+	LeadData            *models.LeadData
+	Stage               string
+	Slots               map[string]string
 }
 
 type AgentOutput struct {
@@ -25,14 +27,34 @@ type AgentOutput struct {
 	ScoringData    *models.ScoringData
 	IntentDetected string
 	Confidence     float64
+	Reasoning      string
+	Prompt         *models.PromptRef
+
+	// Para el trace del turno: prompt enviado, respuesta cruda y datos recuperados
+	PromptText string
+	RawOutput  string
+	Retrieved  []string
+
+	// Tokens consumidos en la llamada al LLM (nil si no hubo llamada)
+	Usage *models.TokenUsage
+
+	// Respuesta armada sin LLM porque no estaba disponible (modo degradado)
+	Degraded bool
+
+	// Quién decidió la intención sin LLM: cache (pregunta ya respondida), rules o model
+	// (clasificador local), keywords (modo degradado). Vacío si decidió el LLM.
+	DecidedBy string
+
+	// Respuesta tomada del cache de FAQs, sin llamada al LLM
+	Cached bool
 }
 
 type IntentType string
 
 const (
-	IntentFAQ      IntentType = "faq"
-	IntentSubasta  IntentType = "subasta"
-	IntentSpam     IntentType = "spam"
-	IntentAmbiguo  IntentType = "ambiguo"
-	IntentGeneral  IntentType = "general"
+	IntentFAQ     IntentType = "faq"
+	IntentSubasta IntentType = "subasta"
+	IntentSpam    IntentType = "spam"
+	IntentAmbiguo IntentType = "ambiguo"
+	IntentGeneral IntentType = "general"
 )
@@ -0,0 +1,199 @@
+package agents
+
+import (
+	"bob-hackathon/internal/models"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// BatchScorer puntúa muchos registros con concurrencia acotada y un límite de llamadas
+// por segundo al modelo. Sirve para backtesting del rubric contra datasets etiquetados.
+type BatchScorer struct {
+	Agent   Agent   // normalmente un *ScoringAgent
+	Workers int     // llamadas en paralelo (mínimo 1)
+	RPS     float64 // llamadas por segundo; 0 = sin límite
+	Channel string  // canal por defecto para registros sin canal
+}
+
+// Run puntúa los registros y devuelve los resultados en el orden de entrada.
+// onResult (opcional) se llama a medida que termina cada registro, desde varias goroutines.
+func (b *BatchScorer) Run(ctx context.Context, records []models.LeadRecord, onResult func(models.BatchScoreResult)) []models.BatchScoreResult {
+	workers := b.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var limiter <-chan time.Time
+	if b.RPS > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / b.RPS))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	results := make([]models.BatchScoreResult, len(records))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if limiter != nil {
+					select {
+					case <-limiter:
+					case <-ctx.Done():
+					}
+				}
+				results[i] = b.score(ctx, &records[i])
+				if onResult != nil {
+					onResult(results[i])
+				}
+			}
+		}()
+	}
+
+	for i := range records {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Los registros que no alcanzaron a correr quedan marcados como cancelados
+	if err := ctx.Err(); err != nil {
+		for i := range results {
+			if results[i].ID == "" {
+				results[i] = newBatchResult(&records[i])
+				results[i].Error = err.Error()
+			}
+		}
+	}
+	return results
+}
+
+func (b *BatchScorer) score(ctx context.Context, record *models.LeadRecord) models.BatchScoreResult {
+	result := newBatchResult(record)
+	if err := ctx.Err(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	channel := record.Channel
+	if channel == "" {
+		channel = b.Channel
+	}
+	if channel == "" {
+		channel = "web"
+	}
+
+	message := ""
+	if n := len(record.Messages); n > 0 {
+		message = record.Messages[n-1].Content
+	}
+
+	start := time.Now()
+	output, err := b.Agent.Process(ctx, &AgentInput{
+		Message:             message,
+		SessionID:           batchSessionID(record.ID),
+		Channel:             channel,
+		ConversationHistory: record.Messages,
+	})
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if output.ScoringData == nil {
+		result.Error = "el agente no devolvió scoring"
+		return result
+	}
+
+	result.Score = output.ScoringData.TotalScore
+	result.Category = output.ScoringData.Category
+	result.DimensionScores = output.ScoringData.DimensionScores
+	result.Prompt = output.Prompt
+	if record.ExpectedCategory != "" {
+		match := result.Category == record.ExpectedCategory
+		result.Match = &match
+	}
+	return result
+}
+
+// batchSessionID es el sessionId con que el scorer identifica cada registro ante el agente
+func batchSessionID(id string) string {
+	return "batch-" + id
+}
+
+func newBatchResult(record *models.LeadRecord) models.BatchScoreResult {
+	return models.BatchScoreResult{
+		ID:               record.ID,
+		ExpectedCategory: record.ExpectedCategory,
+		ExpectedScore:    record.ExpectedScore,
+	}
+}
+
+// SummarizeBatch calcula distribución, precisión contra las etiquetas y matriz de confusión
+func SummarizeBatch(results []models.BatchScoreResult, elapsed time.Duration) models.BatchScoreSummary {
+	summary := models.BatchScoreSummary{
+		Total:           len(results),
+		ByCategory:      make(map[string]int),
+		Confusion:       make(map[string]map[string]int),
+		PromptVersions:  make(map[string]int),
+		DurationSeconds: math.Round(elapsed.Seconds()*100) / 100,
+	}
+
+	scoreSum, matches := 0, 0
+	absErrSum, withExpectedScore := 0, 0
+	for _, r := range results {
+		if r.Error != "" {
+			summary.Failed++
+			continue
+		}
+		summary.Scored++
+		summary.ByCategory[r.Category]++
+		scoreSum += r.Score
+		if r.Prompt != nil {
+			summary.PromptVersions[r.Prompt.Name+"@"+r.Prompt.Version]++
+		}
+
+		if r.ExpectedCategory != "" {
+			summary.Labeled++
+			if r.Match != nil && *r.Match {
+				matches++
+			}
+			if summary.Confusion[r.ExpectedCategory] == nil {
+				summary.Confusion[r.ExpectedCategory] = make(map[string]int)
+			}
+			summary.Confusion[r.ExpectedCategory][r.Category]++
+		}
+		if r.ExpectedScore != nil {
+			withExpectedScore++
+			diff := r.Score - *r.ExpectedScore
+			if diff < 0 {
+				diff = -diff
+			}
+			absErrSum += diff
+		}
+	}
+
+	if summary.Scored > 0 {
+		summary.AvgScore = round2(float64(scoreSum) / float64(summary.Scored))
+	}
+	if summary.Labeled > 0 {
+		summary.Accuracy = round2(float64(matches) / float64(summary.Labeled))
+	}
+	if withExpectedScore > 0 {
+		summary.ScoreMAE = round2(float64(absErrSum) / float64(withExpectedScore))
+	}
+	return summary
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
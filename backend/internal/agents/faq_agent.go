@@ -4,8 +4,9 @@ import (
 	"bob-hackathon/internal/config"
 	"bob-hackathon/internal/models"
 	"bob-hackathon/internal/services"
+	"bob-hackathon/internal/telemetry"
 	"context"
-	"fmt"
+	"log"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
@@ -13,9 +14,11 @@ import (
 )
 
 type FAQAgent struct {
-	client     *genai.Client
-	model      *genai.GenerativeModel
-	faqService *services.FAQService
+	client        *genai.Client
+	model         *genai.GenerativeModel
+	faqService    *services.FAQService
+	promptService *services.PromptService
+	cache         *services.ResponseCacheService
 }
 
 func NewFAQAgent() (*FAQAgent, error) {
@@ -26,9 +29,11 @@ func NewFAQAgent() (*FAQAgent, error) {
 	}
 
 	return &FAQAgent{
-		client:     client,
-		model:      client.GenerativeModel(config.AppConfig.GeminiModel),
-		faqService: services.GetFAQService(),
+		client:        client,
+		model:         client.GenerativeModel(config.AppConfig.GeminiModel),
+		faqService:    services.GetFAQService(),
+		promptService: services.GetPromptService(),
+		cache:         services.GetResponseCacheService(),
 	}, nil
 }
 
@@ -38,6 +43,7 @@ func (f *FAQAgent) Name() string {
 
 func (f *FAQAgent) Process(ctx context.Context, input *AgentInput) (*AgentOutput, error) {
 	faqs := f.faqService.SearchFAQs(input.Message, "", "")
+	telemetry.CountFAQRetrieval(len(faqs) > 0)
 
 	if len(faqs) == 0 {
 		return &AgentOutput{
@@ -45,46 +51,78 @@ func (f *FAQAgent) Process(ctx context.Context, input *AgentInput) (*AgentOutput
 		}, nil
 	}
 
-	prompt := f.buildPrompt(input, faqs)
-
-	resp, err := f.model.GenerateContent(ctx, genai.Text(prompt))
+	prompt, promptRef, err := f.buildPrompt(input, faqs)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from model")
+	// Misma pregunta con las mismas FAQs, etapa y prompt: se reutiliza la respuesta anterior
+	// (solo si la sesión no tiene slots, que el prompt le pasa al modelo)
+	faqSet := services.FAQFingerprint(promptFAQs(faqs))
+	if cached, ok := f.cache.Get(input.Channel, input.Stage, input.Slots, input.Message, faqSet, promptRef.Version); ok {
+		return &AgentOutput{
+			Response:  cached.Response,
+			Prompt:    &promptRef,
+			Retrieved: faqTitles(faqs),
+			Cached:    true,
+		}, nil
+	}
+
+	responseText, usage, err := callModel(ctx, f.client, f.model, f.Name(), prompt)
+	if err != nil {
+		// Sin LLM se responde con la FAQ mejor ubicada, tal cual está escrita
+		log.Printf("⚠️ FAQ Agent en modo degradado: %v", err)
+		telemetry.CountLLMDegraded(f.Name())
+		return &AgentOutput{
+			Response:   faqs[0].Respuesta,
+			Prompt:     &promptRef,
+			PromptText: prompt,
+			Retrieved:  faqTitles(faqs[:1]),
+			Degraded:   true,
+			Usage:      usage,
+		}, nil
 	}
 
-	responseText := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
+	response := strings.TrimSpace(responseText)
+	// Las respuestas del modelo económico (presupuesto superado) no se guardan
+	if _, override := ModelFromContext(ctx); !override {
+		f.cache.Put(input.Channel, input.Stage, input.Slots, input.Message, faqSet, promptRef.Version, response, faqTitles(faqs))
+	}
 
 	return &AgentOutput{
-		Response: strings.TrimSpace(responseText),
+		Response:   response,
+		Prompt:     &promptRef,
+		PromptText: prompt,
+		RawOutput:  responseText,
+		Retrieved:  faqTitles(faqs),
+		Usage:      usage,
 	}, nil
 }
 
-func (f *FAQAgent) buildPrompt(input *AgentInput, faqs []models.FAQ) string {
-	faqContext := "\n\nFAQs RELEVANTES:\n"
-	for i, faq := range faqs {
-		if i >= 5 {
-			break
-		}
-		faqContext += fmt.Sprintf("\nP: %s\nR: %s\n", faq.Pregunta, faq.Respuesta)
+// promptFAQs son las FAQs que recibe el prompt: las 5 primeras
+func promptFAQs(faqs []models.FAQ) []models.FAQ {
+	if len(faqs) > 5 {
+		return faqs[:5]
 	}
+	return faqs
+}
 
-	return fmt.Sprintf(`Eres el Agente de FAQ de BOB Subastas. Tu especialidad es responder preguntas frecuentes.
-
-PREGUNTA DEL USUARIO: "%s"
-%s
-
-INSTRUCCIONES:
-1. Responde la pregunta usando la información de las FAQs
-2. Si hay múltiples FAQs relevantes, combina la información de manera coherente
-3. Sé conciso pero completo
-4. Usa un tono amigable y profesional
-5. Si la información no está en las FAQs, reconócelo y ofrece ayuda alternativa
-6. NO inventes información que no esté en las FAQs
-7. Incluye enlaces relevantes si están en las FAQs
+// faqTitles resume las FAQs que recibe el prompt para el trace
+func faqTitles(faqs []models.FAQ) []string {
+	faqs = promptFAQs(faqs)
+	titles := make([]string, len(faqs))
+	for i, faq := range faqs {
+		titles[i] = faq.Categoria + ": " + faq.Pregunta
+	}
+	return titles
+}
 
-Responde de manera directa y útil.`, input.Message, faqContext)
+func (f *FAQAgent) buildPrompt(input *AgentInput, faqs []models.FAQ) (string, models.PromptRef, error) {
+	return f.promptService.Render("faq_agent", input.Channel, map[string]any{
+		"Message": input.Message,
+		"Channel": input.Channel,
+		"FAQs":    promptFAQs(faqs),
+		"Stage":   input.Stage,
+		"Slots":   input.Slots,
+	})
 }
@@ -0,0 +1,166 @@
+package agents
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/telemetry"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+type modelOverrideKey struct{}
+
+// WithModel hace que las llamadas al LLM hechas con ctx usen otro modelo de Gemini
+// (ej. uno más barato cuando se supera el presupuesto)
+func WithModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelOverrideKey{}, model)
+}
+
+// ModelFromContext devuelve el modelo fijado con WithModel, si hay uno
+func ModelFromContext(ctx context.Context) (string, bool) {
+	model, ok := ctx.Value(modelOverrideKey{}).(string)
+	return model, ok && model != ""
+}
+
+// callModel envía el prompt a Gemini y devuelve el texto de la primera respuesta y los
+// tokens consumidos. Cada intento tiene el timeout del agente; los errores transitorios se
+// reintentan con backoff y, si el modelo sigue fallando o su circuito está abierto, se pasa
+// a LLM_FALLBACK_MODEL. Si ninguno responde devuelve ErrLLMUnavailable.
+func callModel(ctx context.Context, client *genai.Client, model *genai.GenerativeModel, agent, prompt string) (string, *models.TokenUsage, error) {
+	modelName := config.AppConfig.GeminiModel
+	if override, ok := ModelFromContext(ctx); ok && client != nil {
+		modelName = override
+		model = modelNamed(client, model, override)
+	}
+
+	names := []string{modelName}
+	if fallback := config.AppConfig.LLMFallbackModel; fallback != "" && fallback != modelName && client != nil {
+		names = append(names, fallback)
+	}
+
+	// Los intentos fallidos también consumen tokens: se suman por modelo
+	var used []*models.TokenUsage
+	var lastErr error
+	for i, name := range names {
+		breaker := breakerFor(name)
+		if !breaker.allow() {
+			lastErr = fmt.Errorf("circuito abierto para %s", name)
+			continue
+		}
+
+		current := model
+		if i > 0 {
+			log.Printf("↪️ %s: usando modelo de respaldo %s (%v)", agent, name, lastErr)
+			telemetry.CountLLMFallback(agent, name)
+			current = modelNamed(client, model, name)
+		}
+
+		text, usage, err := generateWithRetry(ctx, current, name, agent, prompt, breaker)
+		if usage != nil {
+			used = append(used, usage)
+		}
+		if err == nil {
+			return text, combineUsage(used), nil
+		}
+		lastErr = err
+
+		// El request se canceló: no tiene sentido probar otro modelo
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return "", combineUsage(used), fmt.Errorf("%w: %v", ErrLLMUnavailable, lastErr)
+}
+
+// combineUsage devuelve el consumo del último modelo usado con el de los anteriores en Other
+func combineUsage(used []*models.TokenUsage) *models.TokenUsage {
+	if len(used) == 0 {
+		return nil
+	}
+	last := used[len(used)-1]
+	for _, previous := range used[:len(used)-1] {
+		last.Other = append(last.Other, *previous)
+	}
+	return last
+}
+
+// addUsage suma los tokens de un intento al total de un mismo modelo
+func addUsage(total, attempt *models.TokenUsage) *models.TokenUsage {
+	if attempt == nil {
+		return total
+	}
+	if total == nil {
+		return attempt
+	}
+	total.PromptTokens += attempt.PromptTokens
+	total.CompletionTokens += attempt.CompletionTokens
+	total.TotalTokens += attempt.TotalTokens
+	return total
+}
+
+// generateWithRetry llama al modelo reintentando los errores transitorios hasta
+// LLM_MAX_RETRIES veces, mientras el circuito siga cerrado. Devuelve los tokens de todos
+// los intentos.
+func generateWithRetry(ctx context.Context, model *genai.GenerativeModel, modelName, agent, prompt string, breaker *circuitBreaker) (string, *models.TokenUsage, error) {
+	var total *models.TokenUsage
+	for attempt := 0; ; attempt++ {
+		text, usage, err := generateOnce(ctx, model, modelName, agent, prompt)
+		total = addUsage(total, usage)
+		if err == nil {
+			breaker.success()
+			return text, total, nil
+		}
+		if !isTransient(err) {
+			// El modelo respondió, pero con un error que se repetiría igual
+			breaker.success()
+			return "", total, err
+		}
+
+		breaker.failure()
+		if attempt >= config.AppConfig.LLMMaxRetries || ctx.Err() != nil || !breaker.allow() {
+			return "", total, err
+		}
+
+		delay := retryDelay(attempt)
+		log.Printf("🔁 %s: reintento %d en %v tras error de %s: %v", agent, attempt+1, delay.Round(time.Millisecond), modelName, err)
+		telemetry.CountLLMRetry(agent)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return "", total, err
+		}
+	}
+}
+
+// generateOnce hace un intento con el timeout del agente y registra latencia, errores y tokens
+func generateOnce(ctx context.Context, model *genai.GenerativeModel, modelName, agent, prompt string) (string, *models.TokenUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutFor(agent))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err == nil && (len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0) {
+		err = fmt.Errorf("no response from model")
+	}
+
+	var usage *models.TokenUsage
+	if resp != nil && resp.UsageMetadata != nil {
+		usage = &models.TokenUsage{
+			Model:            modelName,
+			PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
+			CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+			TotalTokens:      int(resp.UsageMetadata.TotalTokenCount),
+		}
+		telemetry.ObserveLLMCall(agent, time.Since(start), err, usage.PromptTokens, usage.CompletionTokens)
+	} else {
+		telemetry.ObserveLLMCall(agent, time.Since(start), err, 0, 0)
+	}
+
+	if err != nil {
+		return "", usage, err
+	}
+	return fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]), usage, nil
+}
@@ -2,9 +2,13 @@ package agents
 
 import (
 	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"bob-hackathon/internal/telemetry"
 	"context"
 	"encoding/json"
-	"fmt"
+	"log"
+	"regexp"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
@@ -12,8 +16,12 @@ import (
 )
 
 type OrchestratorAgent struct {
-	client *genai.Client
-	model  *genai.GenerativeModel
+	client        *genai.Client
+	model         *genai.GenerativeModel
+	promptService *services.PromptService
+	classifier    *services.IntentClassifier
+	cache         *services.ResponseCacheService
+	funnel        *services.FunnelService
 }
 
 func NewOrchestratorAgent() (*OrchestratorAgent, error) {
@@ -24,8 +32,12 @@ func NewOrchestratorAgent() (*OrchestratorAgent, error) {
 	}
 
 	return &OrchestratorAgent{
-		client: client,
-		model:  client.GenerativeModel(config.AppConfig.GeminiModel),
+		client:        client,
+		model:         client.GenerativeModel(config.AppConfig.GeminiModel),
+		promptService: services.GetPromptService(),
+		classifier:    services.GetIntentClassifier(),
+		cache:         services.GetResponseCacheService(),
+		funnel:        services.GetFunnelService(),
 	}, nil
 }
 
@@ -34,76 +46,48 @@ func (o *OrchestratorAgent) Name() string {
 }
 
 func (o *OrchestratorAgent) Process(ctx context.Context, input *AgentInput) (*AgentOutput, error) {
-	prompt := o.buildPrompt(input)
+	// Mensajes obvios (saludos, gracias, spam, FAQs conocidas) se resuelven sin LLM
+	if decision := o.fastPath(input); decision != nil {
+		telemetry.CountIntentFastPath(decision.DecidedBy)
+		return decision, nil
+	}
+	telemetry.CountIntentFastPath("llm")
 
-	resp, err := o.model.GenerateContent(ctx, genai.Text(prompt))
+	prompt, promptRef, err := o.buildPrompt(input)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from model")
+	responseText, usage, err := callModel(ctx, o.client, o.model, o.Name(), prompt)
+	if err != nil {
+		// Sin LLM se rutea por palabras clave para no dejar al usuario sin respuesta
+		log.Printf("⚠️ Orchestrator en modo degradado: %v", err)
+		telemetry.CountLLMDegraded(o.Name())
+		decision := keywordDecision(input.Message)
+		decision.Reasoning += ": " + err.Error()
+		decision.Prompt = &promptRef
+		decision.PromptText = prompt
+		decision.Usage = usage
+		return decision, nil
 	}
 
-	responseText := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-
 	decision := o.parseDecision(responseText)
+	decision.Prompt = &promptRef
+	decision.PromptText = prompt
+	decision.RawOutput = responseText
+	decision.Usage = usage
 
 	return decision, nil
 }
 
-func (o *OrchestratorAgent) buildPrompt(input *AgentInput) string {
-	historyText := ""
-	if len(input.ConversationHistory) > 0 {
-		historyText = "\n\nHISTORIAL DE CONVERSACIÓN:\n"
-		for _, msg := range input.ConversationHistory {
-			historyText += fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
-		}
-	}
-
-	return fmt.Sprintf(`Eres el Agente Orquestador de BOB Subastas. Tu tarea es analizar el mensaje del usuario y decidir cómo manejarlo.
-
-MENSAJE DEL USUARIO: "%s"
-CANAL: %s%s
-
-ANÁLISIS REQUERIDO:
-
-1. DETECCIÓN DE SPAM/AMBIGÜEDAD:
-   - ¿Es spam? (publicidad, mensajes sin sentido, trolling)
-   - ¿Es ambiguo? (no se entiende la intención)
-   - ¿Es un saludo inicial? (primera interacción)
-
-2. CLASIFICACIÓN DE INTENCIÓN:
-   - FAQ: Preguntas sobre cómo funciona BOB, proceso de subasta, pagos, etc.
-   - SUBASTA: Búsqueda de vehículos, preguntas sobre subastas específicas
-   - GENERAL: Conversación general, necesita respuesta del orquestador
-   - SPAM: Mensaje no válido
-   - AMBIGUO: No está clara la intención
-
-3. ROUTING:
-   - Si es FAQ → ruta a "faq_agent"
-   - Si es SUBASTA → ruta a "auction_agent"
-   - Si es GENERAL → responde tú mismo
-   - Si es SPAM → responde mensaje educado de rechazo
-   - Si es AMBIGUO → pide clarificación
-
-FORMATO DE RESPUESTA (JSON):
-{
-  "intent": "faq|auction|general|spam|ambiguous",
-  "confidence": 0.0-1.0,
-  "shouldRoute": true/false,
-  "routeTo": "faq_agent|auction_agent|null",
-  "response": "tu respuesta si no se rutea",
-  "reasoning": "breve explicación de tu decisión"
-}
-
-IMPORTANTE:
-- Sé conciso y directo
-- Si detectas spam, sé educado pero firme
-- Si es ambiguo, pide específicamente qué necesita
-- Si es saludo inicial, da bienvenida cálida y explica cómo puedes ayudar
-
-Responde SOLO con el JSON, sin texto adicional.`, input.Message, input.Channel, historyText)
+func (o *OrchestratorAgent) buildPrompt(input *AgentInput) (string, models.PromptRef, error) {
+	return o.promptService.Render("orchestrator", input.Channel, map[string]any{
+		"Message": input.Message,
+		"Channel": input.Channel,
+		"History": input.ConversationHistory,
+		"Stage":   input.Stage,
+		"Slots":   input.Slots,
+	})
 }
 
 type OrchestratorDecision struct {
@@ -127,6 +111,7 @@ func (o *OrchestratorAgent) parseDecision(responseText string) *AgentOutput {
 			ShouldRoute:    false,
 			IntentDetected: string(IntentAmbiguo),
 			Confidence:     0.0,
+			Reasoning:      "respuesta del modelo sin JSON",
 		}
 	}
 
@@ -139,6 +124,7 @@ func (o *OrchestratorAgent) parseDecision(responseText string) *AgentOutput {
 			ShouldRoute:    false,
 			IntentDetected: string(IntentAmbiguo),
 			Confidence:     0.0,
+			Reasoning:      "JSON inválido: " + err.Error(),
 		}
 	}
 
@@ -148,5 +134,123 @@ func (o *OrchestratorAgent) parseDecision(responseText string) *AgentOutput {
 		RouteTo:        decision.RouteTo,
 		IntentDetected: decision.Intent,
 		Confidence:     decision.Confidence,
+		Reasoning:      decision.Reasoning,
+	}
+}
+
+var (
+	auctionKeywordRegex  = regexp.MustCompile(`(?i)\b(subastas?|remates?|pujas?|pujar|ofertar|lotes?|precio base|unidades|disponibles?)\b`)
+	faqKeywordRegex      = regexp.MustCompile(`(?i)(\?|\b(c[oó]mo|qu[eé]|cu[aá]ndo|d[oó]nde|requisitos?|registr\w*|inscrib\w*|pagos?|pagar|garant[ií]as?|comisi[oó]n|documentos?|entrega|devoluci[oó]n)\b)`)
+	greetingKeywordRegex = regexp.MustCompile(`(?i)^\s*(hola|buen[oa]s( d[ií]as| tardes| noches)?|hey|saludos)\b`)
+)
+
+const (
+	greetingReply = "¡Hola! Soy el asistente de BOB Subastas. ¿Buscas algún vehículo en particular o tienes alguna consulta sobre cómo participar?"
+	thanksReply   = "¡Con gusto! Si necesitas algo más sobre nuestras subastas, aquí estoy."
+	spamReply     = "Este canal es solo para consultas sobre las subastas de BOB. Si buscas un vehículo o tienes dudas del proceso, con gusto te ayudo."
+)
+
+// cacheHit indica si el FAQ agent tendría la respuesta en cache: usa la etapa que va a
+// tener la sesión después de rutear como FAQ, que es con la que el FAQ agent busca
+func (o *OrchestratorAgent) cacheHit(input *AgentInput) bool {
+	stage := input.Stage
+	if o.funnel != nil {
+		stage, _ = o.funnel.NextStage(input.Stage, services.FunnelEvent{Intent: string(IntentFAQ), Slots: input.Slots})
+	}
+	return o.cache.MatchesQuestion(input.Channel, stage, input.Slots, input.Message)
+}
+
+// fastPath resuelve sin LLM las preguntas que ya están en el cache de FAQs y lo que el
+// clasificador local decide con confianza suficiente: FAQ y subasta se rutean al subagente,
+// saludos, agradecimientos y spam se responden directo. Devuelve nil si el mensaje tiene
+// que ir al LLM.
+func (o *OrchestratorAgent) fastPath(input *AgentInput) *AgentOutput {
+	if o.cache != nil && o.cacheHit(input) {
+		return &AgentOutput{
+			IntentDetected: string(IntentFAQ),
+			Confidence:     1,
+			ShouldRoute:    true,
+			RouteTo:        "faq_agent",
+			DecidedBy:      "cache",
+			Reasoning:      "pregunta ya respondida (cache de FAQs)",
+		}
+	}
+
+	if !config.AppConfig.IntentFastPathEnabled || o.classifier == nil {
+		return nil
+	}
+
+	prediction := o.classifier.Classify(input.Message)
+	if prediction.Confidence < config.AppConfig.IntentFastPathThreshold {
+		return nil
+	}
+
+	decision := &AgentOutput{
+		IntentDetected: prediction.Intent,
+		Confidence:     prediction.Confidence,
+		DecidedBy:      prediction.Source,
+		Reasoning:      "clasificador local: " + prediction.Source,
+	}
+	if prediction.Rule != "" {
+		decision.Reasoning += " (" + prediction.Rule + ")"
+	}
+
+	switch prediction.Intent {
+	case string(IntentFAQ):
+		decision.ShouldRoute = true
+		decision.RouteTo = "faq_agent"
+	case string(IntentSubasta):
+		decision.ShouldRoute = true
+		decision.RouteTo = "auction_agent"
+	case services.IntentSpam:
+		decision.Response = spamReply
+	case string(IntentGeneral):
+		// Solo las reglas saben qué responder; lo general que estima el modelo va al LLM
+		switch prediction.Rule {
+		case services.IntentRuleGreeting:
+			decision.Response = greetingReply
+		case services.IntentRuleThanks:
+			decision.Response = thanksReply
+		default:
+			return nil
+		}
+	default:
+		return nil
+	}
+	return decision
+}
+
+const degradedReply = "Estoy con una demora técnica, pero te ayudo igual. Si necesitas algo más detallado, escríbeme de nuevo en unos minutos."
+
+// keywordDecision reemplaza al LLM cuando no está disponible: subastas y vehículos van al
+// auction agent, preguntas al FAQ agent, saludos y el resto se responden directo
+func keywordDecision(message string) *AgentOutput {
+	decision := &AgentOutput{
+		Response:  degradedReply,
+		Reasoning: "LLM no disponible, ruteo por palabras clave",
+		Degraded:  true,
+		DecidedBy: "keywords",
+	}
+
+	slots := services.GetFunnelService().ExtractSlots(message)
+	switch {
+	case auctionKeywordRegex.MatchString(message) || slots[services.SlotBrand] != "" || slots[services.SlotVehicle] != "":
+		decision.IntentDetected = string(IntentSubasta)
+		decision.ShouldRoute = true
+		decision.RouteTo = "auction_agent"
+		decision.Confidence = 0.5
+	case faqKeywordRegex.MatchString(message):
+		decision.IntentDetected = string(IntentFAQ)
+		decision.ShouldRoute = true
+		decision.RouteTo = "faq_agent"
+		decision.Confidence = 0.5
+	case greetingKeywordRegex.MatchString(message):
+		decision.IntentDetected = string(IntentGeneral)
+		decision.Confidence = 0.5
+		decision.Response = greetingReply
+	default:
+		decision.IntentDetected = string(IntentAmbiguo)
+		decision.Response = "Estoy con una demora técnica. ¿Me cuentas si buscas un vehículo en subasta o tienes una consulta sobre el proceso?"
 	}
+	return decision
 }
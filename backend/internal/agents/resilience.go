@@ -0,0 +1,225 @@
+package agents
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/telemetry"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrLLMUnavailable indica que ningún modelo respondió: errores, timeouts o circuitos abiertos.
+// Los agentes lo usan para pasar a modo degradado en vez de devolver el error.
+var ErrLLMUnavailable = errors.New("LLM no disponible")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "abierto"
+	case circuitHalfOpen:
+		return "semiabierto"
+	}
+	return "cerrado"
+}
+
+// circuitBreaker corta las llamadas a un modelo tras LLM_BREAKER_FAILURES errores transitorios
+// seguidos. Pasado el cooldown deja pasar una sola llamada de prueba: si responde se cierra,
+// si falla vuelve a abrirse.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	model    string
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*circuitBreaker)
+)
+
+func breakerFor(model string) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[model]
+	if !ok {
+		b = &circuitBreaker{model: model}
+		breakers[model] = b
+		telemetry.SetLLMCircuitState(model, int(circuitClosed))
+	}
+	return b
+}
+
+// allow indica si se puede llamar al modelo ahora
+func (b *circuitBreaker) allow() bool {
+	if config.AppConfig.LLMBreakerFailures <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		cooldown := time.Duration(config.AppConfig.LLMBreakerCooldownSeconds) * time.Second
+		if time.Since(b.openedAt) < cooldown {
+			return false
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return true
+	case circuitHalfOpen:
+		// Solo una llamada de prueba a la vez
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// success registra que el modelo respondió (aunque la respuesta no sirva)
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != circuitClosed {
+		b.setState(circuitClosed)
+	}
+}
+
+// failure registra un error transitorio y abre el circuito si corresponde
+func (b *circuitBreaker) failure() {
+	if config.AppConfig.LLMBreakerFailures <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == circuitHalfOpen || b.failures >= config.AppConfig.LLMBreakerFailures {
+		b.openedAt = time.Now()
+		if b.state != circuitOpen {
+			b.setState(circuitOpen)
+		}
+	}
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	log.Printf("🔌 Circuito de %s: %s → %s", b.model, b.state, state)
+	b.state = state
+	telemetry.SetLLMCircuitState(b.model, int(state))
+}
+
+var (
+	agentTimeouts     map[string]int
+	agentTimeoutsOnce sync.Once
+)
+
+// timeoutFor devuelve el timeout de cada intento: el de LLM_TIMEOUTS_JSON para el agente
+// o LLM_TIMEOUT_SECONDS
+func timeoutFor(agent string) time.Duration {
+	agentTimeoutsOnce.Do(func() {
+		agentTimeouts = make(map[string]int)
+		if raw := config.AppConfig.LLMTimeoutsJSON; raw != "" {
+			if err := json.Unmarshal([]byte(raw), &agentTimeouts); err != nil {
+				log.Printf("⚠️ LLM_TIMEOUTS_JSON inválido, se usa LLM_TIMEOUT_SECONDS: %v", err)
+			}
+		}
+	})
+
+	seconds := agentTimeouts[agent]
+	if seconds <= 0 {
+		seconds = config.AppConfig.LLMTimeoutSeconds
+	}
+	if seconds <= 0 {
+		seconds = 20
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// isTransient distingue los errores que vale la pena reintentar (timeouts, cuota,
+// errores del servidor, red) de los que van a repetirse igual (request inválido, bloqueo)
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryDelay es el backoff exponencial con jitter completo: un valor al azar entre 0 y
+// base * 2^intento, para que los reintentos de varios requests no lleguen juntos
+func retryDelay(attempt int) time.Duration {
+	base := config.AppConfig.LLMRetryBaseMs
+	if base <= 0 {
+		base = 300
+	}
+	if attempt > 6 {
+		attempt = 6
+	}
+	ceiling := time.Duration(base) * time.Millisecond << attempt
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// modelNamed crea el modelo con otro nombre conservando la configuración del original
+// (temperatura, safety settings, system instruction)
+func modelNamed(client *genai.Client, base *genai.GenerativeModel, name string) *genai.GenerativeModel {
+	model := client.GenerativeModel(name)
+	if base != nil {
+		model.GenerationConfig = base.GenerationConfig
+		model.SafetySettings = base.SafetySettings
+		model.Tools = base.Tools
+		model.ToolConfig = base.ToolConfig
+		model.SystemInstruction = base.SystemInstruction
+	}
+	return model
+}
@@ -0,0 +1,255 @@
+package agents
+
+import (
+	"bob-hackathon/internal/models"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Modos de evaluación del scoring
+const (
+	EvalModeReal = "real" // Gemini
+	EvalModeFake = "fake" // respuestas grabadas en modelResponse
+)
+
+// ScoringCategories son las categorías que puede devolver el ScoringAgent
+var ScoringCategories = []string{"hot", "warm", "cold", "discarded"}
+
+// scoreDriftThreshold es el cambio de score que se reporta como drift aunque la categoría no cambie
+const scoreDriftThreshold = 10
+
+// ScoringEvalThresholds son los mínimos que debe cumplir una evaluación (ej. en go test o CI).
+// Los valores en cero no se verifican.
+type ScoringEvalThresholds struct {
+	MinAccuracy      float64 // fracción de categorías correctas
+	MinScoreInRange  float64 // fracción de scores dentro del rango esperado
+	MaxDimensionMAE  float64 // error absoluto medio máximo en cualquier dimensión
+	MaxAccuracyDrop  float64 // caída máxima de accuracy contra la corrida anterior
+	MaxFailedPercent float64 // fracción máxima de casos con error
+}
+
+// NewReplayGenerator devuelve un TextGenerator que responde con la respuesta grabada de cada caso.
+// Permite evaluar el prompt y el parseo del ScoringAgent sin llamar a Gemini.
+func NewReplayGenerator(cases []models.LeadRecord) TextGenerator {
+	responses := make(map[string]string, len(cases))
+	for _, c := range cases {
+		responses[batchSessionID(c.ID)] = c.ModelResponse
+	}
+
+	return func(ctx context.Context, input *AgentInput, prompt string) (string, error) {
+		resp := responses[input.SessionID]
+		if resp == "" {
+			return "", fmt.Errorf("caso sin modelResponse grabada")
+		}
+		return resp, nil
+	}
+}
+
+// EvaluateScoring corre los casos etiquetados por el scorer y los compara con lo esperado.
+// Uso desde un test (go test corre en el directorio del paquete, por eso las rutas suben a
+// backend/; ver scoring_eval_test.go):
+//
+//	t.Setenv("PROMPTS_DIR", "../../data/prompts")
+//	config.LoadOfflineConfig()
+//	cases, _ := services.LoadLeadDataset("../../data/eval/scoring_cases.jsonl")
+//	agent := agents.NewScoringAgentWithGenerator(agents.NewReplayGenerator(cases))
+//	report := agents.EvaluateScoring(ctx, &agents.BatchScorer{Agent: agent, Workers: 4}, cases)
+//	if err := agents.CheckScoringEval(report, agents.ScoringEvalThresholds{MinAccuracy: 0.8}); err != nil {
+//		t.Fatal(err)
+//	}
+func EvaluateScoring(ctx context.Context, scorer *BatchScorer, cases []models.LeadRecord) *models.ScoringEvalReport {
+	results := scorer.Run(ctx, cases, nil)
+
+	report := &models.ScoringEvalReport{
+		RunAt:          time.Now(),
+		PromptVersions: make(map[string]int),
+		Total:          len(cases),
+		Confusion:      make(map[string]map[string]int),
+		DimensionMAE:   make(map[string]float64),
+		Cases:          make([]models.ScoringEvalCase, 0, len(cases)),
+	}
+	for _, expected := range ScoringCategories {
+		report.Confusion[expected] = make(map[string]int)
+		for _, got := range ScoringCategories {
+			report.Confusion[expected][got] = 0
+		}
+	}
+
+	labeled, matches := 0, 0
+	ranged, inRange := 0, 0
+	dimErrSum := make(map[string]int)
+	dimCount := make(map[string]int)
+
+	for i, r := range results {
+		c := cases[i]
+		ec := models.ScoringEvalCase{
+			ID:               c.ID,
+			ExpectedCategory: c.ExpectedCategory,
+			Category:         r.Category,
+			Score:            r.Score,
+			ScoreMin:         c.ExpectedScoreMin,
+			ScoreMax:         c.ExpectedScoreMax,
+			DimensionScores:  r.DimensionScores,
+			Error:            r.Error,
+		}
+		if r.Error != "" {
+			report.Failed++
+			report.Cases = append(report.Cases, ec)
+			continue
+		}
+		report.Evaluated++
+		if r.Prompt != nil {
+			report.PromptVersions[r.Prompt.Name+"@"+r.Prompt.Version]++
+		}
+
+		if c.ExpectedCategory != "" {
+			labeled++
+			ec.CategoryMatch = r.Category == c.ExpectedCategory
+			if ec.CategoryMatch {
+				matches++
+			}
+			if report.Confusion[c.ExpectedCategory] == nil {
+				report.Confusion[c.ExpectedCategory] = make(map[string]int)
+			}
+			report.Confusion[c.ExpectedCategory][r.Category]++
+		}
+
+		if min, max := expectedRange(c); min != nil || max != nil {
+			ok := (min == nil || r.Score >= *min) && (max == nil || r.Score <= *max)
+			ec.ScoreInRange = &ok
+			ranged++
+			if ok {
+				inRange++
+			}
+		}
+
+		for key, expected := range c.ExpectedDimensions {
+			got, ok := r.DimensionScores[key]
+			if !ok {
+				continue
+			}
+			diff := got - expected
+			if diff < 0 {
+				diff = -diff
+			}
+			if ec.DimensionErrors == nil {
+				ec.DimensionErrors = make(map[string]int)
+			}
+			ec.DimensionErrors[key] = diff
+			dimErrSum[key] += diff
+			dimCount[key]++
+		}
+
+		report.Cases = append(report.Cases, ec)
+	}
+
+	if labeled > 0 {
+		report.Accuracy = round2(float64(matches) / float64(labeled))
+	}
+	if ranged > 0 {
+		report.ScoreInRange = round2(float64(inRange) / float64(ranged))
+	}
+	for key, n := range dimCount {
+		report.DimensionMAE[key] = round2(float64(dimErrSum[key]) / float64(n))
+	}
+	return report
+}
+
+// expectedRange usa min/max si están; un expectedScore exacto cuenta como rango de un punto
+func expectedRange(c models.LeadRecord) (*int, *int) {
+	if c.ExpectedScoreMin != nil || c.ExpectedScoreMax != nil {
+		return c.ExpectedScoreMin, c.ExpectedScoreMax
+	}
+	return c.ExpectedScore, c.ExpectedScore
+}
+
+// CompareScoringEval agrega al reporte el drift contra una corrida anterior
+func CompareScoringEval(current, baseline *models.ScoringEvalReport) {
+	if current == nil || baseline == nil {
+		return
+	}
+
+	drift := &models.ScoringEvalDrift{
+		BaselineRunAt:     baseline.RunAt,
+		AccuracyDelta:     round2(current.Accuracy - baseline.Accuracy),
+		ScoreInRangeDelta: round2(current.ScoreInRange - baseline.ScoreInRange),
+		DimensionMAEDelta: make(map[string]float64),
+	}
+	for key, mae := range current.DimensionMAE {
+		if prev, ok := baseline.DimensionMAE[key]; ok {
+			drift.DimensionMAEDelta[key] = round2(mae - prev)
+		}
+	}
+
+	previous := make(map[string]models.ScoringEvalCase, len(baseline.Cases))
+	for _, c := range baseline.Cases {
+		previous[c.ID] = c
+	}
+	for _, c := range current.Cases {
+		prev, ok := previous[c.ID]
+		if !ok || c.Error != "" || prev.Error != "" {
+			continue
+		}
+		delta := c.Score - prev.Score
+		if c.Category != prev.Category || delta >= scoreDriftThreshold || delta <= -scoreDriftThreshold {
+			drift.Changed = append(drift.Changed, models.ScoringEvalCaseChange{
+				ID:           c.ID,
+				FromCategory: prev.Category,
+				ToCategory:   c.Category,
+				ScoreDelta:   delta,
+			})
+		}
+	}
+	sort.Slice(drift.Changed, func(i, j int) bool {
+		return drift.Changed[i].ID < drift.Changed[j].ID
+	})
+
+	current.Drift = drift
+}
+
+// CheckScoringEval devuelve un error con todos los umbrales que la evaluación no cumple
+func CheckScoringEval(report *models.ScoringEvalReport, th ScoringEvalThresholds) error {
+	var failures []string
+
+	if report.Total == 0 {
+		failures = append(failures, "no hay casos para evaluar")
+	}
+	if th.MinAccuracy > 0 && report.Accuracy < th.MinAccuracy {
+		failures = append(failures, fmt.Sprintf("accuracy %.2f < %.2f", report.Accuracy, th.MinAccuracy))
+	}
+	if th.MinScoreInRange > 0 && report.ScoreInRange < th.MinScoreInRange {
+		failures = append(failures, fmt.Sprintf("score en rango %.2f < %.2f", report.ScoreInRange, th.MinScoreInRange))
+	}
+	if th.MaxDimensionMAE > 0 {
+		for _, key := range sortedMapKeys(report.DimensionMAE) {
+			if mae := report.DimensionMAE[key]; mae > th.MaxDimensionMAE {
+				failures = append(failures, fmt.Sprintf("MAE de %s %.2f > %.2f", key, mae, th.MaxDimensionMAE))
+			}
+		}
+	}
+	if th.MaxAccuracyDrop > 0 && report.Drift != nil && -report.Drift.AccuracyDelta > th.MaxAccuracyDrop {
+		failures = append(failures, fmt.Sprintf("accuracy cayó %.2f (máximo %.2f)", -report.Drift.AccuracyDelta, th.MaxAccuracyDrop))
+	}
+	if th.MaxFailedPercent > 0 && report.Total > 0 {
+		if failed := float64(report.Failed) / float64(report.Total); failed > th.MaxFailedPercent {
+			failures = append(failures, fmt.Sprintf("%.0f%% de casos con error (máximo %.0f%%)", failed*100, th.MaxFailedPercent*100))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("evaluación de scoring fuera de umbral: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
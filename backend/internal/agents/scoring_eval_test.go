@@ -0,0 +1,88 @@
+package agents
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Los tests corren desde internal/agents: los datos se resuelven desde backend/
+var evalDataDir = filepath.Join("..", "..", "data")
+
+// coldResponse es lo que contesta un LLM que califica todo como frío
+const coldResponse = `{"dimension1_perfilDemografico": {"score": 2}, "dimension2_comportamientoDigital": {"score": 2},
+"dimension3_capacidadFinanciera": {"score": 2}, "dimension4_necesidadUrgencia": {"score": 2},
+"dimension5_experienciaPrevia": {"score": 2}, "dimension6_engagementActual": {"score": 2},
+"dimension7_contextoCompra": {"score": 2}, "boosts": [], "penalizaciones": [], "totalScore": 14,
+"category": "cold", "accionRecomendada": "Nutrir", "tiempoContacto": "7d", "tipoSeguimiento": "email", "resumenEjecutivo": ""}`
+
+func loadEvalCases(t *testing.T) []models.LeadRecord {
+	t.Helper()
+	t.Setenv("PROMPTS_DIR", filepath.Join(evalDataDir, "prompts"))
+	config.LoadOfflineConfig()
+
+	cases, err := services.LoadLeadDataset(filepath.Join(evalDataDir, "eval", "scoring_cases.jsonl"))
+	if err != nil {
+		t.Fatalf("leyendo casos: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("el set de evaluación está vacío")
+	}
+	return cases
+}
+
+func runEval(cases []models.LeadRecord, gen TextGenerator) *models.ScoringEvalReport {
+	agent := NewScoringAgentWithGenerator(gen)
+	return EvaluateScoring(context.Background(), &BatchScorer{Agent: agent, Workers: 4}, cases)
+}
+
+func TestScoringEvalReplay(t *testing.T) {
+	cases := loadEvalCases(t)
+	report := runEval(cases, NewReplayGenerator(cases))
+
+	if report.Failed != 0 {
+		for _, c := range report.Cases {
+			if c.Error != "" {
+				t.Errorf("caso %s: %s", c.ID, c.Error)
+			}
+		}
+	}
+	th := ScoringEvalThresholds{MinAccuracy: 0.8, MinScoreInRange: 0.8, MaxDimensionMAE: 3}
+	if err := CheckScoringEval(report, th); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScoringEvalDetectsRegression(t *testing.T) {
+	cases := loadEvalCases(t)
+	baseline := runEval(cases, NewReplayGenerator(cases))
+
+	cold := func(ctx context.Context, input *AgentInput, prompt string) (string, error) {
+		return coldResponse, nil
+	}
+	report := runEval(cases, cold)
+	CompareScoringEval(report, baseline)
+
+	err := CheckScoringEval(report, ScoringEvalThresholds{MinAccuracy: 0.8, MaxAccuracyDrop: 0.1})
+	if err == nil {
+		t.Fatalf("se esperaba que la evaluación fallara (accuracy %.2f)", report.Accuracy)
+	}
+	for _, want := range []string{"accuracy", "cayó"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("el error no menciona %q: %v", want, err)
+		}
+	}
+	if report.Drift == nil || len(report.Drift.Changed) == 0 {
+		t.Error("se esperaban casos con drift contra la línea base")
+	}
+}
+
+func TestCheckScoringEvalEmpty(t *testing.T) {
+	if err := CheckScoringEval(&models.ScoringEvalReport{}, ScoringEvalThresholds{}); err == nil {
+		t.Fatal("una evaluación sin casos debe fallar")
+	}
+}
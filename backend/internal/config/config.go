@@ -3,22 +3,148 @@ package config
 import (
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	GeminiAPIKey    string
-	GeminiModel     string
-	Port            string
-	BOBAPIBaseURL   string
-	CORSOrigins     string
-	FrontendURL     string
+	GeminiAPIKey  string
+	GeminiModel   string
+	Port          string
+	BOBAPIBaseURL string
+	CORSOrigins   string
+	FrontendURL   string
+	PromptsDir    string
+
+	// Formato de respuestas por WhatsApp
+	WhatsAppMaxMessageLen int
+	WhatsAppMaxMessages   int
+
+	// Atención humana (handoff)
+	WhatsAppSendURL   string
+	HandoffWebhookURL string
+
+	// Escalaciones automáticas
+	EscalationRulesFile     string
+	EscalationWebhookURL    string
+	EscalationEmailTo       string
+	EscalationWhatsAppGroup string
+
+	// Seguimientos programados
+	FollowUpCheckSeconds int
+
+	// Asignación de leads a especialistas
+	AssignmentStrategy string
+	SpecialistTimezone string
+
+	// Sincronización con CRM
+	CRMConnectors   string
+	CRMWebhookURL   string
+	CRMHubSpotURL   string
+	CRMHubSpotToken string
+	CRMCSVDir       string
+	CRMSyncSeconds  int
+
+	// Registro de decisiones del orchestrator
+	DecisionLogMax int
+
+	// Trace por turno de chat
+	TraceEnabled  bool
+	TraceRedact   bool
+	TraceMaxTurns int
+	TracePrompts  bool
+
+	// OpenTelemetry
+	OTelServiceName    string
+	OTelTracesExporter string
+
+	// Métricas Prometheus: minutos sin mensajes para dejar de contar una sesión como activa
+	MetricsActiveSessionMinutes int
+
+	// Consumo de LLM: precios (USD por millón de tokens) y presupuestos (0 = sin límite)
+	LLMPricesJSON         string
+	LLMSessionTokenBudget int
+	LLMDailyBudgetUSD     float64
+	LLMBudgetModel        string
+	LLMBudgetHistory      int
+	UsageLogMax           int
+
+	// Resiliencia del LLM: timeout por intento (LLM_TIMEOUTS_JSON ajusta por agente),
+	// reintentos con backoff, circuit breaker por modelo y modelo de respaldo
+	LLMTimeoutSeconds         int
+	LLMTimeoutsJSON           string
+	LLMMaxRetries             int
+	LLMRetryBaseMs            int
+	LLMBreakerFailures        int
+	LLMBreakerCooldownSeconds int
+	LLMFallbackModel          string
+
+	// Clasificador local de intenciones: responde sin LLM los mensajes obvios
+	// (saludos, agradecimientos, spam, FAQs) con confianza mayor o igual al umbral
+	IntentFastPathEnabled   bool
+	IntentFastPathThreshold float64
+	IntentModelFile         string
+
+	// Cache de respuestas del FAQ agent: vigencia, tamaño y similitud mínima entre preguntas
+	FAQCacheEnabled    bool
+	FAQCacheTTLMinutes int
+	FAQCacheMaxEntries int
+	FAQCacheSimilarity float64
+
+	// Guardrails sobre mensajes entrantes y respuestas (acciones por regla en GuardrailRulesFile)
+	GuardrailsEnabled           bool
+	GuardrailRulesFile          string
+	GuardrailMaxMessageChars    int
+	GuardrailFloodMessages      int
+	GuardrailFloodWindowSeconds int
+	GuardrailRepeatLimit        int
+	GuardrailMaxPenalty         int // tope de la penalidad acumulada por sesión
+	GuardrailAllowedDomains     string
+	GuardrailCompetitors        string
+	GuardrailLogMax             int
+
+	// Datos personales (DNI, RUC, teléfonos, emails, placas) en transcripciones, logs y webhooks
+	PIIMode        string // redact, tokenize u off
+	PIITokenSecret string
+	PIIAccessKey   string // clave para leer los datos de contacto del lead
+
+	// Autenticación: API keys para integraciones de canal (nombre:rol:clave) y JWT para el dashboard
+	AuthEnabled         bool
+	AuthJWTSecret       string
+	AuthTokenTTLMinutes int
+	AuthAPIKeys         string
+	AuthUsersFile       string
+
+	// Límite de mensajes al chat: token buckets por sesión, IP y API key (ráfaga y recarga por minuto)
+	RateLimitEnabled          bool
+	RateLimitSessionBurst     int
+	RateLimitSessionPerMinute float64
+	RateLimitIPBurst          int
+	RateLimitIPPerMinute      float64
+	RateLimitKeyBurst         int
+	RateLimitKeyPerMinute     float64
+	RateLimitStore            string // memory o redis (compartido entre instancias)
+	RateLimitRedisURL         string
+	TrustedProxies            string // proxies cuyo X-Forwarded-For se usa para la IP del cliente
 }
 
 var AppConfig *Config
 
 func LoadConfig() {
+	LoadOfflineConfig()
+
+	if AppConfig.GeminiAPIKey == "" {
+		log.Fatal("GEMINI_API_KEY es requerido")
+	}
+
+	log.Printf("Configuración cargada - Puerto: %s, Modelo: %s", AppConfig.Port, AppConfig.GeminiModel)
+}
+
+// LoadOfflineConfig carga la configuración sin exigir GEMINI_API_KEY, para herramientas
+// y evaluaciones que no llaman al modelo
+func LoadOfflineConfig() {
 	// Cargar .env
 	if err := godotenv.Load(); err != nil {
 		log.Println("No se encontró archivo .env, usando variables de entorno del sistema")
@@ -31,13 +157,99 @@ func LoadConfig() {
 		BOBAPIBaseURL: getEnv("BOB_API_BASE_URL", "https://apiv3.somosbob.com/v3"),
 		CORSOrigins:   getEnv("CORS_ORIGINS", "http://localhost:5173,http://localhost:3000"),
 		FrontendURL:   getEnv("FRONTEND_URL", "http://localhost:5173"),
-	}
+		PromptsDir:    getEnv("PROMPTS_DIR", filepath.Join("data", "prompts")),
 
-	if AppConfig.GeminiAPIKey == "" {
-		log.Fatal("GEMINI_API_KEY es requerido")
-	}
+		WhatsAppMaxMessageLen: getEnvInt("WHATSAPP_MAX_MESSAGE_LEN", 700),
+		WhatsAppMaxMessages:   getEnvInt("WHATSAPP_MAX_MESSAGES", 4),
 
-	log.Printf("Configuración cargada - Puerto: %s, Modelo: %s", AppConfig.Port, AppConfig.GeminiModel)
+		WhatsAppSendURL:   getEnv("WHATSAPP_SEND_URL", "http://localhost:8080/api/send"),
+		HandoffWebhookURL: getEnv("HANDOFF_WEBHOOK_URL", ""),
+
+		EscalationRulesFile:     getEnv("ESCALATION_RULES_FILE", filepath.Join("data", "escalation_rules.json")),
+		EscalationWebhookURL:    getEnv("ESCALATION_WEBHOOK_URL", ""),
+		EscalationEmailTo:       getEnv("ESCALATION_EMAIL_TO", ""),
+		EscalationWhatsAppGroup: getEnv("ESCALATION_WHATSAPP_GROUP", ""),
+
+		FollowUpCheckSeconds: getEnvInt("FOLLOWUP_CHECK_SECONDS", 60),
+
+		AssignmentStrategy: getEnv("ASSIGNMENT_STRATEGY", "skill"),
+		SpecialistTimezone: getEnv("SPECIALIST_TIMEZONE", "America/Lima"),
+
+		CRMConnectors:   getEnv("CRM_CONNECTORS", ""),
+		CRMWebhookURL:   getEnv("CRM_WEBHOOK_URL", ""),
+		CRMHubSpotURL:   getEnv("CRM_HUBSPOT_URL", "https://api.hubapi.com"),
+		CRMHubSpotToken: getEnv("CRM_HUBSPOT_TOKEN", ""),
+		CRMCSVDir:       getEnv("CRM_CSV_DIR", filepath.Join("data", "crm")),
+		CRMSyncSeconds:  getEnvInt("CRM_SYNC_SECONDS", 15),
+
+		DecisionLogMax: getEnvInt("DECISION_LOG_MAX", 20000),
+
+		TraceEnabled:  getEnvBool("TRACE_ENABLED", true),
+		TraceRedact:   getEnvBool("TRACE_REDACT", false),
+		TraceMaxTurns: getEnvInt("TRACE_MAX_TURNS", 200),
+		TracePrompts:  getEnvBool("TRACE_PROMPTS", true),
+
+		OTelServiceName:    getEnv("OTEL_SERVICE_NAME", "bob-backend"),
+		OTelTracesExporter: getEnv("OTEL_TRACES_EXPORTER", "none"),
+
+		MetricsActiveSessionMinutes: getEnvInt("METRICS_ACTIVE_SESSION_MINUTES", 30),
+
+		LLMPricesJSON:         getEnv("LLM_PRICES_JSON", ""),
+		LLMSessionTokenBudget: getEnvInt("LLM_SESSION_TOKEN_BUDGET", 0),
+		LLMDailyBudgetUSD:     getEnvFloat("LLM_DAILY_BUDGET_USD", 0),
+		LLMBudgetModel:        getEnv("LLM_BUDGET_MODEL", "gemini-2.5-flash-lite"),
+		LLMBudgetHistory:      getEnvInt("LLM_BUDGET_HISTORY_MESSAGES", 6),
+		UsageLogMax:           getEnvInt("USAGE_LOG_MAX", 50000),
+
+		LLMTimeoutSeconds:         getEnvInt("LLM_TIMEOUT_SECONDS", 20),
+		LLMTimeoutsJSON:           getEnv("LLM_TIMEOUTS_JSON", `{"Orchestrator":10,"FAQ_Agent":15,"Auction_Agent":20,"Scoring_Agent":30}`),
+		LLMMaxRetries:             getEnvInt("LLM_MAX_RETRIES", 2),
+		LLMRetryBaseMs:            getEnvInt("LLM_RETRY_BASE_MS", 300),
+		LLMBreakerFailures:        getEnvInt("LLM_BREAKER_FAILURES", 5),
+		LLMBreakerCooldownSeconds: getEnvInt("LLM_BREAKER_COOLDOWN_SECONDS", 30),
+		LLMFallbackModel:          getEnv("LLM_FALLBACK_MODEL", "gemini-2.5-flash-lite"),
+
+		IntentFastPathEnabled:   getEnvBool("INTENT_FASTPATH_ENABLED", true),
+		IntentFastPathThreshold: getEnvFloat("INTENT_FASTPATH_THRESHOLD", 0.9),
+		IntentModelFile:         getEnv("INTENT_MODEL_FILE", filepath.Join("data", "intent_model.json")),
+
+		FAQCacheEnabled:    getEnvBool("FAQ_CACHE_ENABLED", true),
+		FAQCacheTTLMinutes: getEnvInt("FAQ_CACHE_TTL_MINUTES", 1440),
+		FAQCacheMaxEntries: getEnvInt("FAQ_CACHE_MAX_ENTRIES", 2000),
+		FAQCacheSimilarity: getEnvFloat("FAQ_CACHE_SIMILARITY", 0.85),
+
+		GuardrailsEnabled:           getEnvBool("GUARDRAILS_ENABLED", true),
+		GuardrailRulesFile:          getEnv("GUARDRAIL_RULES_FILE", filepath.Join("data", "guardrail_rules.json")),
+		GuardrailMaxMessageChars:    getEnvInt("GUARDRAIL_MAX_MESSAGE_CHARS", 1000),
+		GuardrailFloodMessages:      getEnvInt("GUARDRAIL_FLOOD_MESSAGES", 8),
+		GuardrailFloodWindowSeconds: getEnvInt("GUARDRAIL_FLOOD_WINDOW_SECONDS", 60),
+		GuardrailRepeatLimit:        getEnvInt("GUARDRAIL_REPEAT_LIMIT", 3),
+		GuardrailMaxPenalty:         getEnvInt("GUARDRAIL_MAX_PENALTY", 30),
+		GuardrailAllowedDomains:     getEnv("GUARDRAIL_ALLOWED_DOMAINS", "somosbob.com"),
+		GuardrailCompetitors:        getEnv("GUARDRAIL_COMPETITORS", "mercadolibre,mercado libre,olx,neoauto,autocosmos,copart,marketplace"),
+		GuardrailLogMax:             getEnvInt("GUARDRAIL_LOG_MAX", 20000),
+
+		PIIMode:        getEnv("PII_MODE", "redact"),
+		PIITokenSecret: getEnv("PII_TOKEN_SECRET", ""),
+		PIIAccessKey:   getEnv("PII_ACCESS_KEY", ""),
+
+		AuthEnabled:         getEnvBool("AUTH_ENABLED", false),
+		AuthJWTSecret:       getEnv("AUTH_JWT_SECRET", ""),
+		AuthTokenTTLMinutes: getEnvInt("AUTH_TOKEN_TTL_MINUTES", 720),
+		AuthAPIKeys:         getEnv("AUTH_API_KEYS", ""),
+		AuthUsersFile:       getEnv("AUTH_USERS_FILE", filepath.Join("data", "users.json")),
+
+		RateLimitEnabled:          getEnvBool("RATE_LIMIT_ENABLED", true),
+		RateLimitSessionBurst:     getEnvInt("RATE_LIMIT_SESSION_BURST", 5),
+		RateLimitSessionPerMinute: getEnvFloat("RATE_LIMIT_SESSION_PER_MINUTE", 12),
+		RateLimitIPBurst:          getEnvInt("RATE_LIMIT_IP_BURST", 20),
+		RateLimitIPPerMinute:      getEnvFloat("RATE_LIMIT_IP_PER_MINUTE", 40),
+		RateLimitKeyBurst:         getEnvInt("RATE_LIMIT_KEY_BURST", 100),
+		RateLimitKeyPerMinute:     getEnvFloat("RATE_LIMIT_KEY_PER_MINUTE", 600),
+		RateLimitStore:            getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimitRedisURL:         getEnv("RATE_LIMIT_REDIS_URL", "redis://localhost:6379/0"),
+		TrustedProxies:            getEnv("TRUSTED_PROXIES", "127.0.0.1,::1"),
+	}
 }
 
 func getEnv(key, defaultValue string) string {
@@ -47,3 +259,27 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
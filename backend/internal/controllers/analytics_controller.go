@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsController struct {
+	analyticsService *services.AnalyticsService
+	decisionLog      *services.DecisionLogService
+}
+
+func NewAnalyticsController() *AnalyticsController {
+	return &AnalyticsController{
+		analyticsService: services.GetAnalyticsService(),
+		decisionLog:      services.GetDecisionLogService(),
+	}
+}
+
+// GetSummary devuelve todas las métricas; acepta from, to, channel y bucket (day, week, month)
+func (a *AnalyticsController) GetSummary(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	summary, err := a.analyticsService.Summary(filter)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"analytics": summary,
+	})
+}
+
+// GetTimeSeries devuelve leads, score promedio y categorías por intervalo
+func (a *AnalyticsController) GetTimeSeries(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	series, err := a.analyticsService.TimeSeries(filter)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"bucket":  filter.Bucket,
+		"series":  series,
+	})
+}
+
+// GetCategories devuelve la distribución de categorías por canal y por día
+func (a *AnalyticsController) GetCategories(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+	filter.Bucket = services.BucketDay
+
+	byDay, err := a.analyticsService.TimeSeries(filter)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"byChannel": a.analyticsService.CategoryByChannel(filter),
+		"byDay":     byDay,
+	})
+}
+
+func (a *AnalyticsController) GetDimensions(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"dimensions": a.analyticsService.Dimensions(filter),
+	})
+}
+
+func (a *AnalyticsController) GetFunnel(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"funnel":  a.analyticsService.Funnel(filter),
+	})
+}
+
+func (a *AnalyticsController) GetTimeToHot(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"timeToFirstHot": a.analyticsService.TimeToFirstHot(filter),
+	})
+}
+
+// GetIntents resume las decisiones del orchestrator. Además de from, to, channel y bucket
+// acepta threshold (confianza considerada baja, 0.6 por defecto) y top (mensajes ambiguos, 20)
+func (a *AnalyticsController) GetIntents(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	threshold, err := strconv.ParseFloat(ctx.DefaultQuery("threshold", "0.6"), 64)
+	if err != nil || threshold < 0 || threshold > 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "threshold debe ser un número entre 0 y 1",
+		})
+		return
+	}
+	top, err := strconv.Atoi(ctx.DefaultQuery("top", "20"))
+	if err != nil || top < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "top debe ser un entero positivo",
+		})
+		return
+	}
+
+	intents, err := a.analyticsService.Intents(filter, threshold, top)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"intents": intents,
+	})
+}
+
+// GetDecisions lista las decisiones del orchestrator, de la más reciente a la más antigua
+func (a *AnalyticsController) GetDecisions(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if err != nil || limit < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "limit debe ser un entero positivo",
+		})
+		return
+	}
+
+	decisions := a.decisionLog.List(models.DecisionFilter{
+		From:      filter.From,
+		To:        filter.To,
+		Channel:   filter.Channel,
+		Intent:    ctx.Query("intent"),
+		SessionID: ctx.Query("sessionId"),
+		Limit:     limit,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"count":     len(decisions),
+		"decisions": decisions,
+	})
+}
+
+// parseAnalyticsFilter lee from/to (AAAA-MM-DD o RFC3339, to inclusive), channel y bucket.
+// Si los parámetros son inválidos responde 400 y devuelve false.
+func parseAnalyticsFilter(ctx *gin.Context) (models.AnalyticsFilter, bool) {
+	from, errFrom := services.ParseExportDate(ctx.Query("from"), false)
+	to, errTo := services.ParseExportDate(ctx.Query("to"), true)
+	bucket, errBucket := services.NormalizeBucket(ctx.Query("bucket"))
+	if err := errors.Join(errFrom, errTo, errBucket); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return models.AnalyticsFilter{}, false
+	}
+
+	return models.AnalyticsFilter{
+		From:    from,
+		To:      to,
+		Channel: ctx.Query("channel"),
+		Bucket:  bucket,
+	}, true
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"bob-hackathon/internal/telemetry"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey guarda en el contexto de gin a quien hace el request
+const principalKey = "authPrincipal"
+
+type AuthController struct {
+	authService *services.AuthService
+}
+
+func NewAuthController() *AuthController {
+	return &AuthController{
+		authService: services.GetAuthService(),
+	}
+}
+
+// Login cambia usuario y contraseña del dashboard por un JWT
+func (a *AuthController) Login(ctx *gin.Context) {
+	var req models.LoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Datos inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	token, expiresAt, principal, err := a.authService.Login(req.Username, req.Password)
+	switch {
+	case errors.Is(err, services.ErrLoginUnavailable):
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error":   "Usuario o contraseña incorrectos",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.LoginResponse{
+		Success:   true,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      principal,
+	})
+}
+
+// Me devuelve el usuario o la API key del request
+func (a *AuthController) Me(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"authEnabled": a.authService.Enabled(),
+		"user":        currentPrincipal(ctx),
+	})
+}
+
+// Identify reconoce las credenciales si vienen, sin exigirlas; las rutas públicas
+// (chat) lo usan para distinguir a las integraciones de canal
+func (a *AuthController) Identify() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, err := a.authService.Authenticate(ctx.Request)
+		if err != nil && !errors.Is(err, services.ErrAuthMissing) && a.authService.Enabled() {
+			telemetry.CountAuth("none", "invalid")
+			abortUnauthorized(ctx)
+			return
+		}
+		if principal != nil {
+			ctx.Set(principalKey, principal)
+		}
+		ctx.Next()
+	}
+}
+
+// Require exige credenciales con alguno de los roles (admin siempre pasa). Con
+// AUTH_ENABLED=false deja pasar todo, pero igual reconoce las credenciales que vengan.
+func (a *AuthController) Require(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// Un grupo y su ruta pueden exigir roles distintos; se autentica una sola vez
+		principal := currentPrincipal(ctx)
+		fresh := principal == nil
+		var err error
+		if fresh {
+			principal, err = a.authService.Authenticate(ctx.Request)
+			if principal != nil {
+				ctx.Set(principalKey, principal)
+			}
+		}
+		if !a.authService.Enabled() {
+			ctx.Next()
+			return
+		}
+
+		switch {
+		case errors.Is(err, services.ErrAuthMissing):
+			telemetry.CountAuth("none", "missing")
+			abortUnauthorized(ctx)
+			return
+		case err != nil:
+			telemetry.CountAuth("none", "invalid")
+			abortUnauthorized(ctx)
+			return
+		case !a.authService.Allows(principal, roles...):
+			telemetry.CountAuth(principal.Method, "forbidden")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Permiso insuficiente para esta acción",
+			})
+			return
+		}
+
+		if fresh {
+			telemetry.CountAuth(principal.Method, "ok")
+		}
+		ctx.Next()
+	}
+}
+
+func abortUnauthorized(ctx *gin.Context) {
+	ctx.Header("WWW-Authenticate", `Bearer realm="bob"`)
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"error":   "Autenticación requerida: header Authorization: Bearer <token> o X-API-Key",
+	})
+}
+
+// currentPrincipal devuelve quien hace el request, o nil si no trajo credenciales
+func currentPrincipal(ctx *gin.Context) *models.AuthPrincipal {
+	if value, ok := ctx.Get(principalKey); ok {
+		if principal, ok := value.(*models.AuthPrincipal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// actorFor usa el usuario autenticado como actor de la auditoría; sin credenciales
+// se queda con el que vino en el request
+func actorFor(ctx *gin.Context, fallback string) string {
+	if principal := currentPrincipal(ctx); principal != nil {
+		return principal.Subject
+	}
+	return strings.TrimSpace(fallback)
+}
+
+// specialistFor es la identidad del especialista que atiende (handoff, escalaciones): con
+// credenciales es el especialista asociado al usuario, o el usuario si no tiene uno; sin
+// credenciales se queda con el que vino en el request
+func specialistFor(ctx *gin.Context, fallback string) string {
+	if principal := currentPrincipal(ctx); principal != nil && principal.SpecialistID != "" {
+		return principal.SpecialistID
+	}
+	return actorFor(ctx, fallback)
+}
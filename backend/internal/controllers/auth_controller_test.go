@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireRoles(t *testing.T) {
+	users := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(users, []byte(`[{"username":"ana","passwordHash":"x","role":"specialist","specialistId":"sp-ana"}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.AppConfig = &config.Config{
+		AuthEnabled:   true,
+		AuthJWTSecret: "0123456789abcdef0123456789abcdef",
+		AuthAPIKeys:   "whatsapp:channel:clave-bot,panel:admin:clave-admin",
+		AuthUsersFile: users,
+	}
+	auth := NewAuthController()
+	token, _, err := services.GetAuthService().IssueToken("ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(ctx *gin.Context) { ctx.JSON(http.StatusOK, gin.H{"specialist": specialistFor(ctx, "")}) }
+	leads := router.Group("/leads", auth.Require(services.AuthRoleSpecialist))
+	leads.GET("", ok)
+	leads.POST("/reset", auth.Require(services.AuthRoleAdmin), ok)
+
+	cases := []struct {
+		name, method, path, header, value string
+		want                              int
+	}{
+		{"sin credenciales", "GET", "/leads", "", "", http.StatusUnauthorized},
+		{"clave inválida", "GET", "/leads", "X-API-Key", "otra", http.StatusUnauthorized},
+		{"token alterado", "GET", "/leads", "Authorization", "Bearer " + token + "x", http.StatusUnauthorized},
+		{"canal sin permiso", "GET", "/leads", "X-API-Key", "clave-bot", http.StatusForbidden},
+		{"especialista", "GET", "/leads", "Authorization", "Bearer " + token, http.StatusOK},
+		{"admin", "GET", "/leads", "X-API-Key", "clave-admin", http.StatusOK},
+		{"especialista en ruta de admin", "POST", "/leads/reset", "Authorization", "Bearer " + token, http.StatusForbidden},
+		{"admin en ruta de admin", "POST", "/leads/reset", "Authorization", "Bearer clave-admin", http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			if c.header != "" {
+				req.Header.Set(c.header, c.value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != c.want {
+				t.Errorf("%s %s = %d, se esperaba %d: %s", c.method, c.path, rec.Code, c.want, rec.Body.String())
+			}
+			if c.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("un 401 debe traer WWW-Authenticate")
+			}
+		})
+	}
+
+	// El especialista del request sale del usuario autenticado
+	req := httptest.NewRequest("GET", "/leads", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if body := rec.Body.String(); body != `{"specialist":"sp-ana"}` {
+		t.Errorf("specialistFor: %s", body)
+	}
+}
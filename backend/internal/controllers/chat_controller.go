@@ -4,20 +4,38 @@ import (
 	"bob-hackathon/internal/agents"
 	"bob-hackathon/internal/models"
 	"bob-hackathon/internal/services"
+	"bob-hackathon/internal/telemetry"
 	"context"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// degradedLLM marca los turnos respondidos sin LLM (ruteo por palabras clave, FAQ textual)
+const degradedLLM = "llm"
+
 type ChatController struct {
-	orchestrator   agents.Agent
-	faqAgent       agents.Agent
-	auctionAgent   agents.Agent
-	scoringAgent   agents.Agent
-	sessionService *services.SessionService
+	orchestrator     agents.Agent
+	faqAgent         agents.Agent
+	auctionAgent     agents.Agent
+	scoringAgent     agents.Agent
+	sessionService   *services.SessionService
+	formatterService *services.FormatterService
+	funnelService    *services.FunnelService
+	handoffService   *services.HandoffService
+	escalations      *services.EscalationService
+	followUps        *services.FollowUpService
+	specialists      *services.SpecialistService
+	decisionLog      *services.DecisionLogService
+	traces           *services.TraceService
+	usage            *services.UsageService
+	guardrails       *services.GuardrailService
+	pii              *services.PIIService
+	auth             *services.AuthService
 }
 
 func NewChatController() *ChatController {
@@ -42,11 +60,23 @@ func NewChatController() *ChatController {
 	}
 
 	return &ChatController{
-		orchestrator:   orchestrator,
-		faqAgent:       faqAgent,
-		auctionAgent:   auctionAgent,
-		scoringAgent:   scoringAgent,
-		sessionService: services.GetSessionService(),
+		orchestrator:     orchestrator,
+		faqAgent:         faqAgent,
+		auctionAgent:     auctionAgent,
+		scoringAgent:     scoringAgent,
+		sessionService:   services.GetSessionService(),
+		formatterService: services.GetFormatterService(),
+		funnelService:    services.GetFunnelService(),
+		handoffService:   services.GetHandoffService(),
+		escalations:      services.GetEscalationService(),
+		followUps:        services.GetFollowUpService(),
+		specialists:      services.GetSpecialistService(),
+		decisionLog:      services.GetDecisionLogService(),
+		traces:           services.GetTraceService(),
+		usage:            services.GetUsageService(),
+		guardrails:       services.GetGuardrailService(),
+		pii:              services.GetPIIService(),
+		auth:             services.GetAuthService(),
 	}
 }
 
@@ -60,23 +90,110 @@ func (c *ChatController) SendMessage(ctx *gin.Context) {
 		return
 	}
 
+	// Con autenticación, las sesiones de canales que no son web (wa-<número>) solo las
+	// escriben las integraciones de canal; se mira el canal de la sesión si ya existe
+	channel := req.Channel
+	if existing := c.sessionService.GetSession(req.SessionID); existing != nil {
+		channel = existing.Channel
+	}
+	if !c.auth.CanWriteSession(currentPrincipal(ctx), channel) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "El canal " + channel + " requiere una API key de integración",
+		})
+		return
+	}
+
 	// Obtener o crear sesión
 	session := c.sessionService.GetOrCreateSession(req.SessionID, req.Channel)
 
+	// Guardrails: largo, flood, repetición, lenguaje, enlaces y prompt injection
+	check := c.guardrails.CheckInput(session.SessionID, req.Channel, req.Message, session.Messages)
+	if check.Blocked {
+		c.replyBlocked(ctx, session, req, check)
+		return
+	}
+
+	// Datos personales: se guardan aparte en el lead y el mensaje sigue redactado
+	message, newContact := c.pii.ProcessMessage(session.SessionID, check.Message)
+	if newContact {
+		c.sessionService.SetLeadContact(session.SessionID, true)
+	}
+	req.Message = message
+
 	// Agregar mensaje del usuario
 	c.sessionService.AddMessage(session.SessionID, "user", req.Message)
 
+	// El prospecto respondió: los seguimientos pendientes ya no aplican
+	c.followUps.CancelForSession(session.SessionID, "user_replied")
+
+	// Embudo: acumular slots detectados en el mensaje
+	messageSlots := c.funnelService.ExtractSlots(req.Message)
+	slots := c.sessionService.MergeSlots(session.SessionID, messageSlots)
+
+	// Modo humano: el especialista responde, los agentes no intervienen
+	if session.IsHumanMode() {
+		c.handoffService.NotifyInbound(session.SessionID, req.Message)
+
+		ctx.JSON(http.StatusOK, models.ChatResponse{
+			Success:   true,
+			SessionID: session.SessionID,
+			Replies:   []string{},
+			LeadScore: session.LeadScore,
+			Category:  session.Category,
+			Stage:     session.Stage,
+			HumanMode: true,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Se conserva la traza del request pero no su cancelación: si el cliente corta,
+	// el turno igual se completa y se guarda, como antes
+	reqCtx := context.WithoutCancel(ctx.Request.Context())
+	telemetry.Annotate(reqCtx,
+		telemetry.AttrSessionID.String(session.SessionID),
+		telemetry.AttrChannel.String(req.Channel),
+	)
+
+	// Trace del turno: se guarda al terminar, también si el orchestrator falla
+	turn := &models.TurnTrace{
+		SessionID:   session.SessionID,
+		Channel:     req.Channel,
+		Message:     req.Message,
+		TraceID:     telemetry.TraceID(reqCtx),
+		StageBefore: session.Stage,
+		Guardrails:  check.Applied,
+		StartedAt:   time.Now(),
+	}
+	defer c.traces.Record(turn)
+
 	// FASE 1: ORCHESTRATOR - Analiza intención y rutea
 	agentInput := &agents.AgentInput{
 		Message:             req.Message,
 		SessionID:           session.SessionID,
 		Channel:             req.Channel,
 		ConversationHistory: session.Messages,
+		Stage:               session.Stage,
+		Slots:               slots,
+	}
+
+	// Presupuesto de LLM superado: modelo económico, historial corto y sin scoring
+	if reason := c.usage.Exceeded(session.SessionID); reason != "" {
+		turn.Degraded = reason
+		if model := c.usage.BudgetModel(); model != "" {
+			reqCtx = agents.WithModel(reqCtx, model)
+		}
+		agentInput.ConversationHistory = c.usage.TrimHistory(session.Messages)
+		log.Printf("💸 Presupuesto %s superado para %s: modo económico", reason, session.SessionID)
 	}
 
-	orchestratorOutput, err := c.orchestrator.Process(context.Background(), agentInput)
+	orchestratorOutput, orchestratorElapsed, err := c.runAgent(reqCtx, c.orchestrator, agentInput)
+	c.recordDecision(agentInput, orchestratorOutput, err, orchestratorElapsed)
+	turn.Orchestrator = agentCall(c.orchestrator.Name(), orchestratorOutput, err, orchestratorElapsed)
 	if err != nil {
 		log.Printf("❌ Error en Orchestrator: %v", err)
+		turn.Errors = append(turn.Errors, "orchestrator: "+err.Error())
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Error procesando mensaje: " + err.Error(),
@@ -84,66 +201,139 @@ func (c *ChatController) SendMessage(ctx *gin.Context) {
 		return
 	}
 
+	if orchestratorOutput.Degraded {
+		markDegraded(turn)
+	}
+
+	// Mover el embudo según la intención detectada antes de llamar al subagente
+	agentInput.Stage = c.advanceFunnel(session.SessionID, services.FunnelEvent{
+		Intent: orchestratorOutput.IntentDetected,
+		Slots:  slots,
+	})
+
+	turn.Decision = &models.TraceDecision{
+		Intent:      orchestratorOutput.IntentDetected,
+		Confidence:  orchestratorOutput.Confidence,
+		ShouldRoute: orchestratorOutput.ShouldRoute,
+		RouteTo:     orchestratorOutput.RouteTo,
+		Reasoning:   orchestratorOutput.Reasoning,
+		DecidedBy:   orchestratorOutput.DecidedBy,
+	}
+
 	var finalReply string
+	trace := &models.ReplyTrace{
+		Intent: orchestratorOutput.IntentDetected,
+		Agent:  c.orchestrator.Name(),
+	}
+	trace.AddPrompt(orchestratorOutput.Prompt)
 
 	// FASE 2: ROUTING - Según decisión del orchestrator
 	if orchestratorOutput.ShouldRoute {
+		var subAgent agents.Agent
 		var subAgentOutput *agents.AgentOutput
 
 		switch orchestratorOutput.RouteTo {
 		case "faq_agent":
 			log.Printf("🔀 Ruteando a FAQ Agent")
-			subAgentOutput, err = c.faqAgent.Process(context.Background(), agentInput)
+			subAgent = c.faqAgent
 		case "auction_agent":
 			log.Printf("🔀 Ruteando a Auction Agent")
-			subAgentOutput, err = c.auctionAgent.Process(context.Background(), agentInput)
+			subAgent = c.auctionAgent
 		default:
 			log.Printf("⚠️ RouteTo desconocido: %s, usando respuesta del orchestrator", orchestratorOutput.RouteTo)
 			finalReply = orchestratorOutput.Response
+			turn.Errors = append(turn.Errors, "routeTo desconocido: "+orchestratorOutput.RouteTo)
+		}
+
+		if subAgent != nil {
+			var elapsed time.Duration
+			subAgentOutput, elapsed, err = c.runAgent(reqCtx, subAgent, agentInput)
+			turn.SubAgent = agentCall(subAgent.Name(), subAgentOutput, err, elapsed)
 		}
 
 		if err != nil {
 			log.Printf("❌ Error en SubAgent: %v", err)
+			turn.Errors = append(turn.Errors, "subagente: "+err.Error())
 			finalReply = orchestratorOutput.Response // Fallback a respuesta del orchestrator
 		} else if subAgentOutput != nil {
 			finalReply = subAgentOutput.Response
+			trace.Agent = orchestratorOutput.RouteTo
+			trace.AddPrompt(subAgentOutput.Prompt)
+			if subAgentOutput.Degraded {
+				markDegraded(turn)
+			}
 		}
 	} else {
 		// El orchestrator maneja directamente (general, spam, ambiguo)
 		finalReply = orchestratorOutput.Response
 	}
 
+	// Guardrails de salida: sin instrucciones internas, competidores ni promesas
+	finalReply, applied := c.guardrails.FilterOutput(session.SessionID, req.Channel, finalReply)
+	turn.Guardrails = append(turn.Guardrails, applied...)
+	if check.Warning != "" {
+		finalReply = check.Warning + "\n\n" + finalReply
+	}
+
+	// Adaptar la respuesta al canal (markdown, largo máximo, múltiples mensajes)
+	replies := c.formatterService.FormatReply(req.Channel, finalReply)
+	finalReply = strings.Join(replies, "\n\n")
+	turn.Reply = finalReply
+	turn.Replies = replies
+
 	// Agregar respuesta del asistente
-	c.sessionService.AddMessage(session.SessionID, "assistant", finalReply)
+	c.sessionService.AddMessageWithTrace(session.SessionID, "assistant", finalReply, trace)
 
 	// FASE 3: SCORING - Calcular después de 3+ mensajes
 	var leadScore int
 	var category string
 
-	if len(session.Messages) >= 6 { // 3 pares user-assistant mínimo
+	if len(session.Messages) >= 6 && turn.Degraded == "" { // 3 pares user-assistant mínimo
 		log.Printf("📊 Calculando scoring con %d mensajes", len(session.Messages))
 
-		scoringOutput, err := c.scoringAgent.Process(context.Background(), agentInput)
+		scoringOutput, scoringElapsed, err := c.runAgent(reqCtx, c.scoringAgent, agentInput)
+		turn.Scoring = agentCall(c.scoringAgent.Name(), scoringOutput, err, scoringElapsed)
 		if err != nil {
 			log.Printf("⚠️ Error en ScoringAgent: %v", err)
+			turn.Errors = append(turn.Errors, "scoring: "+err.Error())
 			leadScore = 0
 			category = "cold"
 		} else if scoringOutput.ScoringData != nil {
-			leadScore = scoringOutput.ScoringData.TotalScore
-			category = scoringOutput.ScoringData.Category
+			turn.ScoringData = scoringOutput.ScoringData
+			trace.AddPrompt(scoringOutput.Prompt)
+			c.sessionService.AddTracePrompt(session.SessionID, scoringOutput.Prompt)
+			// Las reglas de guardrails disparadas en la sesión restan puntos
+			leadScore = services.PenalizedScore(scoringOutput.ScoringData.TotalScore, session.GuardrailPenalty)
+			category = services.PenalizedCategory(scoringOutput.ScoringData.Category, leadScore)
 
 			// Actualizar lead con scoring detallado
 			lead := &models.Lead{
-				SessionID:    session.SessionID,
-				Channel:      session.Channel,
-				Score:        leadScore,
-				Category:     category,
-				LastMessage:  req.Message,
-				CreatedAt:    session.CreatedAt,
-				UpdatedAt:    time.Now(),
+				SessionID:   session.SessionID,
+				Channel:     session.Channel,
+				Score:       leadScore,
+				Category:    category,
+				LastMessage: req.Message,
+				Dimensions:  scoringOutput.ScoringData.DimensionScores,
+				CreatedAt:   session.CreatedAt,
+				UpdatedAt:   time.Now(),
+
+				GuardrailPenalty: session.GuardrailPenalty,
+				GuardrailFlags:   session.GuardrailFlags,
+				HasContact:       c.pii.HasContact(session.SessionID),
 			}
 			c.sessionService.CreateOrUpdateLead(lead)
 
+			// Los leads hot/warm sin dueño se asignan a un especialista
+			c.specialists.AutoAssign(session.SessionID)
+
+			// Programar el seguimiento según la categoría (hot 4h, warm 24h, cold 1 mes)
+			c.followUps.Schedule(session, scoringOutput.ScoringData)
+
+			agentInput.Stage = c.advanceFunnel(session.SessionID, services.FunnelEvent{
+				Slots:    slots,
+				Category: category,
+			})
+
 			log.Printf("✅ Score calculado: %d/100 - Categoría: %s", leadScore, category)
 		}
 	} else {
@@ -158,19 +348,192 @@ func (c *ChatController) SendMessage(ctx *gin.Context) {
 	// Actualizar score en sesión
 	c.sessionService.UpdateScore(session.SessionID, leadScore, category)
 
+	// Escalar a un especialista si alguna regla aplica (hot, pide asesor, frustración, alto valor)
+	c.escalations.Evaluate(services.EscalationInput{
+		Session:      c.sessionService.Snapshot(session.SessionID),
+		Message:      req.Message,
+		Intent:       orchestratorOutput.IntentDetected,
+		Category:     category,
+		MessageSlots: messageSlots,
+		Slots:        slots,
+	})
+
+	turn.StageAfter = agentInput.Stage
+
 	// Responder
 	response := models.ChatResponse{
 		Success:   true,
 		SessionID: session.SessionID,
 		Reply:     finalReply,
+		Replies:   replies,
 		LeadScore: leadScore,
 		Category:  category,
+		Stage:     agentInput.Stage,
+		Degraded:  turn.Degraded,
+		Guardrail: check.Rule,
 		Timestamp: time.Now(),
+		Trace:     trace,
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
+// replyBlocked responde a un mensaje bloqueado por un guardrail sin llamar a los agentes ni
+// guardarlo en el historial; el turno queda en las trazas con la regla que lo bloqueó
+func (c *ChatController) replyBlocked(ctx *gin.Context, session *models.Session, req models.ChatRequest, check services.GuardrailResult) {
+	replies := c.formatterService.FormatReply(req.Channel, check.Reply)
+	reply := strings.Join(replies, "\n\n")
+
+	c.traces.Record(&models.TurnTrace{
+		SessionID:   session.SessionID,
+		Channel:     req.Channel,
+		Message:     services.RedactPII(check.Message),
+		StageBefore: session.Stage,
+		StageAfter:  session.Stage,
+		Reply:       reply,
+		Replies:     replies,
+		Guardrails:  check.Applied,
+		StartedAt:   time.Now(),
+	})
+
+	ctx.JSON(http.StatusOK, models.ChatResponse{
+		Success:   true,
+		SessionID: session.SessionID,
+		Reply:     reply,
+		Replies:   replies,
+		LeadScore: session.LeadScore,
+		Category:  session.Category,
+		Stage:     session.Stage,
+		Guardrail: check.Rule,
+		Timestamp: time.Now(),
+	})
+}
+
+// runAgent llama al agente dentro de un span con su nombre, la sesión y lo que decidió,
+// y registra los tokens que consumió
+func (c *ChatController) runAgent(ctx context.Context, agent agents.Agent, input *agents.AgentInput) (*agents.AgentOutput, time.Duration, error) {
+	ctx, span := telemetry.Start(ctx, "agent "+agent.Name(),
+		telemetry.AttrAgent.String(agent.Name()),
+		telemetry.AttrSessionID.String(input.SessionID),
+		telemetry.AttrChannel.String(input.Channel),
+	)
+	defer span.End()
+
+	start := time.Now()
+	output, err := agent.Process(ctx, input)
+	elapsed := time.Since(start)
+
+	telemetry.RecordError(span, err)
+	if output != nil {
+		_, degraded := agents.ModelFromContext(ctx)
+		c.usage.Record(input.SessionID, input.Channel, agent.Name(), output.Usage, degraded)
+		if output.Usage != nil {
+			span.SetAttributes(
+				telemetry.AttrModel.String(output.Usage.Model),
+				telemetry.AttrTokens.Int(output.Usage.TotalTokens),
+			)
+		}
+		if output.IntentDetected != "" {
+			span.SetAttributes(
+				telemetry.AttrIntent.String(output.IntentDetected),
+				telemetry.AttrConfidence.Float64(output.Confidence),
+			)
+		}
+		if output.RouteTo != "" {
+			span.SetAttributes(telemetry.AttrRouteTo.String(output.RouteTo))
+		}
+		if output.Prompt != nil {
+			span.SetAttributes(telemetry.AttrPrompt.String(output.Prompt.Name + "@" + output.Prompt.Version))
+		}
+		if len(output.Retrieved) > 0 {
+			span.SetAttributes(telemetry.AttrRetrieved.Int(len(output.Retrieved)))
+		}
+		if output.ScoringData != nil {
+			span.SetAttributes(
+				telemetry.AttrScore.Int(output.ScoringData.TotalScore),
+				telemetry.AttrCategory.String(output.ScoringData.Category),
+			)
+		}
+	}
+	return output, elapsed, err
+}
+
+// markDegraded marca el turno como respondido sin LLM, salvo que ya esté degradado por presupuesto
+func markDegraded(turn *models.TurnTrace) {
+	if turn.Degraded == "" {
+		turn.Degraded = degradedLLM
+	}
+}
+
+// agentCall resume la llamada a un agente para el trace del turno
+func agentCall(name string, output *agents.AgentOutput, err error, elapsed time.Duration) *models.AgentCall {
+	call := &models.AgentCall{
+		Agent:      name,
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	if output != nil {
+		call.Prompt = output.Prompt
+		call.PromptText = output.PromptText
+		call.RawOutput = output.RawOutput
+		call.Retrieved = output.Retrieved
+		call.Usage = output.Usage
+		call.Cached = output.Cached
+	}
+	return call
+}
+
+// recordDecision guarda la decisión del orchestrator (o su error) para la analítica de intenciones
+func (c *ChatController) recordDecision(input *agents.AgentInput, output *agents.AgentOutput, err error, latency time.Duration) {
+	decision := models.OrchestratorDecision{
+		SessionID: input.SessionID,
+		Channel:   input.Channel,
+		Message:   input.Message,
+		LatencyMs: latency.Milliseconds(),
+	}
+	if err != nil {
+		decision.Error = err.Error()
+		telemetry.CountIntent("error")
+	} else if output != nil {
+		telemetry.CountIntent(intentLabel(output.IntentDetected))
+		decision.Intent = output.IntentDetected
+		decision.Confidence = output.Confidence
+		decision.ShouldRoute = output.ShouldRoute
+		decision.RouteTo = output.RouteTo
+		decision.Reasoning = output.Reasoning
+		decision.Prompt = output.Prompt
+		decision.DecidedBy = output.DecidedBy
+	}
+	c.decisionLog.Record(decision)
+}
+
+// intentLabel acota la intención a las conocidas para que el LLM no abra una serie por cada texto
+func intentLabel(intent string) string {
+	switch normalized := services.NormalizeIntent(intent); normalized {
+	case string(agents.IntentFAQ), string(agents.IntentSubasta), string(agents.IntentSpam),
+		string(agents.IntentGeneral), services.IntentAmbiguous:
+		return normalized
+	}
+	return "other"
+}
+
+// advanceFunnel aplica las transiciones del embudo y devuelve la etapa resultante
+func (c *ChatController) advanceFunnel(sessionID string, ev services.FunnelEvent) string {
+	session := c.sessionService.GetSession(sessionID)
+	if session == nil {
+		return ""
+	}
+
+	next, trigger := c.funnelService.NextStage(session.Stage, ev)
+	if trigger != "" {
+		c.sessionService.UpdateStage(sessionID, next, trigger)
+	}
+
+	return next
+}
+
 func (c *ChatController) GetScore(ctx *gin.Context) {
 	var req models.ScoreRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -199,7 +562,7 @@ func (c *ChatController) GetScore(ctx *gin.Context) {
 		ConversationHistory: session.Messages,
 	}
 
-	scoringOutput, err := c.scoringAgent.Process(context.Background(), agentInput)
+	scoringOutput, _, err := c.runAgent(context.WithoutCancel(ctx.Request.Context()), c.scoringAgent, agentInput)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -218,8 +581,8 @@ func (c *ChatController) GetScore(ctx *gin.Context) {
 
 	// Construir respuesta en formato compatible
 	scoreResponse := models.ScoreResponse{
-		Success: true,
-		Score:   scoringOutput.ScoringData.TotalScore,
+		Success:  true,
+		Score:    scoringOutput.ScoringData.TotalScore,
 		Category: scoringOutput.ScoringData.Category,
 		Reasons: []string{
 			scoringOutput.ScoringData.AccionRecomendada,
@@ -247,7 +610,8 @@ func (c *ChatController) GetScore(ctx *gin.Context) {
 func (c *ChatController) GetHistory(ctx *gin.Context) {
 	sessionID := ctx.Param("sessionId")
 
-	session := c.sessionService.GetSession(sessionID)
+	// Copia: los turnos en curso modifican los slots y mensajes mientras se serializa
+	session := c.sessionService.Snapshot(sessionID)
 	if session == nil {
 		ctx.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -255,6 +619,13 @@ func (c *ChatController) GetHistory(ctx *gin.Context) {
 		})
 		return
 	}
+	if !c.auth.CanReadSession(currentPrincipal(ctx), session.Channel) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "El historial de sesiones del canal " + session.Channel + " requiere credenciales",
+		})
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"success":  true,
@@ -263,6 +634,38 @@ func (c *ChatController) GetHistory(ctx *gin.Context) {
 	})
 }
 
+// GetTrace devuelve el trace de los últimos turnos de la sesión. Acepta limit y redact=true
+// para ocultar emails y números (teléfonos, DNI) aunque se hayan guardado sin redactar.
+func (c *ChatController) GetTrace(ctx *gin.Context) {
+	sessionID := ctx.Param("sessionId")
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "limit debe ser un entero positivo",
+		})
+		return
+	}
+	redact, _ := strconv.ParseBool(ctx.Query("redact"))
+
+	turns := c.traces.GetTurns(sessionID, limit, redact)
+	if len(turns) == 0 && c.sessionService.GetSession(sessionID) == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Sesión no encontrada",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"sessionId": sessionID,
+		"count":     len(turns),
+		"turns":     turns,
+	})
+}
+
 func (c *ChatController) DeleteSession(ctx *gin.Context) {
 	_ = ctx.Param("sessionId") // sessionID no usado aún
 
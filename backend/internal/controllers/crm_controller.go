@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CRMController struct {
+	crmService *services.CRMService
+}
+
+func NewCRMController() *CRMController {
+	return &CRMController{
+		crmService: services.GetCRMService(),
+	}
+}
+
+func (c *CRMController) GetConnectors(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"connectors": c.crmService.GetConnectors(),
+	})
+}
+
+// GetOutbox lista los eventos de sincronización; status acepta pending, sent o dead
+func (c *CRMController) GetOutbox(ctx *gin.Context) {
+	entries := c.crmService.GetOutbox(ctx.Query("status"), ctx.Query("connector"))
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(entries),
+		"outbox":  entries,
+	})
+}
+
+// Sync envía ahora los eventos pendientes, sin esperar a la sincronización periódica
+func (c *CRMController) Sync(ctx *gin.Context) {
+	sent, failed := c.crmService.Sync()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"sent":    sent,
+		"failed":  failed,
+	})
+}
+
+func (c *CRMController) RetryEntry(ctx *gin.Context) {
+	entry, err := c.crmService.Retry(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entry":   entry,
+	})
+}
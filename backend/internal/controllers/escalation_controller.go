@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EscalationController struct {
+	escalationService *services.EscalationService
+}
+
+func NewEscalationController() *EscalationController {
+	return &EscalationController{
+		escalationService: services.GetEscalationService(),
+	}
+}
+
+func (e *EscalationController) GetEscalations(ctx *gin.Context) {
+	escalations := e.escalationService.GetEscalations(ctx.Query("status"), ctx.Query("sessionId"))
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"count":       len(escalations),
+		"escalations": escalations,
+	})
+}
+
+func (e *EscalationController) GetEscalationStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"stats":   e.escalationService.GetStats(),
+	})
+}
+
+func (e *EscalationController) GetRules(ctx *gin.Context) {
+	rules := e.escalationService.GetRules()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(rules),
+		"rules":   rules,
+	})
+}
+
+// ResolveEscalation marca la escalación como atendida por un especialista
+func (e *EscalationController) ResolveEscalation(ctx *gin.Context) {
+	var req models.ResolveEscalationRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Datos inválidos: " + err.Error(),
+			})
+			return
+		}
+	}
+	req.Specialist = specialistFor(ctx, req.Specialist)
+	if req.Specialist == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "specialist es requerido",
+		})
+		return
+	}
+
+	escalation, err := e.escalationService.Resolve(ctx.Param("id"), req.Specialist)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"escalation": escalation,
+	})
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FAQController struct {
+	faqService    *services.FAQService
+	responseCache *services.ResponseCacheService
+}
+
+func NewFAQController() *FAQController {
+	return &FAQController{
+		faqService:    services.GetFAQService(),
+		responseCache: services.GetResponseCacheService(),
+	}
+}
+
+// ReloadFAQs vuelve a leer data/faqs.csv; si alguna FAQ cambió, las respuestas cacheadas
+// con la versión anterior se descartan
+func (f *FAQController) ReloadFAQs(ctx *gin.Context) {
+	if err := f.faqService.Reload(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Error recargando FAQs: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"count":        len(f.faqService.GetAllFAQs()),
+		"version":      f.faqService.Version(),
+		"cacheRemoved": f.responseCache.Purge(),
+	})
+}
+
+// GetCacheStats devuelve el estado del cache de respuestas y las entradas más reutilizadas (top, 20 por defecto)
+func (f *FAQController) GetCacheStats(ctx *gin.Context) {
+	top, err := strconv.Atoi(ctx.DefaultQuery("top", "20"))
+	if err != nil || top < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "top debe ser un entero positivo",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"cache":   f.responseCache.Stats(top),
+	})
+}
+
+// ClearCache vacía el cache de respuestas
+func (f *FAQController) ClearCache(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"removed": f.responseCache.Invalidate(),
+	})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FollowUpController struct {
+	followUpService *services.FollowUpService
+}
+
+func NewFollowUpController() *FollowUpController {
+	return &FollowUpController{
+		followUpService: services.GetFollowUpService(),
+	}
+}
+
+// GetFollowUps lista seguimientos; status acepta pending, overdue, sent, cancelled o failed
+func (f *FollowUpController) GetFollowUps(ctx *gin.Context) {
+	followUps := f.followUpService.GetFollowUps(ctx.Query("status"), ctx.Query("sessionId"))
+
+	now := time.Now()
+	overdue := 0
+	for _, followUp := range followUps {
+		if followUp.Overdue(now) {
+			overdue++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"count":     len(followUps),
+		"overdue":   overdue,
+		"followups": followUps,
+	})
+}
+
+func (f *FollowUpController) CancelFollowUp(ctx *gin.Context) {
+	followUp, err := f.followUpService.Cancel(ctx.Param("id"), "manual")
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"followup": followUp,
+	})
+}
+
+// RunDue ejecuta ahora los seguimientos vencidos, sin esperar al scheduler
+func (f *FollowUpController) RunDue(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"processed": f.followUpService.RunDue(),
+	})
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GuardrailController struct {
+	guardrailService *services.GuardrailService
+}
+
+func NewGuardrailController() *GuardrailController {
+	return &GuardrailController{
+		guardrailService: services.GetGuardrailService(),
+	}
+}
+
+// GetEvents lista las reglas disparadas, de la más reciente a la más antigua
+func (g *GuardrailController) GetEvents(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if err != nil || limit < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "limit debe ser un entero positivo",
+		})
+		return
+	}
+
+	events := g.guardrailService.Events(models.GuardrailFilter{
+		SessionID: ctx.Query("sessionId"),
+		Rule:      ctx.Query("rule"),
+		Action:    ctx.Query("action"),
+		Limit:     limit,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(events),
+		"events":  events,
+	})
+}
+
+func (g *GuardrailController) GetStats(ctx *gin.Context) {
+	byRule, byAction, sessions := g.guardrailService.Stats()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"byRule":   byRule,
+		"byAction": byAction,
+		"sessions": sessions,
+	})
+}
+
+func (g *GuardrailController) GetRules(ctx *gin.Context) {
+	rules := g.guardrailService.Rules()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(rules),
+		"rules":   rules,
+	})
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HandoffController struct {
+	handoffService *services.HandoffService
+	sessionService *services.SessionService
+}
+
+func NewHandoffController() *HandoffController {
+	return &HandoffController{
+		handoffService: services.GetHandoffService(),
+		sessionService: services.GetSessionService(),
+	}
+}
+
+// GetHandoffs lista las sesiones en modo humano y las que esperan un especialista
+func (h *HandoffController) GetHandoffs(ctx *gin.Context) {
+	sessions := h.sessionService.GetHumanSessions(ctx.Query("specialist"))
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"count":    len(sessions),
+		"sessions": sessions,
+	})
+}
+
+func (h *HandoffController) ClaimSession(ctx *gin.Context) {
+	var req models.HandoffClaimRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Datos inválidos: " + err.Error(),
+		})
+		return
+	}
+	req.Specialist = specialistFor(ctx, req.Specialist)
+	if req.Specialist == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "specialist es requerido",
+		})
+		return
+	}
+
+	session, err := h.handoffService.Claim(ctx.Param("sessionId"), req.Specialist)
+	if err != nil {
+		ctx.JSON(handoffErrorStatus(err), gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"session": session,
+	})
+}
+
+// SendReply envía la respuesta del especialista por el canal de la sesión
+func (h *HandoffController) SendReply(ctx *gin.Context) {
+	var req models.HandoffReplyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Datos inválidos: " + err.Error(),
+		})
+		return
+	}
+	req.Specialist = specialistFor(ctx, req.Specialist)
+	if req.Specialist == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "specialist es requerido",
+		})
+		return
+	}
+
+	replies, err := h.handoffService.Reply(ctx.Param("sessionId"), req.Specialist, req.Message)
+	if err != nil {
+		ctx.JSON(handoffErrorStatus(err), gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"replies": replies,
+	})
+}
+
+// ReleaseSession devuelve la sesión al bot. Solo el especialista que la tomó, salvo admin.
+func (h *HandoffController) ReleaseSession(ctx *gin.Context) {
+	var req models.HandoffReleaseRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Datos inválidos: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	specialist := specialistFor(ctx, req.Specialist)
+	if principal := currentPrincipal(ctx); principal != nil && principal.Role == services.AuthRoleAdmin {
+		specialist = ""
+	} else if specialist == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "specialist es requerido",
+		})
+		return
+	}
+
+	session, err := h.handoffService.Release(ctx.Param("sessionId"), specialist)
+	if err != nil {
+		ctx.JSON(handoffErrorStatus(err), gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"session": session,
+	})
+}
+
+func handoffErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSessionClaimed), errors.Is(err, services.ErrNotHumanMode):
+		return http.StatusConflict
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -1,9 +1,15 @@
 package controllers
 
 import (
+	"bob-hackathon/internal/models"
 	"bob-hackathon/internal/services"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,6 +18,8 @@ type LeadController struct {
 	sessionService *services.SessionService
 	faqService     *services.FAQService
 	bobAPIService  *services.BOBAPIService
+	specialists    *services.SpecialistService
+	pii            *services.PIIService
 }
 
 func NewLeadController() *LeadController {
@@ -19,14 +27,18 @@ func NewLeadController() *LeadController {
 		sessionService: services.GetSessionService(),
 		faqService:     services.GetFAQService(),
 		bobAPIService:  services.GetBOBAPIService(),
+		specialists:    services.GetSpecialistService(),
+		pii:            services.GetPIIService(),
 	}
 }
 
 func (l *LeadController) GetAllLeads(ctx *gin.Context) {
-	category := ctx.Query("category")
-	channel := ctx.Query("channel")
-
-	leads := l.sessionService.GetAllLeads(category, channel)
+	leads := l.sessionService.GetAllLeads(models.LeadFilter{
+		Category: ctx.Query("category"),
+		Channel:  ctx.Query("channel"),
+		Stage:    ctx.Query("stage"),
+		Status:   ctx.Query("status"),
+	})
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -53,6 +65,179 @@ func (l *LeadController) GetLead(ctx *gin.Context) {
 	})
 }
 
+// UpdateLead cambia el estado comercial del lead, su motivo de pérdida, lotes o agrega una nota
+func (l *LeadController) UpdateLead(ctx *gin.Context) {
+	var req models.LeadUpdateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Datos inválidos: " + err.Error(),
+		})
+		return
+	}
+	req.Actor = actorFor(ctx, req.Actor)
+	if req.Actor == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "actor es requerido",
+		})
+		return
+	}
+
+	sessionID := ctx.Param("sessionId")
+	if l.sessionService.GetLead(sessionID) == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Lead no encontrado",
+		})
+		return
+	}
+
+	lead, err := l.sessionService.UpdateLeadLifecycle(sessionID, req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"lead":    lead,
+	})
+}
+
+// GetLeadAudit devuelve el historial de cambios del lead
+func (l *LeadController) GetLeadAudit(ctx *gin.Context) {
+	lead := l.sessionService.GetLead(ctx.Param("sessionId"))
+	if lead == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Lead no encontrado",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(lead.Audit),
+		"audit":   lead.Audit,
+	})
+}
+
+// GetLeadContact devuelve los datos de contacto que dio el prospecto. Requiere la clave
+// PII_ACCESS_KEY en el header X-PII-Key; el actor (usuario autenticado o parámetro actor)
+// queda en la auditoría del lead.
+func (l *LeadController) GetLeadContact(ctx *gin.Context) {
+	sessionID, actor, ok := l.contactAccess(ctx)
+	if !ok {
+		return
+	}
+
+	contact := l.pii.GetContact(sessionID)
+	if contact == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Sin datos de contacto para la sesión",
+		})
+		return
+	}
+	l.sessionService.AuditContactAccess(sessionID, actor, "read")
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"contact": contact,
+	})
+}
+
+// DeleteLeadContact borra los datos de contacto del prospecto (por ejemplo, si lo solicita)
+func (l *LeadController) DeleteLeadContact(ctx *gin.Context) {
+	sessionID, actor, ok := l.contactAccess(ctx)
+	if !ok {
+		return
+	}
+
+	if !l.pii.DeleteContact(sessionID) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Sin datos de contacto para la sesión",
+		})
+		return
+	}
+	l.sessionService.SetLeadContact(sessionID, false)
+	l.sessionService.AuditContactAccess(sessionID, actor, "deleted")
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// contactAccess valida la clave y el actor para los datos de contacto
+func (l *LeadController) contactAccess(ctx *gin.Context) (string, string, bool) {
+	if !l.pii.CanAccess(ctx.GetHeader("X-PII-Key")) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "Acceso a datos de contacto no autorizado",
+		})
+		return "", "", false
+	}
+
+	actor := actorFor(ctx, ctx.Query("actor"))
+	if actor == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "actor es requerido",
+		})
+		return "", "", false
+	}
+	return ctx.Param("sessionId"), actor, true
+}
+
+// AssignLead asigna o reasigna el lead; sin specialistId se elige con la estrategia configurada
+func (l *LeadController) AssignLead(ctx *gin.Context) {
+	var req models.AssignLeadRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Datos inválidos: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	// Un especialista solo puede tomar el lead para sí; reasignar a otro queda para admin
+	if principal := currentPrincipal(ctx); principal != nil && principal.Role == services.AuthRoleSpecialist {
+		if principal.SpecialistID == "" || (req.SpecialistID != "" && req.SpecialistID != principal.SpecialistID) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Solo puedes asignarte leads a ti mismo",
+			})
+			return
+		}
+		req.SpecialistID = principal.SpecialistID
+	}
+
+	lead, err := l.specialists.Assign(ctx.Param("sessionId"), req.SpecialistID, req.Reason, actorFor(ctx, ""))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, services.ErrNoSpecialistAvailable) {
+			status = http.StatusConflict
+		}
+		ctx.JSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"lead":    lead,
+	})
+}
+
 func (l *LeadController) GetLeadsStats(ctx *gin.Context) {
 	stats := l.sessionService.GetLeadsStats()
 
@@ -62,6 +247,66 @@ func (l *LeadController) GetLeadsStats(ctx *gin.Context) {
 	})
 }
 
+// ExportLeads descarga los leads filtrados como csv, xlsx o ndjson. Con el header X-PII-Key
+// incluye los datos de contacto que dieron los prospectos en la conversación.
+func (l *LeadController) ExportLeads(ctx *gin.Context) {
+	format := strings.ToLower(ctx.DefaultQuery("format", services.ExportCSV))
+	contentType, ok := services.ExportContentTypes[format]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Formato inválido: " + format + " (válidos: csv, xlsx, ndjson)",
+		})
+		return
+	}
+
+	from, errFrom := services.ParseExportDate(ctx.Query("from"), false)
+	to, errTo := services.ParseExportDate(ctx.Query("to"), true)
+	if err := errors.Join(errFrom, errTo); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	filter := models.LeadFilter{
+		Category: ctx.Query("category"),
+		Channel:  ctx.Query("channel"),
+		Stage:    ctx.Query("stage"),
+		Status:   ctx.Query("status"),
+		From:     from,
+		To:       to,
+	}
+
+	// Los datos de contacto extraídos de las conversaciones solo van con la clave PII_ACCESS_KEY
+	var contacts map[string]*models.LeadContact
+	if key := ctx.GetHeader("X-PII-Key"); key != "" {
+		if !l.pii.CanAccess(key) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Acceso a datos de contacto no autorizado",
+			})
+			return
+		}
+		contacts = l.pii.Contacts()
+		log.Printf("🔐 Exportación de leads con datos de contacto (%s)", format)
+	}
+
+	filename := fmt.Sprintf("leads-%s.%s", time.Now().Format("20060102-150405"), format)
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Status(http.StatusOK)
+
+	count, err := l.sessionService.ExportLeads(ctx.Writer, format, filter, contacts)
+	if err != nil {
+		// Los headers ya se enviaron: solo queda registrar el error
+		log.Printf("❌ Error exportando leads (%s): %v", format, err)
+		return
+	}
+	log.Printf("📤 %d leads exportados en %s", count, format)
+}
+
 func (l *LeadController) GetFAQs(ctx *gin.Context) {
 	search := ctx.Query("search")
 	categoria := ctx.Query("categoria")
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromptController struct {
+	promptService *services.PromptService
+}
+
+func NewPromptController() *PromptController {
+	return &PromptController{
+		promptService: services.GetPromptService(),
+	}
+}
+
+func (p *PromptController) GetPrompts(ctx *gin.Context) {
+	templates := p.promptService.List()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"count":     len(templates),
+		"templates": templates,
+	})
+}
+
+// PreviewPrompt renderiza una plantilla con los datos enviados o, si no vienen, con sus datos de ejemplo
+func (p *PromptController) PreviewPrompt(ctx *gin.Context) {
+	name := ctx.Param("name")
+
+	var req models.PromptPreviewRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Datos inválidos: " + err.Error(),
+			})
+			return
+		}
+	}
+	if req.Channel == "" {
+		req.Channel = ctx.DefaultQuery("channel", services.DefaultPromptChannel)
+	}
+
+	data := req.Data
+	if data == nil {
+		sample, err := p.promptService.SampleData(name)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		data = sample
+	}
+
+	rendered, ref, err := p.promptService.Render(name, req.Channel, data)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"template": ref,
+		"prompt":   rendered,
+	})
+}
+
+func (p *PromptController) ReloadPrompts(ctx *gin.Context) {
+	if err := p.promptService.Reload(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Error recargando plantillas: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(p.promptService.List()),
+	})
+}
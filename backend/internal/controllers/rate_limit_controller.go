@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPeekBody es lo máximo que se lee del body para sacar el sessionId
+const maxPeekBody = 1 << 20
+
+type RateLimitController struct {
+	limiter *services.RateLimitService
+}
+
+func NewRateLimitController() *RateLimitController {
+	return &RateLimitController{
+		limiter: services.GetRateLimitService(),
+	}
+}
+
+// Limit aplica los token buckets antes de llamar a los agentes: por sesión (sessionId del
+// body) y por API key si el request viene autenticado, o por IP si no. El bot atiende a
+// todos sus usuarios desde la misma IP, por eso con API key no se limita por IP.
+func (r *RateLimitController) Limit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !r.limiter.Enabled() {
+			ctx.Next()
+			return
+		}
+
+		checks := []services.RateLimitCheck{{Scope: services.RateLimitSession, ID: peekSessionID(ctx)}}
+		if principal := currentPrincipal(ctx); principal != nil {
+			checks = append(checks, services.RateLimitCheck{Scope: services.RateLimitKey, ID: principal.Method + ":" + principal.Subject})
+		} else {
+			checks = append(checks, services.RateLimitCheck{Scope: services.RateLimitIP, ID: ctx.ClientIP()})
+		}
+
+		decision, scope := r.limiter.AllowAll(checks...)
+		if !decision.Allowed {
+			retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"success":    false,
+				"error":      "Demasiados mensajes (" + scope + ")",
+				"reply":      fmt.Sprintf("Estás enviando mensajes muy rápido. Espera %d segundos y vuelve a escribirme.", retryAfter),
+				"scope":      scope,
+				"retryAfter": retryAfter,
+			})
+			return
+		}
+
+		if decision.Remaining >= 0 {
+			ctx.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		}
+		ctx.Next()
+	}
+}
+
+// peekSessionID lee el sessionId del body JSON y lo deja intacto para el controller
+func peekSessionID(ctx *gin.Context) string {
+	if ctx.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxPeekBody))
+	ctx.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), ctx.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		SessionID string `json:"sessionId"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	return req.SessionID
+}
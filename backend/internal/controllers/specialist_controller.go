@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SpecialistController struct {
+	specialistService *services.SpecialistService
+}
+
+func NewSpecialistController() *SpecialistController {
+	return &SpecialistController{
+		specialistService: services.GetSpecialistService(),
+	}
+}
+
+func (s *SpecialistController) GetSpecialists(ctx *gin.Context) {
+	specialists := s.specialistService.GetSpecialists()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"count":       len(specialists),
+		"specialists": specialists,
+	})
+}
+
+// SaveSpecialist crea (POST) o actualiza (PUT /:id) un especialista del directorio
+func (s *SpecialistController) SaveSpecialist(ctx *gin.Context) {
+	var specialist models.Specialist
+	if err := ctx.ShouldBindJSON(&specialist); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Datos inválidos: " + err.Error(),
+		})
+		return
+	}
+	if id := ctx.Param("id"); id != "" {
+		specialist.ID = id
+	}
+
+	if err := s.specialistService.SaveSpecialist(&specialist); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"specialist": specialist,
+	})
+}
+
+// GetQueue devuelve los leads abiertos asignados al especialista
+func (s *SpecialistController) GetQueue(ctx *gin.Context) {
+	queue, err := s.specialistService.GetQueue(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(queue),
+		"leads":   queue,
+	})
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UsageController struct {
+	usageService   *services.UsageService
+	sessionService *services.SessionService
+}
+
+func NewUsageController() *UsageController {
+	return &UsageController{
+		usageService:   services.GetUsageService(),
+		sessionService: services.GetSessionService(),
+	}
+}
+
+// GetUsage resume tokens y costo por agente, canal, modelo, día y sesión.
+// Acepta from, to, channel, agent, sessionId y top (sesiones más caras, 20 por defecto).
+func (u *UsageController) GetUsage(ctx *gin.Context) {
+	filter, ok := parseAnalyticsFilter(ctx)
+	if !ok {
+		return
+	}
+
+	top, err := strconv.Atoi(ctx.DefaultQuery("top", "20"))
+	if err != nil || top < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "top debe ser un entero positivo",
+		})
+		return
+	}
+
+	summary := u.usageService.Summary(models.UsageFilter{
+		From:      filter.From,
+		To:        filter.To,
+		Channel:   filter.Channel,
+		SessionID: ctx.Query("sessionId"),
+		Agent:     ctx.Query("agent"),
+	}, top)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"usage":   summary,
+	})
+}
+
+// GetSessionUsage devuelve el consumo acumulado de la sesión, el detalle por agente y su presupuesto
+func (u *UsageController) GetSessionUsage(ctx *gin.Context) {
+	sessionID := ctx.Param("sessionId")
+
+	session := u.sessionService.GetSession(sessionID)
+	if session == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Sesión no encontrada",
+		})
+		return
+	}
+
+	totals := models.UsageTotals{}
+	if session.Usage != nil {
+		totals = *session.Usage
+	}
+	summary := u.usageService.Summary(models.UsageFilter{SessionID: sessionID}, 0)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"sessionId": sessionID,
+		"totals":    totals,
+		"byAgent":   summary.ByAgent,
+		"byDay":     summary.ByDay,
+		"budget":    summary.Budget,
+	})
+}
+
+// GetPrices devuelve la tabla de precios (USD por millón de tokens) y los presupuestos
+func (u *UsageController) GetPrices(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"prices":      u.usageService.Prices(),
+		"budget":      u.usageService.Budget(""),
+		"budgetModel": u.usageService.BudgetModel(),
+	})
+}
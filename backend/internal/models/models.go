@@ -4,37 +4,380 @@ import "time"
 
 // Session representa una sesión de conversación
 type Session struct {
-	SessionID    string              `json:"sessionId"`
-	Channel      string              `json:"channel"`
-	Messages     []Message           `json:"messages"`
-	CreatedAt    time.Time           `json:"createdAt"`
-	UpdatedAt    time.Time           `json:"updatedAt"`
-	LeadScore    int                 `json:"leadScore"`
-	Category     string              `json:"category"`
-	Metadata     map[string]string   `json:"metadata,omitempty"`
+	SessionID string            `json:"sessionId"`
+	Channel   string            `json:"channel"`
+	Messages  []Message         `json:"messages"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	LeadScore int               `json:"leadScore"`
+	Category  string            `json:"category"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+
+	// Embudo de ventas
+	Stage        string            `json:"stage"`
+	StageHistory []StageChange     `json:"stageHistory,omitempty"`
+	Slots        map[string]string `json:"slots,omitempty"`
+
+	// Atención humana: si está activa, el bot no responde
+	Handoff *Handoff `json:"handoff,omitempty"`
+
+	// Tokens y costo de LLM acumulados en la sesión
+	Usage *UsageTotals `json:"usage,omitempty"`
+
+	// Guardrails: puntos restados al lead y reglas que disparó la sesión
+	GuardrailPenalty int      `json:"guardrailPenalty,omitempty"`
+	GuardrailFlags   []string `json:"guardrailFlags,omitempty"`
+}
+
+// IsHumanMode indica si un especialista tomó el control de la sesión
+func (s *Session) IsHumanMode() bool {
+	return s.Handoff != nil && s.Handoff.Active
+}
+
+// Handoff representa la toma de una sesión por un especialista humano
+type Handoff struct {
+	Active     bool       `json:"active"`
+	Specialist string     `json:"specialist"`
+	ClaimedAt  time.Time  `json:"claimedAt"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+	Pending    int        `json:"pending"` // mensajes del usuario sin responder
+}
+
+// StageChange registra una transición del embudo de la sesión
+type StageChange struct {
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Trigger string    `json:"trigger"`
+	At      time.Time `json:"at"`
 }
 
 // Message representa un mensaje en la conversación
 type Message struct {
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	Role      string      `json:"role"`
+	Content   string      `json:"content"`
+	Timestamp time.Time   `json:"timestamp"`
+	Trace     *ReplyTrace `json:"trace,omitempty"`
+}
+
+// PromptRef identifica la plantilla de prompt (y su versión) usada en una llamada al modelo
+type PromptRef struct {
+	Name    string `json:"name"`
+	Channel string `json:"channel"`
+	Version string `json:"version"`
+}
+
+// ReplyTrace resume cómo se generó una respuesta del asistente
+type ReplyTrace struct {
+	Intent  string      `json:"intent,omitempty"`
+	Agent   string      `json:"agent,omitempty"`
+	Prompts []PromptRef `json:"prompts,omitempty"`
+}
+
+// AddPrompt registra la plantilla usada por un agente, si la hubo
+func (t *ReplyTrace) AddPrompt(ref *PromptRef) {
+	if t == nil || ref == nil || ref.Name == "" {
+		return
+	}
+	t.Prompts = append(t.Prompts, *ref)
+}
+
+// Clone copia el trace para guardarlo sin compartir el slice de prompts
+func (t *ReplyTrace) Clone() *ReplyTrace {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	clone.Prompts = append([]PromptRef(nil), t.Prompts...)
+	return &clone
+}
+
+// AgentCall registra lo que hizo un agente dentro de un turno
+type AgentCall struct {
+	Agent      string      `json:"agent"`
+	Prompt     *PromptRef  `json:"prompt,omitempty"`
+	PromptText string      `json:"promptText,omitempty"`
+	RawOutput  string      `json:"rawOutput,omitempty"`
+	Retrieved  []string    `json:"retrieved,omitempty"` // FAQs o vehículos que recibió el prompt
+	Usage      *TokenUsage `json:"usage,omitempty"`
+	Cached     bool        `json:"cached,omitempty"` // respuesta del cache de FAQs, sin llamada al LLM
+	DurationMs int64       `json:"durationMs"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// TraceDecision es la decisión del orchestrator ya parseada
+type TraceDecision struct {
+	Intent      string  `json:"intent"`
+	Confidence  float64 `json:"confidence"`
+	ShouldRoute bool    `json:"shouldRoute"`
+	RouteTo     string  `json:"routeTo,omitempty"`
+	Reasoning   string  `json:"reasoning,omitempty"`
+	DecidedBy   string  `json:"decidedBy,omitempty"` // cache, rules, model o keywords; vacío = LLM
+}
+
+// TurnTrace registra lo que pasó dentro de un turno de chat, para depurar respuestas
+type TurnTrace struct {
+	ID           string         `json:"id"`
+	SessionID    string         `json:"sessionId"`
+	Channel      string         `json:"channel"`
+	Message      string         `json:"message"`
+	TraceID      string         `json:"traceId,omitempty"` // trace de OpenTelemetry del request
+	StageBefore  string         `json:"stageBefore"`
+	StageAfter   string         `json:"stageAfter,omitempty"`
+	Orchestrator *AgentCall     `json:"orchestrator,omitempty"`
+	Decision     *TraceDecision `json:"decision,omitempty"`
+	SubAgent     *AgentCall     `json:"subAgent,omitempty"`
+	Reply        string         `json:"reply,omitempty"`
+	Replies      []string       `json:"replies,omitempty"`
+	Scoring      *AgentCall     `json:"scoring,omitempty"`
+	ScoringData  *ScoringData   `json:"scoringData,omitempty"`
+	Errors       []string       `json:"errors,omitempty"`
+	Usage        *UsageTotals   `json:"usage,omitempty"`      // tokens y costo de todas las llamadas del turno
+	Degraded     string         `json:"degraded,omitempty"`   // presupuesto superado (session, daily) o LLM no disponible (llm)
+	Guardrails   []string       `json:"guardrails,omitempty"` // reglas que aplicaron, como regla:acción
+	Redacted     bool           `json:"redacted,omitempty"`
+	StartedAt    time.Time      `json:"startedAt"`
+	DurationMs   int64          `json:"durationMs"`
 }
 
 // Lead representa un lead generado
 type Lead struct {
-	SessionID    string              `json:"sessionId"`
-	Channel      string              `json:"channel"`
-	Score        int                 `json:"score"`
-	Category     string              `json:"category"`
-	Urgency      string              `json:"urgency,omitempty"`
-	Budget       string              `json:"budget,omitempty"`
-	BusinessType string              `json:"businessType,omitempty"`
-	Reasons      []string            `json:"reasons,omitempty"`
-	LastMessage  string              `json:"lastMessage"`
-	CreatedAt    time.Time           `json:"createdAt"`
-	UpdatedAt    time.Time           `json:"updatedAt"`
-	Metadata     map[string]string   `json:"metadata,omitempty"`
+	SessionID    string            `json:"sessionId"`
+	Channel      string            `json:"channel"`
+	Score        int               `json:"score"`
+	Category     string            `json:"category"`
+	Urgency      string            `json:"urgency,omitempty"`
+	Budget       string            `json:"budget,omitempty"`
+	BusinessType string            `json:"businessType,omitempty"`
+	Reasons      []string          `json:"reasons,omitempty"`
+	LastMessage  string            `json:"lastMessage"`
+	Dimensions   map[string]int    `json:"dimensionScores,omitempty"` // puntaje por dimensión del último scoring
+	FirstHotAt   *time.Time        `json:"firstHotAt,omitempty"`      // primera vez que el scoring lo calificó hot
+	Stage        string            `json:"stage,omitempty"`
+	AssignedTo   string            `json:"assignedTo,omitempty"`
+	AssignedAt   *time.Time        `json:"assignedAt,omitempty"`
+	Assignments  []Assignment      `json:"assignments,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	UpdatedAt    time.Time         `json:"updatedAt"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+
+	// Guardrails: puntos que se restan al score y reglas que disparó la conversación
+	GuardrailPenalty int      `json:"guardrailPenalty,omitempty"`
+	GuardrailFlags   []string `json:"guardrailFlags,omitempty"`
+
+	// Hay datos de contacto extraídos de la conversación (GET /api/leads/:sessionId/contact)
+	HasContact bool `json:"hasContact,omitempty"`
+
+	// Ciclo de vida comercial
+	Status           string               `json:"status"`
+	StatusTimestamps map[string]time.Time `json:"statusTimestamps,omitempty"` // primera vez en cada estado
+	LossReason       string               `json:"lossReason,omitempty"`
+	VehicleLots      []string             `json:"vehicleLots,omitempty"`
+	Notes            []LeadNote           `json:"notes,omitempty"`
+	Audit            []LeadAuditEntry     `json:"audit,omitempty"`
+}
+
+// LeadContact son los datos personales que dio el prospecto en la conversación. Se guardan
+// aparte del lead (data/lead_contacts.json) y las transcripciones quedan redactadas.
+type LeadContact struct {
+	SessionID string    `json:"sessionId"`
+	Emails    []string  `json:"emails,omitempty"`
+	Phones    []string  `json:"phones,omitempty"`
+	DNI       []string  `json:"dni,omitempty"`
+	RUC       []string  `json:"ruc,omitempty"`
+	Plates    []string  `json:"plates,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PIIMatch es un dato personal encontrado en un texto
+type PIIMatch struct {
+	Type  string `json:"type"` // email, telefono, dni, ruc, placa, tarjeta
+	Value string `json:"value"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// LeadNote es una nota de ventas sobre el lead
+type LeadNote struct {
+	Author string    `json:"author"`
+	Text   string    `json:"text"`
+	At     time.Time `json:"at"`
+}
+
+// LeadAuditEntry registra un cambio en el ciclo de vida del lead
+type LeadAuditEntry struct {
+	Actor string    `json:"actor"`
+	Field string    `json:"field"`
+	From  string    `json:"from,omitempty"`
+	To    string    `json:"to,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// LeadUpdateRequest actualiza el ciclo de vida de un lead; los campos vacíos no se tocan.
+// Con credenciales el actor es el usuario autenticado.
+type LeadUpdateRequest struct {
+	Actor       string   `json:"actor"`
+	Status      string   `json:"status,omitempty"`
+	LossReason  string   `json:"lossReason,omitempty"`
+	VehicleLots []string `json:"vehicleLots,omitempty"`
+	Note        string   `json:"note,omitempty"`
+}
+
+// LeadFilter filtra el listado de leads; los campos vacíos no filtran
+type LeadFilter struct {
+	Category string
+	Channel  string
+	Stage    string
+	Status   string
+	From     time.Time // createdAt desde (inclusive); cero = sin límite
+	To       time.Time // createdAt hasta (exclusive); cero = sin límite
+}
+
+// Assignment registra una asignación (o reasignación) del lead a un especialista
+type Assignment struct {
+	SpecialistID string    `json:"specialistId"`
+	Previous     string    `json:"previous,omitempty"`
+	Strategy     string    `json:"strategy"` // round_robin, skill, load o manual
+	Reason       string    `json:"reason,omitempty"`
+	By           string    `json:"by,omitempty"` // quien la hizo; vacío en las automáticas
+	At           time.Time `json:"at"`
+}
+
+// Specialist es un asesor de ventas que atiende leads
+type Specialist struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Email        string       `json:"email,omitempty"`
+	Phone        string       `json:"phone,omitempty"`
+	Channels     []string     `json:"channels,omitempty"` // vacío = todos
+	Skills       []string     `json:"skills,omitempty"`   // tipos de vehículo, marcas, "empresa"
+	Regions      []string     `json:"regions,omitempty"`
+	WorkingHours WorkingHours `json:"workingHours"`
+	Capacity     int          `json:"capacity"` // leads abiertos simultáneos, 0 = sin límite
+	Active       bool         `json:"active"`
+}
+
+// WorkingHours es el horario de atención de un especialista (hora de Lima)
+type WorkingHours struct {
+	Days  []int  `json:"days,omitempty"`  // 0 = domingo ... 6 = sábado; vacío = todos
+	Start string `json:"start,omitempty"` // "09:00"; vacío = todo el día
+	End   string `json:"end,omitempty"`   // "18:00"
+}
+
+// SpecialistStatus es un especialista con su carga actual
+type SpecialistStatus struct {
+	Specialist
+	Load    int  `json:"load"`
+	OnShift bool `json:"onShift"`
+}
+
+// AssignLeadRequest asigna un lead a un especialista (vacío = asignación automática)
+type AssignLeadRequest struct {
+	SpecialistID string `json:"specialistId"`
+	Reason       string `json:"reason"`
+}
+
+// GuardrailEvent registra una regla de guardrail que se disparó en un mensaje entrante o saliente
+type GuardrailEvent struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	Channel   string    `json:"channel"`
+	Direction string    `json:"direction"` // input u output
+	Rule      string    `json:"rule"`
+	Action    string    `json:"action"` // block, warn, flag o filter
+	Penalty   int       `json:"penalty,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	Excerpt   string    `json:"excerpt,omitempty"` // primeros caracteres del texto evaluado
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GuardrailFilter filtra el registro de guardrails; los campos vacíos no filtran
+type GuardrailFilter struct {
+	SessionID string
+	Rule      string
+	Action    string
+	Limit     int
+}
+
+// Escalation representa un lead que requiere atención de un especialista dentro de un SLA
+type Escalation struct {
+	ID         string     `json:"id"`
+	SessionID  string     `json:"sessionId"`
+	Channel    string     `json:"channel"`
+	RuleID     string     `json:"ruleId"`
+	Reason     string     `json:"reason"`
+	Priority   string     `json:"priority"`
+	Status     string     `json:"status"` // open, breached, resolved
+	CreatedAt  time.Time  `json:"createdAt"`
+	Deadline   time.Time  `json:"deadline"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	ResolvedBy string     `json:"resolvedBy,omitempty"`
+	SLAMet     *bool      `json:"slaMet,omitempty"`
+	Notified   []string   `json:"notified,omitempty"`
+}
+
+// EscalationStats resume el cumplimiento de SLA de las escalaciones
+type EscalationStats struct {
+	Total              int            `json:"total"`
+	Open               int            `json:"open"`
+	Breached           int            `json:"breached"`
+	Resolved           int            `json:"resolved"`
+	SLAMet             int            `json:"slaMet"`
+	SLAMissed          int            `json:"slaMissed"`
+	SLAMetRate         float64        `json:"slaMetRate"`
+	AvgResponseMinutes float64        `json:"avgResponseMinutes"`
+	ByRule             map[string]int `json:"byRule"`
+}
+
+// ResolveEscalationRequest representa la atención de una escalación
+type ResolveEscalationRequest struct {
+	Specialist string `json:"specialist"` // con autenticación se toma del usuario
+}
+
+// FollowUp es una tarea de seguimiento programada a partir del scoring
+type FollowUp struct {
+	ID              string     `json:"id"`
+	SessionID       string     `json:"sessionId"`
+	Channel         string     `json:"channel"`
+	Category        string     `json:"category"`
+	Action          string     `json:"action"` // message (reenganche al prospecto) o notify (aviso a especialista)
+	Status          string     `json:"status"` // pending, sent, cancelled, failed
+	DueAt           time.Time  `json:"dueAt"`
+	TiempoContacto  string     `json:"tiempoContacto,omitempty"`
+	TipoSeguimiento string     `json:"tipoSeguimiento,omitempty"`
+	Attempts        int        `json:"attempts"`
+	LastError       string     `json:"lastError,omitempty"`
+	Message         string     `json:"message,omitempty"`
+	CancelReason    string     `json:"cancelReason,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	FiredAt         *time.Time `json:"firedAt,omitempty"`
+	CancelledAt     *time.Time `json:"cancelledAt,omitempty"`
+}
+
+// Overdue indica si el seguimiento sigue pendiente con la fecha ya cumplida
+func (f *FollowUp) Overdue(now time.Time) bool {
+	return f.Status == "pending" && now.After(f.DueAt)
+}
+
+// CRMEvent es un cambio de lead que se sincroniza con sistemas externos
+type CRMEvent struct {
+	Type           string    `json:"type"` // lead.created, lead.score_changed, lead.status_changed, lead.assigned
+	IdempotencyKey string    `json:"idempotencyKey"`
+	SessionID      string    `json:"sessionId"`
+	Lead           Lead      `json:"lead"`
+	OccurredAt     time.Time `json:"occurredAt"`
+}
+
+// CRMOutboxEntry es un envío pendiente (o realizado) de un evento a un conector
+type CRMOutboxEntry struct {
+	ID            string     `json:"id"`
+	Connector     string     `json:"connector"`
+	Event         CRMEvent   `json:"event"`
+	Status        string     `json:"status"` // pending, sent, dead
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt"`
+	LastError     string     `json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
 }
 
 // FAQ representa una pregunta frecuente
@@ -45,6 +388,35 @@ type FAQ struct {
 	Respuesta string `json:"respuesta"`
 }
 
+// CachedResponse es una respuesta del FAQ agent que se reutiliza para preguntas equivalentes
+// del mismo canal y etapa con las mismas FAQs recuperadas
+type CachedResponse struct {
+	Key           string    `json:"key"`
+	Channel       string    `json:"channel"`
+	Stage         string    `json:"stage,omitempty"` // etapa del embudo con la que se generó
+	Question      string    `json:"question"`        // pregunta que generó la respuesta
+	Terms         []string  `json:"terms"`           // términos normalizados para comparar preguntas
+	FAQSet        string    `json:"faqSet"`          // huella de las FAQs que recibió el prompt
+	FAQVersion    string    `json:"faqVersion"`
+	PromptVersion string    `json:"promptVersion,omitempty"`
+	Response      string    `json:"response"`
+	Retrieved     []string  `json:"retrieved,omitempty"`
+	Hits          int       `json:"hits"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+}
+
+// ResponseCacheStats resume el uso del cache de respuestas de FAQs desde el arranque
+type ResponseCacheStats struct {
+	Enabled   bool             `json:"enabled"`
+	Entries   int              `json:"entries"`
+	ByChannel map[string]int   `json:"byChannel"`
+	Hits      int              `json:"hits"`
+	Misses    int              `json:"misses"`
+	HitRate   float64          `json:"hitRate"`
+	Top       []CachedResponse `json:"top"` // entradas más reutilizadas
+}
+
 // Vehicle representa un vehículo en subasta
 type Vehicle struct {
 	ID           string  `json:"id"`
@@ -66,12 +438,34 @@ type ChatRequest struct {
 
 // ChatResponse representa la respuesta del chat
 type ChatResponse struct {
-	Success   bool      `json:"success"`
-	SessionID string    `json:"sessionId"`
-	Reply     string    `json:"reply"`
-	LeadScore int       `json:"leadScore"`
-	Category  string    `json:"category"`
-	Timestamp time.Time `json:"timestamp"`
+	Success   bool        `json:"success"`
+	SessionID string      `json:"sessionId"`
+	Reply     string      `json:"reply"`
+	Replies   []string    `json:"replies,omitempty"`
+	LeadScore int         `json:"leadScore"`
+	Category  string      `json:"category"`
+	Stage     string      `json:"stage,omitempty"`
+	HumanMode bool        `json:"humanMode,omitempty"`
+	Degraded  string      `json:"degraded,omitempty"`  // presupuesto de LLM superado (session, daily) o LLM caído (llm)
+	Guardrail string      `json:"guardrail,omitempty"` // regla que bloqueó o advirtió el mensaje
+	Timestamp time.Time   `json:"timestamp"`
+	Trace     *ReplyTrace `json:"trace,omitempty"`
+}
+
+// HandoffClaimRequest representa la toma de una sesión por un especialista
+type HandoffClaimRequest struct {
+	Specialist string `json:"specialist"` // con autenticación se toma del usuario
+}
+
+// HandoffReleaseRequest identifica al especialista que devuelve la sesión al bot
+type HandoffReleaseRequest struct {
+	Specialist string `json:"specialist"` // con autenticación se toma del usuario
+}
+
+// HandoffReplyRequest representa una respuesta escrita por el especialista
+type HandoffReplyRequest struct {
+	Specialist string `json:"specialist"` // con autenticación se toma del usuario
+	Message    string `json:"message" binding:"required"`
 }
 
 // ScoreRequest representa una solicitud de scoring
@@ -92,12 +486,266 @@ type ScoreResponse struct {
 
 // LeadStats representa estadísticas de leads
 type LeadStats struct {
-	Total      int     `json:"total"`
-	Hot        int     `json:"hot"`
-	Warm       int     `json:"warm"`
-	Cold       int     `json:"cold"`
-	AvgScore   float64 `json:"avgScore"`
-	ByChannel  map[string]int `json:"byChannel"`
+	Total     int            `json:"total"`
+	Hot       int            `json:"hot"`
+	Warm      int            `json:"warm"`
+	Cold      int            `json:"cold"`
+	Discarded int            `json:"discarded"`
+	AvgScore  float64        `json:"avgScore"`
+	ByChannel map[string]int `json:"byChannel"`
+	ByStage   map[string]int `json:"byStage"`
+	ByStatus  map[string]int `json:"byStatus"`
+	// Conversion es la fracción de leads que alcanzó cada estado (ej. won = chatbot → ganador de subasta)
+	Conversion map[string]float64 `json:"conversion"`
+}
+
+// AnalyticsFilter acota las métricas de leads; los campos vacíos no filtran
+type AnalyticsFilter struct {
+	From    time.Time // desde (inclusive)
+	To      time.Time // hasta (exclusive)
+	Channel string
+	Bucket  string // day, week o month
+}
+
+// AnalyticsBucket agrupa los leads creados en un intervalo de tiempo
+type AnalyticsBucket struct {
+	Start      time.Time      `json:"start"`
+	Leads      int            `json:"leads"`
+	AvgScore   float64        `json:"avgScore"`
+	ByCategory map[string]int `json:"byCategory"`
+}
+
+// FunnelStageStats cuántas sesiones llegaron a cada etapa del embudo
+type FunnelStageStats struct {
+	Stage        string  `json:"stage"`
+	Reached      int     `json:"reached"`      // llegaron a esta etapa o a una posterior
+	Current      int     `json:"current"`      // están ahora en esta etapa
+	FromPrevious float64 `json:"fromPrevious"` // conversión desde la etapa anterior (no aplica a closed)
+	FromStart    float64 `json:"fromStart"`    // conversión desde el saludo
+}
+
+// DimensionAverages promedia los puntajes de las 7 dimensiones del scoring
+type DimensionAverages struct {
+	Leads      int                           `json:"leads"` // leads con detalle por dimensión
+	Overall    map[string]float64            `json:"overall"`
+	ByCategory map[string]map[string]float64 `json:"byCategory"`
+}
+
+// TimeToHotStats mide cuánto tarda una conversación en producir un lead hot
+type TimeToHotStats struct {
+	Leads         int     `json:"leads"`
+	MedianMinutes float64 `json:"medianMinutes"`
+	P90Minutes    float64 `json:"p90Minutes"`
+	AvgMinutes    float64 `json:"avgMinutes"`
+}
+
+// LeadAnalytics es el resumen completo de métricas de leads en un rango de fechas
+type LeadAnalytics struct {
+	From              *time.Time                `json:"from,omitempty"`
+	To                *time.Time                `json:"to,omitempty"`
+	Channel           string                    `json:"channel,omitempty"`
+	Bucket            string                    `json:"bucket"`
+	Leads             int                       `json:"leads"`
+	AvgScore          float64                   `json:"avgScore"`
+	ByCategory        map[string]int            `json:"byCategory"`
+	CategoryByChannel map[string]map[string]int `json:"categoryByChannel"`
+	Series            []AnalyticsBucket         `json:"series"`
+	Dimensions        DimensionAverages         `json:"dimensions"`
+	Funnel            []FunnelStageStats        `json:"funnel"`
+	TimeToFirstHot    TimeToHotStats            `json:"timeToFirstHot"`
+}
+
+// OrchestratorDecision registra una decisión de ruteo del orchestrator
+type OrchestratorDecision struct {
+	ID          string     `json:"id"`
+	SessionID   string     `json:"sessionId"`
+	Channel     string     `json:"channel"`
+	Message     string     `json:"message"`
+	Intent      string     `json:"intent"`
+	Confidence  float64    `json:"confidence"`
+	ShouldRoute bool       `json:"shouldRoute"`
+	RouteTo     string     `json:"routeTo,omitempty"`
+	Reasoning   string     `json:"reasoning,omitempty"`
+	LatencyMs   int64      `json:"latencyMs"`
+	Error       string     `json:"error,omitempty"` // el orchestrator falló y no hubo decisión
+	Prompt      *PromptRef `json:"prompt,omitempty"`
+	DecidedBy   string     `json:"decidedBy,omitempty"` // cache de FAQs, clasificador local (rules, model) o keywords sin LLM; vacío = LLM
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// IntentPrediction es la intención que estima el clasificador local antes de llamar al LLM
+type IntentPrediction struct {
+	Intent     string  `json:"intent"`
+	Confidence float64 `json:"confidence"`
+	Source     string  `json:"source"`         // rules o model
+	Rule       string  `json:"rule,omitempty"` // regla que aplicó (greeting, thanks, spam, faq_match)
+}
+
+// IntentModel es un clasificador naive Bayes de intenciones entrenado con decisiones del orchestrator
+type IntentModel struct {
+	TrainedAt  time.Time                    `json:"trainedAt"`
+	Examples   int                          `json:"examples"`
+	Vocabulary int                          `json:"vocabulary"`
+	Classes    map[string]*IntentClassStats `json:"classes"`
+}
+
+// IntentExample es un mensaje etiquetado para entrenar el clasificador de intenciones
+type IntentExample struct {
+	Message string `json:"message"`
+	Intent  string `json:"intent"`
+}
+
+// IntentClassStats cuenta los mensajes y términos vistos de una intención
+type IntentClassStats struct {
+	Docs   int            `json:"docs"`
+	Tokens int            `json:"tokens"`
+	Counts map[string]int `json:"counts"`
+}
+
+// DecisionFilter filtra el registro de decisiones; los campos vacíos no filtran
+type DecisionFilter struct {
+	From      time.Time
+	To        time.Time
+	Channel   string
+	Intent    string
+	SessionID string
+	Limit     int
+}
+
+// IntentBucket agrupa las decisiones del orchestrator de un intervalo de tiempo
+type IntentBucket struct {
+	Start         time.Time      `json:"start"`
+	Decisions     int            `json:"decisions"`
+	LowConfidence int            `json:"lowConfidence"`
+	ByIntent      map[string]int `json:"byIntent"`
+}
+
+// AmbiguousMessage es un mensaje (normalizado) que el orchestrator no supo clasificar
+type AmbiguousMessage struct {
+	Message  string    `json:"message"`
+	Count    int       `json:"count"`
+	Sessions int       `json:"sessions"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// IntentAnalytics resume las decisiones del orchestrator en un rango de fechas
+type IntentAnalytics struct {
+	From                   *time.Time         `json:"from,omitempty"`
+	To                     *time.Time         `json:"to,omitempty"`
+	Channel                string             `json:"channel,omitempty"`
+	Bucket                 string             `json:"bucket"`
+	Decisions              int                `json:"decisions"`
+	Errors                 int                `json:"errors"`
+	ByIntent               map[string]int     `json:"byIntent"`
+	ByRoute                map[string]int     `json:"byRoute"`
+	AvgConfidence          float64            `json:"avgConfidence"`
+	LowConfidenceThreshold float64            `json:"lowConfidenceThreshold"`
+	LowConfidenceRate      float64            `json:"lowConfidenceRate"`
+	AmbiguousShare         float64            `json:"ambiguousShare"`
+	SpamShare              float64            `json:"spamShare"`
+	AvgLatencyMs           float64            `json:"avgLatencyMs"`
+	P95LatencyMs           float64            `json:"p95LatencyMs"`
+	Series                 []IntentBucket     `json:"series"`
+	TopAmbiguous           []AmbiguousMessage `json:"topAmbiguous"`
+}
+
+// TokenUsage son los tokens de una llamada al LLM y su costo estimado
+type TokenUsage struct {
+	Model            string  `json:"model,omitempty"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+
+	// Other es lo que consumieron otros modelos en la misma llamada (ej. el principal antes
+	// de pasar al de respaldo). Cada uno se registra y se cobra con su precio.
+	Other []TokenUsage `json:"other,omitempty"`
+}
+
+// UsageRecord registra el consumo de una llamada al LLM dentro de una sesión
+type UsageRecord struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	Channel   string    `json:"channel"`
+	Agent     string    `json:"agent"`
+	Degraded  bool      `json:"degraded,omitempty"` // la sesión o el día superaban el presupuesto
+	CreatedAt time.Time `json:"createdAt"`
+	TokenUsage
+}
+
+// UsageTotals acumula el consumo de varias llamadas
+type UsageTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// Add suma una llamada a los totales
+func (t *UsageTotals) Add(usage TokenUsage) {
+	t.Calls++
+	t.PromptTokens += usage.PromptTokens
+	t.CompletionTokens += usage.CompletionTokens
+	t.TotalTokens += usage.TotalTokens
+	t.CostUSD += usage.CostUSD
+}
+
+// UsageFilter filtra el registro de consumo; los campos vacíos no filtran
+type UsageFilter struct {
+	From      time.Time
+	To        time.Time
+	Channel   string
+	SessionID string
+	Agent     string
+}
+
+// ModelPrice es el precio de un modelo en USD por millón de tokens
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// DailyUsage es el consumo de un día (zona horaria del servidor)
+type DailyUsage struct {
+	Day string `json:"day"` // AAAA-MM-DD
+	UsageTotals
+}
+
+// SessionUsage es el consumo acumulado de una sesión
+type SessionUsage struct {
+	SessionID string `json:"sessionId"`
+	Channel   string `json:"channel"`
+	UsageTotals
+}
+
+// BudgetStatus indica si se superó algún presupuesto y el chat opera en modo económico
+type BudgetStatus struct {
+	SessionTokenLimit int     `json:"sessionTokenLimit"` // 0 = sin límite
+	DailyCostLimitUSD float64 `json:"dailyCostLimitUsd"` // 0 = sin límite
+	TodayCostUSD      float64 `json:"todayCostUsd"`
+	DailyExceeded     bool    `json:"dailyExceeded"`
+	SessionTokens     int     `json:"sessionTokens,omitempty"`
+	SessionExceeded   bool    `json:"sessionExceeded,omitempty"`
+}
+
+// UsageSummary resume el consumo de LLM en un rango de fechas
+type UsageSummary struct {
+	From        *time.Time             `json:"from,omitempty"`
+	To          *time.Time             `json:"to,omitempty"`
+	Totals      UsageTotals            `json:"totals"`
+	ByAgent     map[string]UsageTotals `json:"byAgent"`
+	ByChannel   map[string]UsageTotals `json:"byChannel"`
+	ByModel     map[string]UsageTotals `json:"byModel"`
+	ByDay       []DailyUsage           `json:"byDay"`
+	TopSessions []SessionUsage         `json:"topSessions"`
+	Budget      BudgetStatus           `json:"budget"`
+}
+
+// PromptPreviewRequest representa una solicitud de vista previa de plantilla
+type PromptPreviewRequest struct {
+	Channel string         `json:"channel"`
+	Data    map[string]any `json:"data,omitempty"`
 }
 
 // HealthResponse representa la respuesta del health check
@@ -109,18 +757,18 @@ type HealthResponse struct {
 
 // LeadData representa datos detallados de un lead para scoring
 type LeadData struct {
-	SessionID    string    `json:"sessionId"`
+	SessionID      string    `json:"sessionId"`
 	FirstMessageAt time.Time `json:"firstMessageAt"`
 	LastMessageAt  time.Time `json:"lastMessageAt"`
 	MessageCount   int       `json:"messageCount"`
 
-	PerfilDemografico    PerfilDemografico    `json:"perfilDemografico"`
+	PerfilDemografico     PerfilDemografico     `json:"perfilDemografico"`
 	ComportamientoDigital ComportamientoDigital `json:"comportamientoDigital"`
-	CapacidadFinanciera  CapacidadFinanciera  `json:"capacidadFinanciera"`
-	NecesidadUrgencia    NecesidadUrgencia    `json:"necesidadUrgencia"`
-	ExperienciaPrevia    ExperienciaPrevia    `json:"experienciaPrevia"`
-	EngagementActual     EngagementActual     `json:"engagementActual"`
-	ContextoCompra       ContextoCompra       `json:"contextoCompra"`
+	CapacidadFinanciera   CapacidadFinanciera   `json:"capacidadFinanciera"`
+	NecesidadUrgencia     NecesidadUrgencia     `json:"necesidadUrgencia"`
+	ExperienciaPrevia     ExperienciaPrevia     `json:"experienciaPrevia"`
+	EngagementActual      EngagementActual      `json:"engagementActual"`
+	ContextoCompra        ContextoCompra        `json:"contextoCompra"`
 
 	Boosts         []string `json:"boosts,omitempty"`
 	Penalizaciones []string `json:"penalizaciones,omitempty"`
@@ -128,11 +776,11 @@ type LeadData struct {
 
 // PerfilDemografico dimension 1 (0-10 puntos)
 type PerfilDemografico struct {
-	Ubicacion     string `json:"ubicacion"`
-	Profesion     string `json:"profesion"`
-	Coherencia    string `json:"coherencia"`
-	Contexto      string `json:"contexto"`
-	Score         int    `json:"score"`
+	Ubicacion  string `json:"ubicacion"`
+	Profesion  string `json:"profesion"`
+	Coherencia string `json:"coherencia"`
+	Contexto   string `json:"contexto"`
+	Score      int    `json:"score"`
 }
 
 // ComportamientoDigital dimension 2 (0-15 puntos)
@@ -155,10 +803,10 @@ type CapacidadFinanciera struct {
 
 // NecesidadUrgencia dimension 4 (0-15 puntos)
 type NecesidadUrgencia struct {
-	NivelUrgencia    string `json:"nivelUrgencia"`
-	Consecuencias    string `json:"consecuencias"`
-	PresionTemporal  string `json:"presionTemporal"`
-	Score            int    `json:"score"`
+	NivelUrgencia   string `json:"nivelUrgencia"`
+	Consecuencias   string `json:"consecuencias"`
+	PresionTemporal string `json:"presionTemporal"`
+	Score           int    `json:"score"`
 }
 
 // ExperienciaPrevia dimension 5 (0-10 puntos)
@@ -170,15 +818,15 @@ type ExperienciaPrevia struct {
 
 // EngagementActual dimension 6 (0-10 puntos)
 type EngagementActual struct {
-	Disponibilidad       string `json:"disponibilidad"`
-	InteresDemo          string `json:"interesDemo"`
+	Disponibilidad         string `json:"disponibilidad"`
+	InteresDemo            string `json:"interesDemo"`
 	SolicitudesEspecificas string `json:"solicitudesEspecificas"`
-	Score                int    `json:"score"`
+	Score                  int    `json:"score"`
 }
 
 // ContextoCompra dimension 7 (0-15 puntos)
 type ContextoCompra struct {
-	MotivoCompra        string `json:"motivoCompra"`
+	MotivoCompra           string `json:"motivoCompra"`
 	InvestigacionRealizada string `json:"investigacionRealizada"`
 	ConocimientoProducto   string `json:"conocimientoProducto"`
 	Score                  int    `json:"score"`
@@ -186,12 +834,137 @@ type ContextoCompra struct {
 
 // ScoringData resultado completo del scoring
 type ScoringData struct {
-	TotalScore         int      `json:"totalScore"`
-	Category           string   `json:"category"`
-	DimensionScores    map[string]int `json:"dimensionScores"`
-	Boosts             []string `json:"boosts,omitempty"`
-	Penalizaciones     []string `json:"penalizaciones,omitempty"`
-	AccionRecomendada  string   `json:"accionRecomendada"`
-	TiempoContacto     string   `json:"tiempoContacto"`
-	TipoSeguimiento    string   `json:"tipoSeguimiento"`
+	TotalScore        int            `json:"totalScore"`
+	Category          string         `json:"category"`
+	DimensionScores   map[string]int `json:"dimensionScores"`
+	Boosts            []string       `json:"boosts,omitempty"`
+	Penalizaciones    []string       `json:"penalizaciones,omitempty"`
+	AccionRecomendada string         `json:"accionRecomendada"`
+	TiempoContacto    string         `json:"tiempoContacto"`
+	TipoSeguimiento   string         `json:"tipoSeguimiento"`
+}
+
+// LeadRecord es un lead o conversación de un dataset externo (XLSX, CSV o JSONL) para scoring offline
+type LeadRecord struct {
+	ID               string            `json:"id"`
+	Channel          string            `json:"channel,omitempty"`
+	Messages         []Message         `json:"messages,omitempty"`
+	Fields           map[string]string `json:"fields,omitempty"`           // columnas que no son parte de la conversación (contacto, perfil)
+	ExpectedCategory string            `json:"expectedCategory,omitempty"` // etiqueta para backtesting
+	ExpectedScore    *int              `json:"expectedScore,omitempty"`
+
+	// Etiquetas para la evaluación del scoring
+	ExpectedScoreMin   *int           `json:"expectedScoreMin,omitempty"`
+	ExpectedScoreMax   *int           `json:"expectedScoreMax,omitempty"`
+	ExpectedDimensions map[string]int `json:"expectedDimensions,omitempty"`
+	ModelResponse      string         `json:"modelResponse,omitempty"` // respuesta grabada del LLM para evaluar sin Gemini
+}
+
+// BatchScoreResult es el resultado del scoring offline de un LeadRecord
+type BatchScoreResult struct {
+	ID               string         `json:"id"`
+	ExpectedCategory string         `json:"expectedCategory,omitempty"`
+	ExpectedScore    *int           `json:"expectedScore,omitempty"`
+	Score            int            `json:"score"`
+	Category         string         `json:"category"`
+	DimensionScores  map[string]int `json:"dimensionScores,omitempty"`
+	Match            *bool          `json:"match,omitempty"` // categoría igual a la esperada (solo si hay etiqueta)
+	Prompt           *PromptRef     `json:"prompt,omitempty"`
+	Error            string         `json:"error,omitempty"`
+	DurationMs       int64          `json:"durationMs"`
+}
+
+// BatchScoreSummary resume una corrida de scoring offline
+type BatchScoreSummary struct {
+	Total           int                       `json:"total"`
+	Scored          int                       `json:"scored"`
+	Failed          int                       `json:"failed"`
+	ByCategory      map[string]int            `json:"byCategory"`
+	AvgScore        float64                   `json:"avgScore"`
+	Labeled         int                       `json:"labeled"`
+	Accuracy        float64                   `json:"accuracy"`            // sobre los registros etiquetados
+	ScoreMAE        float64                   `json:"scoreMae,omitempty"`  // error absoluto medio contra expectedScore
+	Confusion       map[string]map[string]int `json:"confusion,omitempty"` // esperada -> obtenida -> cantidad
+	PromptVersions  map[string]int            `json:"promptVersions,omitempty"`
+	DurationSeconds float64                   `json:"durationSeconds"`
+}
+
+// ScoringEvalCase compara el scoring de una conversación etiquetada con lo esperado
+type ScoringEvalCase struct {
+	ID               string         `json:"id"`
+	ExpectedCategory string         `json:"expectedCategory,omitempty"`
+	Category         string         `json:"category"`
+	CategoryMatch    bool           `json:"categoryMatch"`
+	Score            int            `json:"score"`
+	ScoreMin         *int           `json:"scoreMin,omitempty"`
+	ScoreMax         *int           `json:"scoreMax,omitempty"`
+	ScoreInRange     *bool          `json:"scoreInRange,omitempty"`
+	DimensionScores  map[string]int `json:"dimensionScores,omitempty"`
+	DimensionErrors  map[string]int `json:"dimensionErrors,omitempty"` // |obtenido - esperado| por dimensión
+	Error            string         `json:"error,omitempty"`
+}
+
+// ScoringEvalReport es el resultado de evaluar el ScoringAgent contra un set etiquetado
+type ScoringEvalReport struct {
+	RunAt          time.Time                 `json:"runAt"`
+	Mode           string                    `json:"mode"` // real o fake
+	Dataset        string                    `json:"dataset,omitempty"`
+	PromptVersions map[string]int            `json:"promptVersions,omitempty"`
+	Total          int                       `json:"total"`
+	Evaluated      int                       `json:"evaluated"`
+	Failed         int                       `json:"failed"`
+	Accuracy       float64                   `json:"accuracy"`               // categoría correcta sobre los etiquetados
+	ScoreInRange   float64                   `json:"scoreInRange"`           // score dentro del rango esperado
+	Confusion      map[string]map[string]int `json:"confusion"`              // esperada -> obtenida -> cantidad
+	DimensionMAE   map[string]float64        `json:"dimensionMae,omitempty"` // error absoluto medio por dimensión
+	Cases          []ScoringEvalCase         `json:"cases"`
+	Drift          *ScoringEvalDrift         `json:"drift,omitempty"`
+}
+
+// ScoringEvalDrift compara una evaluación con una corrida anterior
+type ScoringEvalDrift struct {
+	BaselineRunAt     time.Time               `json:"baselineRunAt"`
+	AccuracyDelta     float64                 `json:"accuracyDelta"`
+	ScoreInRangeDelta float64                 `json:"scoreInRangeDelta"`
+	DimensionMAEDelta map[string]float64      `json:"dimensionMaeDelta,omitempty"`
+	Changed           []ScoringEvalCaseChange `json:"changed,omitempty"`
+}
+
+// ScoringEvalCaseChange es un caso cuya categoría o score cambió respecto de la corrida anterior
+type ScoringEvalCaseChange struct {
+	ID           string `json:"id"`
+	FromCategory string `json:"fromCategory"`
+	ToCategory   string `json:"toCategory"`
+	ScoreDelta   int    `json:"scoreDelta"`
+}
+
+// AuthUser es un usuario del dashboard; la contraseña se guarda con bcrypt
+type AuthUser struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash"`
+	Role         string `json:"role"` // specialist o admin
+	SpecialistID string `json:"specialistId,omitempty"`
+	Disabled     bool   `json:"disabled,omitempty"`
+}
+
+// AuthPrincipal es quien hace el request, autenticado con API key o JWT
+type AuthPrincipal struct {
+	Subject      string `json:"subject"` // usuario o nombre de la API key
+	Role         string `json:"role"`    // channel, specialist o admin
+	Method       string `json:"method"`  // api_key o jwt
+	SpecialistID string `json:"specialistId,omitempty"`
+}
+
+// LoginRequest pide un JWT para el dashboard
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResponse devuelve el JWT y su vencimiento
+type LoginResponse struct {
+	Success   bool           `json:"success"`
+	Token     string         `json:"token"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+	User      *AuthPrincipal `json:"user"`
 }
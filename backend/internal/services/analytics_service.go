@@ -0,0 +1,550 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Intervalos de las series de tiempo
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// maxAnalyticsBuckets evita series enormes cuando se pide bucket=day sobre varios años
+const maxAnalyticsBuckets = 1000
+
+// Intenciones del orchestrator que se destacan en la analítica
+const (
+	IntentAmbiguous = "ambiguous"
+	IntentSpam      = "spam"
+)
+
+// leadCategories son las categorías del scoring, en el orden en que se reportan
+var leadCategories = []string{"hot", "warm", "cold", "discarded"}
+
+// AnalyticsService calcula métricas de leads y del embudo sobre los datos en memoria.
+// Las fechas se agrupan en la zona horaria local del servidor, igual que el export.
+type AnalyticsService struct {
+	sessionService *SessionService
+	decisionLog    *DecisionLogService
+}
+
+var analyticsServiceInstance *AnalyticsService
+var analyticsServiceOnce sync.Once
+
+func GetAnalyticsService() *AnalyticsService {
+	analyticsServiceOnce.Do(func() {
+		analyticsServiceInstance = &AnalyticsService{
+			sessionService: GetSessionService(),
+			decisionLog:    GetDecisionLogService(),
+		}
+	})
+	return analyticsServiceInstance
+}
+
+// NormalizeBucket valida el intervalo pedido; vacío equivale a day
+func NormalizeBucket(bucket string) (string, error) {
+	switch bucket {
+	case "":
+		return BucketDay, nil
+	case BucketDay, BucketWeek, BucketMonth:
+		return bucket, nil
+	}
+	return "", fmt.Errorf("bucket inválido: %s (válidos: day, week, month)", bucket)
+}
+
+// Summary devuelve todas las métricas juntas, para armar un dashboard con una sola llamada
+func (a *AnalyticsService) Summary(filter models.AnalyticsFilter) (*models.LeadAnalytics, error) {
+	leads := a.leads(filter)
+
+	series, err := buildSeries(leads, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.LeadAnalytics{
+		Channel:           filter.Channel,
+		Bucket:            filter.Bucket,
+		Leads:             len(leads),
+		ByCategory:        emptyCategoryCounts(),
+		CategoryByChannel: categoryByChannel(leads),
+		Series:            series,
+		Dimensions:        dimensionAverages(leads),
+		Funnel:            stageFunnel(a.sessions(filter)),
+		TimeToFirstHot:    a.timeToFirstHot(leads),
+	}
+	if !filter.From.IsZero() {
+		result.From = &filter.From
+	}
+	if !filter.To.IsZero() {
+		result.To = &filter.To
+	}
+
+	total := 0
+	for _, lead := range leads {
+		result.ByCategory[lead.Category]++
+		total += lead.Score
+	}
+	if len(leads) > 0 {
+		result.AvgScore = round2(float64(total) / float64(len(leads)))
+	}
+	return result, nil
+}
+
+// TimeSeries cuenta leads y promedia su score por intervalo de creación
+func (a *AnalyticsService) TimeSeries(filter models.AnalyticsFilter) ([]models.AnalyticsBucket, error) {
+	return buildSeries(a.leads(filter), filter)
+}
+
+// CategoryByChannel cuenta leads por canal y categoría
+func (a *AnalyticsService) CategoryByChannel(filter models.AnalyticsFilter) map[string]map[string]int {
+	return categoryByChannel(a.leads(filter))
+}
+
+// Dimensions promedia los puntajes por dimensión del último scoring de cada lead
+func (a *AnalyticsService) Dimensions(filter models.AnalyticsFilter) models.DimensionAverages {
+	return dimensionAverages(a.leads(filter))
+}
+
+// Funnel cuenta cuántas sesiones creadas en el rango llegaron a cada etapa
+func (a *AnalyticsService) Funnel(filter models.AnalyticsFilter) []models.FunnelStageStats {
+	return stageFunnel(a.sessions(filter))
+}
+
+// TimeToFirstHot mide el tiempo entre el inicio de la conversación y el primer scoring hot
+func (a *AnalyticsService) TimeToFirstHot(filter models.AnalyticsFilter) models.TimeToHotStats {
+	return a.timeToFirstHot(a.leads(filter))
+}
+
+// Intents resume las decisiones del orchestrator: intenciones por intervalo, tasa de baja
+// confianza, proporción de ambiguos y spam, y los mensajes ambiguos más repetidos
+func (a *AnalyticsService) Intents(filter models.AnalyticsFilter, threshold float64, top int) (*models.IntentAnalytics, error) {
+	decisions := a.decisionLog.List(models.DecisionFilter{
+		From:    filter.From,
+		To:      filter.To,
+		Channel: filter.Channel,
+	})
+
+	times := make([]time.Time, len(decisions))
+	for i, decision := range decisions {
+		times[i] = decision.CreatedAt
+	}
+	starts, err := bucketRange(times, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.IntentAnalytics{
+		Channel:                filter.Channel,
+		Bucket:                 filter.Bucket,
+		ByIntent:               make(map[string]int),
+		ByRoute:                make(map[string]int),
+		LowConfidenceThreshold: threshold,
+		Series:                 make([]models.IntentBucket, len(starts)),
+		TopAmbiguous:           []models.AmbiguousMessage{},
+	}
+	if !filter.From.IsZero() {
+		result.From = &filter.From
+	}
+	if !filter.To.IsZero() {
+		result.To = &filter.To
+	}
+	index := make(map[int64]int, len(starts))
+	for i, start := range starts {
+		result.Series[i] = models.IntentBucket{Start: start, ByIntent: make(map[string]int)}
+		index[start.Unix()] = i
+	}
+
+	ambiguous := make(map[string]*models.AmbiguousMessage)
+	ambiguousSessions := make(map[string]map[string]bool)
+	var latencies []float64
+	confidenceSum := 0.0
+	lowConfidence, ambiguousCount, spamCount := 0, 0, 0
+
+	for _, decision := range decisions {
+		latencies = append(latencies, float64(decision.LatencyMs))
+		if decision.Error != "" {
+			result.Errors++
+			continue
+		}
+		result.Decisions++
+
+		intent := NormalizeIntent(decision.Intent)
+		result.ByIntent[intent]++
+		route := decision.RouteTo
+		if !decision.ShouldRoute || route == "" {
+			route = "orchestrator"
+		}
+		result.ByRoute[route]++
+
+		confidenceSum += decision.Confidence
+		low := decision.Confidence < threshold
+		if low {
+			lowConfidence++
+		}
+
+		if i, ok := index[bucketStart(decision.CreatedAt, filter.Bucket).Unix()]; ok {
+			result.Series[i].Decisions++
+			result.Series[i].ByIntent[intent]++
+			if low {
+				result.Series[i].LowConfidence++
+			}
+		}
+
+		switch intent {
+		case IntentSpam:
+			spamCount++
+		case IntentAmbiguous:
+			ambiguousCount++
+			key := normalizeMessage(decision.Message)
+			if key == "" {
+				continue
+			}
+			entry, ok := ambiguous[key]
+			if !ok {
+				entry = &models.AmbiguousMessage{Message: key}
+				ambiguous[key] = entry
+				ambiguousSessions[key] = make(map[string]bool)
+			}
+			entry.Count++
+			ambiguousSessions[key][decision.SessionID] = true
+			if decision.CreatedAt.After(entry.LastSeen) {
+				entry.LastSeen = decision.CreatedAt
+			}
+		}
+	}
+
+	if result.Decisions > 0 {
+		n := float64(result.Decisions)
+		result.AvgConfidence = round2(confidenceSum / n)
+		result.LowConfidenceRate = round2(float64(lowConfidence) / n)
+		result.AmbiguousShare = round2(float64(ambiguousCount) / n)
+		result.SpamShare = round2(float64(spamCount) / n)
+	}
+	if len(latencies) > 0 {
+		sort.Float64s(latencies)
+		sum := 0.0
+		for _, l := range latencies {
+			sum += l
+		}
+		result.AvgLatencyMs = round2(sum / float64(len(latencies)))
+		result.P95LatencyMs = round2(percentile(latencies, 0.95))
+	}
+
+	for key, entry := range ambiguous {
+		entry.Sessions = len(ambiguousSessions[key])
+		result.TopAmbiguous = append(result.TopAmbiguous, *entry)
+	}
+	sort.Slice(result.TopAmbiguous, func(i, j int) bool {
+		x, y := result.TopAmbiguous[i], result.TopAmbiguous[j]
+		if x.Count != y.Count {
+			return x.Count > y.Count
+		}
+		return x.LastSeen.After(y.LastSeen)
+	})
+	if top > 0 && len(result.TopAmbiguous) > top {
+		result.TopAmbiguous = result.TopAmbiguous[:top]
+	}
+	return result, nil
+}
+
+func (a *AnalyticsService) leads(filter models.AnalyticsFilter) []*models.Lead {
+	return a.sessionService.GetAllLeads(models.LeadFilter{
+		Channel: filter.Channel,
+		From:    filter.From,
+		To:      filter.To,
+	})
+}
+
+func (a *AnalyticsService) sessions(filter models.AnalyticsFilter) []models.Session {
+	return a.sessionService.sessionsCreatedBetween(filter.From, filter.To, filter.Channel)
+}
+
+// sessionsCreatedBetween copia las sesiones creadas en [from, to) para calcular métricas sin bloquear
+func (s *SessionService) sessionsCreatedBetween(from, to time.Time, channel string) []models.Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []models.Session
+	for _, session := range s.sessions {
+		if channel != "" && session.Channel != channel {
+			continue
+		}
+		if !from.IsZero() && session.CreatedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !session.CreatedAt.Before(to) {
+			continue
+		}
+		result = append(result, *session)
+	}
+	return result
+}
+
+func (a *AnalyticsService) timeToFirstHot(leads []*models.Lead) models.TimeToHotStats {
+	var minutes []float64
+	for _, lead := range leads {
+		if lead.FirstHotAt == nil {
+			continue
+		}
+		start := lead.CreatedAt
+		if session := a.sessionService.GetSession(lead.SessionID); session != nil {
+			start = session.CreatedAt
+		}
+		if elapsed := lead.FirstHotAt.Sub(start); elapsed >= 0 {
+			minutes = append(minutes, elapsed.Minutes())
+		}
+	}
+
+	stats := models.TimeToHotStats{Leads: len(minutes)}
+	if len(minutes) == 0 {
+		return stats
+	}
+	sort.Float64s(minutes)
+
+	sum := 0.0
+	for _, m := range minutes {
+		sum += m
+	}
+	stats.MedianMinutes = round2(percentile(minutes, 0.5))
+	stats.P90Minutes = round2(percentile(minutes, 0.9))
+	stats.AvgMinutes = round2(sum / float64(len(minutes)))
+	return stats
+}
+
+// buildSeries arma la serie de leads por intervalo de creación
+func buildSeries(leads []*models.Lead, filter models.AnalyticsFilter) ([]models.AnalyticsBucket, error) {
+	times := make([]time.Time, len(leads))
+	for i, lead := range leads {
+		times[i] = lead.CreatedAt
+	}
+	starts, err := bucketRange(times, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]models.AnalyticsBucket, len(starts))
+	index := make(map[int64]int, len(starts))
+	for i, start := range starts {
+		series[i] = models.AnalyticsBucket{Start: start, ByCategory: emptyCategoryCounts()}
+		index[start.Unix()] = i
+	}
+
+	totals := make([]int, len(series))
+	for _, lead := range leads {
+		i, ok := index[bucketStart(lead.CreatedAt, filter.Bucket).Unix()]
+		if !ok {
+			continue
+		}
+		series[i].Leads++
+		series[i].ByCategory[lead.Category]++
+		totals[i] += lead.Score
+	}
+	for i := range series {
+		if series[i].Leads > 0 {
+			series[i].AvgScore = round2(float64(totals[i]) / float64(series[i].Leads))
+		}
+	}
+	return series, nil
+}
+
+// bucketRange devuelve el inicio de cada intervalo entre from y to (o entre la primera y la
+// última fecha), incluyendo los intervalos vacíos para que los gráficos no tengan huecos
+func bucketRange(times []time.Time, filter models.AnalyticsFilter) ([]time.Time, error) {
+	start, end := filter.From, filter.To
+	for _, t := range times {
+		if filter.From.IsZero() && (start.IsZero() || t.Before(start)) {
+			start = t
+		}
+		if filter.To.IsZero() && (end.IsZero() || !t.Before(end)) {
+			end = t.Add(time.Nanosecond)
+		}
+	}
+
+	starts := []time.Time{}
+	if start.IsZero() || end.IsZero() || !start.Before(end) {
+		return starts, nil
+	}
+	for t := bucketStart(start, filter.Bucket); t.Before(end); t = nextBucket(t, filter.Bucket) {
+		if len(starts) == maxAnalyticsBuckets {
+			return nil, fmt.Errorf("el rango tiene más de %d intervalos de %s; usa un bucket mayor o acota from/to", maxAnalyticsBuckets, filter.Bucket)
+		}
+		starts = append(starts, t)
+	}
+	return starts, nil
+}
+
+// bucketStart trunca la fecha al inicio del día, de la semana (lunes) o del mes
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.In(time.Local)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	switch bucket {
+	case BucketWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	return day
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return t.AddDate(0, 0, 7)
+	case BucketMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+func categoryByChannel(leads []*models.Lead) map[string]map[string]int {
+	result := make(map[string]map[string]int)
+	for _, lead := range leads {
+		counts, ok := result[lead.Channel]
+		if !ok {
+			counts = emptyCategoryCounts()
+			result[lead.Channel] = counts
+		}
+		counts[lead.Category]++
+	}
+	return result
+}
+
+func dimensionAverages(leads []*models.Lead) models.DimensionAverages {
+	result := models.DimensionAverages{
+		Overall:    make(map[string]float64),
+		ByCategory: make(map[string]map[string]float64),
+	}
+
+	sums := make(map[string]map[string]int)
+	counts := make(map[string]map[string]int)
+	add := func(group, key string, value int) {
+		if sums[group] == nil {
+			sums[group] = make(map[string]int)
+			counts[group] = make(map[string]int)
+		}
+		sums[group][key] += value
+		counts[group][key]++
+	}
+
+	for _, lead := range leads {
+		if len(lead.Dimensions) == 0 {
+			continue
+		}
+		result.Leads++
+		for key, value := range lead.Dimensions {
+			add("", key, value)
+			add(lead.Category, key, value)
+		}
+	}
+
+	for group, byKey := range sums {
+		averages := make(map[string]float64, len(byKey))
+		for key, sum := range byKey {
+			averages[key] = round2(float64(sum) / float64(counts[group][key]))
+		}
+		if group == "" {
+			result.Overall = averages
+		} else {
+			result.ByCategory[group] = averages
+		}
+	}
+	return result
+}
+
+// stageFunnel cuenta por etapa las sesiones que llegaron a ella. Como las etapas avanzan
+// en orden, llegar a una implica haber pasado por las anteriores aunque se hayan saltado.
+// closed se cuenta aparte porque se puede cerrar desde cualquier etapa.
+func stageFunnel(sessions []models.Session) []models.FunnelStageStats {
+	reached := make([]int, len(FunnelStages))
+	current := make([]int, len(FunnelStages))
+	closedIndex := StageIndex(StageClosed)
+
+	for _, session := range sessions {
+		current[StageIndex(session.Stage)]++
+
+		furthest, closed := 0, session.Stage == StageClosed
+		visited := []string{session.Stage}
+		for _, change := range session.StageHistory {
+			visited = append(visited, change.From, change.To)
+		}
+		for _, stage := range visited {
+			i := StageIndex(stage)
+			if i == closedIndex {
+				closed = true
+			} else if i > furthest {
+				furthest = i
+			}
+		}
+
+		for i := 0; i <= furthest; i++ {
+			reached[i]++
+		}
+		if closed {
+			reached[closedIndex]++
+		}
+	}
+
+	funnel := make([]models.FunnelStageStats, len(FunnelStages))
+	for i, stage := range FunnelStages {
+		funnel[i] = models.FunnelStageStats{
+			Stage:   stage,
+			Reached: reached[i],
+			Current: current[i],
+		}
+		if reached[0] > 0 {
+			funnel[i].FromStart = round2(float64(reached[i]) / float64(reached[0]))
+		}
+		if i > 0 && i != closedIndex && reached[i-1] > 0 {
+			funnel[i].FromPrevious = round2(float64(reached[i]) / float64(reached[i-1]))
+		}
+	}
+	return funnel
+}
+
+// NormalizeIntent unifica las variantes de una intención: el prompt pide "ambiguous"
+// y el orchestrator usa "ambiguo" cuando no puede leer la respuesta del modelo
+func NormalizeIntent(intent string) string {
+	intent = strings.ToLower(strings.TrimSpace(intent))
+	switch intent {
+	case "", "ambiguo":
+		return IntentAmbiguous
+	}
+	return intent
+}
+
+// normalizeMessage agrupa mensajes que solo difieren en mayúsculas, espacios o puntuación final
+func normalizeMessage(message string) string {
+	message = strings.ToLower(strings.Join(strings.Fields(message), " "))
+	return strings.Trim(message, " .,;:!?¿¡")
+}
+
+func emptyCategoryCounts() map[string]int {
+	counts := make(map[string]int, len(leadCategories))
+	for _, category := range leadCategories {
+		counts[category] = 0
+	}
+	return counts
+}
+
+// percentile interpola linealmente sobre valores ya ordenados
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{10, 20, 30, 40, 50}
+	cases := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 10},
+		{0.5, 30},
+		{0.9, 46},
+		{0.95, 48},
+		{1, 50},
+	}
+	for _, c := range cases {
+		if got := percentile(values, c.p); got != c.want {
+			t.Errorf("percentile(%v) = %v, se esperaba %v", c.p, got, c.want)
+		}
+	}
+	if got := percentile([]float64{7}, 0.95); got != 7 {
+		t.Errorf("un solo valor: %v", got)
+	}
+	if got := percentile([]float64{1, 2}, 0.5); got != 1.5 {
+		t.Errorf("interpolación entre dos valores: %v", got)
+	}
+}
+
+func TestBucketStart(t *testing.T) {
+	at := time.Date(2025, 3, 12, 18, 30, 0, 0, time.Local) // miércoles
+	cases := map[string]time.Time{
+		BucketDay:   time.Date(2025, 3, 12, 0, 0, 0, 0, time.Local),
+		BucketWeek:  time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local),
+		BucketMonth: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local),
+	}
+	for bucket, want := range cases {
+		if got := bucketStart(at, bucket); !got.Equal(want) {
+			t.Errorf("bucketStart(%s) = %v, se esperaba %v", bucket, got, want)
+		}
+	}
+	// El domingo pertenece a la semana que empezó el lunes anterior
+	sunday := time.Date(2025, 3, 16, 23, 0, 0, 0, time.Local)
+	if got := bucketStart(sunday, BucketWeek); !got.Equal(cases[BucketWeek]) {
+		t.Errorf("domingo: %v", got)
+	}
+}
+
+func TestBucketRange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 10, 0, 0, 0, time.Local) }
+
+	// Sin from/to el rango va de la primera a la última fecha, con los días vacíos incluidos
+	starts, err := bucketRange([]time.Time{day(5), day(2), day(3)}, models.AnalyticsFilter{Bucket: BucketDay})
+	if err != nil || len(starts) != 4 {
+		t.Fatalf("rango por día: %v, %v", starts, err)
+	}
+	if starts[0].Day() != 2 || starts[3].Day() != 5 {
+		t.Errorf("días inesperados: %v", starts)
+	}
+
+	// from y to mandan sobre las fechas
+	filter := models.AnalyticsFilter{
+		From:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local),
+		To:     time.Date(2025, 3, 31, 23, 59, 59, 0, time.Local),
+		Bucket: BucketMonth,
+	}
+	if starts, _ := bucketRange([]time.Time{day(5)}, filter); len(starts) != 3 || starts[0].Month() != time.January {
+		t.Errorf("rango por mes: %v", starts)
+	}
+
+	filter.Bucket = BucketWeek
+	if starts, _ := bucketRange(nil, filter); len(starts) != 14 || starts[0].Weekday() != time.Monday {
+		t.Errorf("rango por semana: %d intervalos desde %v", len(starts), starts)
+	}
+
+	if starts, err := bucketRange(nil, models.AnalyticsFilter{Bucket: BucketDay}); err != nil || len(starts) != 0 {
+		t.Errorf("sin datos no hay intervalos: %v, %v", starts, err)
+	}
+
+	filter = models.AnalyticsFilter{From: time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local), To: time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local), Bucket: BucketDay}
+	if _, err := bucketRange(nil, filter); err == nil || !strings.Contains(err.Error(), "intervalos") {
+		t.Errorf("más de %d intervalos debía fallar: %v", maxAnalyticsBuckets, err)
+	}
+}
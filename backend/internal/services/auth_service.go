@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles de acceso a la API; admin puede todo
+const (
+	AuthRoleChannel    = "channel"    // integraciones de canal (bot de WhatsApp)
+	AuthRoleSpecialist = "specialist" // asesores en el dashboard
+	AuthRoleAdmin      = "admin"
+)
+
+// Formas de autenticarse
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+const jwtIssuer = "bob-backend"
+
+// publicChannel es el canal del widget web, abierto sin credenciales. Las sesiones de otros
+// canales (wa-<número>) solo las escriben las integraciones de canal.
+const publicChannel = "web"
+
+var (
+	ErrAuthMissing      = errors.New("credenciales requeridas")
+	ErrAuthInvalid      = errors.New("credenciales inválidas")
+	ErrLoginUnavailable = errors.New("login deshabilitado: falta AUTH_JWT_SECRET")
+)
+
+type jwtClaims struct {
+	Sub string `json:"sub"`
+	Iss string `json:"iss"`
+	Iat int64  `json:"iat"`
+	Exp int64  `json:"exp"`
+}
+
+// AuthService autentica los requests: API keys (AUTH_API_KEYS) para las integraciones de
+// canal y JWT firmados con AUTH_JWT_SECRET para los usuarios del dashboard (AUTH_USERS_FILE).
+// El rol del JWT se toma siempre del archivo de usuarios, así deshabilitar o cambiar el rol
+// de un usuario aplica sin esperar a que venza su token.
+type AuthService struct {
+	enabled  bool
+	secret   []byte
+	ttl      time.Duration
+	apiKeys  map[string]*models.AuthPrincipal // sha256 de la clave -> principal
+	users    map[string]*models.AuthUser
+	dataFile string
+	// dummyHash se compara cuando el usuario no existe, para que el login tarde lo mismo
+	dummyHash []byte
+	mu        sync.RWMutex
+}
+
+var authServiceInstance *AuthService
+var authServiceOnce sync.Once
+
+func GetAuthService() *AuthService {
+	authServiceOnce.Do(func() {
+		ttl := time.Duration(config.AppConfig.AuthTokenTTLMinutes) * time.Minute
+		if ttl <= 0 {
+			ttl = 12 * time.Hour
+		}
+
+		authServiceInstance = &AuthService{
+			enabled:  config.AppConfig.AuthEnabled,
+			secret:   []byte(config.AppConfig.AuthJWTSecret),
+			ttl:      ttl,
+			apiKeys:  parseAPIKeys(config.AppConfig.AuthAPIKeys),
+			users:    make(map[string]*models.AuthUser),
+			dataFile: config.AppConfig.AuthUsersFile,
+		}
+		authServiceInstance.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("bob-dummy-password"), bcrypt.DefaultCost)
+		authServiceInstance.loadFromDisk()
+
+		switch {
+		case !authServiceInstance.enabled:
+			log.Printf("⚠️ AUTH_ENABLED=false: leads y acciones de administración quedan sin autenticación")
+		case len(authServiceInstance.secret) == 0:
+			log.Printf("⚠️ Sin AUTH_JWT_SECRET el login del dashboard está deshabilitado, solo se aceptan API keys")
+		case len(authServiceInstance.secret) < 32:
+			log.Printf("⚠️ AUTH_JWT_SECRET tiene menos de 32 caracteres")
+		}
+	})
+	return authServiceInstance
+}
+
+// parseAPIKeys lee "nombre:rol:clave" separados por coma
+func parseAPIKeys(raw string) map[string]*models.AuthPrincipal {
+	keys := make(map[string]*models.AuthPrincipal)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" || !validRole(parts[1]) {
+			log.Printf("⚠️ API key ignorada, formato esperado nombre:rol:clave (rol channel, specialist o admin)")
+			continue
+		}
+		keys[hashAPIKey(parts[2])] = &models.AuthPrincipal{
+			Subject: parts[0],
+			Role:    parts[1],
+			Method:  AuthMethodAPIKey,
+		}
+	}
+	return keys
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func validRole(role string) bool {
+	return role == AuthRoleChannel || role == AuthRoleSpecialist || role == AuthRoleAdmin
+}
+
+// Enabled indica si las rutas protegidas exigen credenciales
+func (a *AuthService) Enabled() bool {
+	return a.enabled
+}
+
+// Allows indica si el principal tiene alguno de los roles; admin siempre puede
+func (a *AuthService) Allows(principal *models.AuthPrincipal, roles ...string) bool {
+	if principal == nil {
+		return false
+	}
+	if principal.Role == AuthRoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if principal.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CanWriteSession indica si el principal puede enviar mensajes a una sesión del canal
+func (a *AuthService) CanWriteSession(principal *models.AuthPrincipal, channel string) bool {
+	return !a.enabled || channel == publicChannel || a.Allows(principal, AuthRoleChannel)
+}
+
+// CanReadSession indica si el principal puede leer el historial de una sesión del canal
+func (a *AuthService) CanReadSession(principal *models.AuthPrincipal, channel string) bool {
+	return !a.enabled || channel == publicChannel || a.Allows(principal, AuthRoleChannel, AuthRoleSpecialist)
+}
+
+// Authenticate identifica el request por el header X-API-Key o Authorization: Bearer
+// (JWT o API key). Devuelve ErrAuthMissing si no trae credenciales.
+func (a *AuthService) Authenticate(r *http.Request) (*models.AuthPrincipal, error) {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return a.authenticateKey(key)
+	}
+
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if header == "" {
+		return nil, ErrAuthMissing
+	}
+	scheme, token, found := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrAuthInvalid
+	}
+	if strings.Count(token, ".") == 2 {
+		return a.verifyToken(token)
+	}
+	return a.authenticateKey(token)
+}
+
+func (a *AuthService) authenticateKey(key string) (*models.AuthPrincipal, error) {
+	principal, ok := a.apiKeys[hashAPIKey(key)]
+	if !ok {
+		return nil, ErrAuthInvalid
+	}
+	copied := *principal
+	return &copied, nil
+}
+
+// Login valida usuario y contraseña y devuelve un JWT
+func (a *AuthService) Login(username, password string) (string, time.Time, *models.AuthPrincipal, error) {
+	if len(a.secret) == 0 {
+		return "", time.Time{}, nil, ErrLoginUnavailable
+	}
+
+	a.mu.RLock()
+	user, ok := a.users[strings.ToLower(strings.TrimSpace(username))]
+	a.mu.RUnlock()
+
+	hash := a.dummyHash
+	if ok {
+		hash = []byte(user.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok || user.Disabled {
+		log.Printf("🔒 Login rechazado: %s", username)
+		return "", time.Time{}, nil, ErrAuthInvalid
+	}
+
+	token, expiresAt, err := a.IssueToken(user.Username)
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	log.Printf("🔑 Login: %s (%s)", user.Username, user.Role)
+	return token, expiresAt, userPrincipal(user), nil
+}
+
+// IssueToken firma un JWT (HS256) para el usuario
+func (a *AuthService) IssueToken(username string) (string, time.Time, error) {
+	if len(a.secret) == 0 {
+		return "", time.Time{}, ErrLoginUnavailable
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(a.ttl)
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims, err := json.Marshal(jwtClaims{
+		Sub: username,
+		Iss: jwtIssuer,
+		Iat: now.Unix(),
+		Exp: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + a.sign(unsigned), expiresAt, nil
+}
+
+func (a *AuthService) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (a *AuthService) verifyToken(token string) (*models.AuthPrincipal, error) {
+	if len(a.secret) == 0 {
+		return nil, ErrAuthInvalid
+	}
+
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(raw, &header) != nil || header.Alg != "HS256" {
+		return nil, ErrAuthInvalid
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(a.sign(parts[0]+"."+parts[1]))) {
+		return nil, ErrAuthInvalid
+	}
+
+	var claims jwtClaims
+	raw, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(raw, &claims) != nil {
+		return nil, ErrAuthInvalid
+	}
+	if claims.Iss != jwtIssuer || time.Now().Unix() >= claims.Exp {
+		return nil, ErrAuthInvalid
+	}
+
+	a.mu.RLock()
+	user, ok := a.users[strings.ToLower(claims.Sub)]
+	a.mu.RUnlock()
+	if !ok || user.Disabled {
+		return nil, ErrAuthInvalid
+	}
+	return userPrincipal(user), nil
+}
+
+func userPrincipal(user *models.AuthUser) *models.AuthPrincipal {
+	return &models.AuthPrincipal{
+		Subject:      user.Username,
+		Role:         user.Role,
+		Method:       AuthMethodJWT,
+		SpecialistID: user.SpecialistID,
+	}
+}
+
+// User devuelve el usuario del dashboard, o nil si no existe
+func (a *AuthService) User(username string) *models.AuthUser {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	user, ok := a.users[strings.ToLower(strings.TrimSpace(username))]
+	if !ok {
+		return nil
+	}
+	copied := *user
+	return &copied
+}
+
+// SaveUser crea o actualiza un usuario del dashboard; password vacío conserva la anterior
+func (a *AuthService) SaveUser(user models.AuthUser, password string) error {
+	user.Username = strings.ToLower(strings.TrimSpace(user.Username))
+	if user.Username == "" {
+		return fmt.Errorf("username es requerido")
+	}
+	if user.Role != AuthRoleSpecialist && user.Role != AuthRoleAdmin {
+		return fmt.Errorf("rol inválido: %s (specialist o admin)", user.Role)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		user.PasswordHash = string(hash)
+	} else if existing, ok := a.users[user.Username]; ok {
+		user.PasswordHash = existing.PasswordHash
+	} else {
+		return fmt.Errorf("password es requerido para un usuario nuevo")
+	}
+
+	a.users[user.Username] = &user
+	a.saveToDisk()
+
+	log.Printf("👤 Usuario guardado: %s (%s)", user.Username, user.Role)
+	return nil
+}
+
+// NewAPIKey genera una clave aleatoria para AUTH_API_KEYS
+func NewAPIKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "bob_" + hex.EncodeToString(buf), nil
+}
+
+func (a *AuthService) loadFromDisk() {
+	data, err := os.ReadFile(a.dataFile)
+	if err != nil {
+		return
+	}
+
+	var users []*models.AuthUser
+	if err := json.Unmarshal(data, &users); err != nil {
+		log.Printf("Error al cargar usuarios: %v", err)
+		return
+	}
+	for _, user := range users {
+		if user.Username == "" || !validRole(user.Role) || user.Role == AuthRoleChannel {
+			continue
+		}
+		a.users[strings.ToLower(user.Username)] = user
+	}
+	log.Printf("%d usuarios del dashboard cargados desde disco", len(a.users))
+}
+
+func (a *AuthService) saveToDisk() {
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	users := make([]*models.AuthUser, 0, len(names))
+	for _, name := range names {
+		users = append(users, a.users[name])
+	}
+
+	if data, err := json.MarshalIndent(users, "", "  "); err == nil {
+		// Solo el usuario del servidor puede leer los hashes
+		if err := os.WriteFile(a.dataFile, data, 0600); err != nil {
+			log.Printf("Error al guardar usuarios: %v", err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"encoding/base64"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuth(t *testing.T) *AuthService {
+	t.Helper()
+	return &AuthService{
+		enabled: true,
+		secret:  []byte("0123456789abcdef0123456789abcdef"),
+		ttl:     time.Hour,
+		apiKeys: parseAPIKeys("whatsapp:channel:clave-bot, panel:admin:clave-admin, mala:root:x, incompleta"),
+		users: map[string]*models.AuthUser{
+			"ana":  {Username: "ana", Role: AuthRoleSpecialist, SpecialistID: "sp-ana"},
+			"luis": {Username: "luis", Role: AuthRoleAdmin, Disabled: true},
+		},
+		dataFile: filepath.Join(t.TempDir(), "users.json"),
+	}
+}
+
+func authenticate(a *AuthService, header, value string) (*models.AuthPrincipal, error) {
+	req := httptest.NewRequest("GET", "/api/leads", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	return a.Authenticate(req)
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	a := newTestAuth(t)
+	if len(a.apiKeys) != 2 {
+		t.Fatalf("se esperaban 2 claves válidas, hay %d", len(a.apiKeys))
+	}
+	for hash := range a.apiKeys {
+		if strings.Contains(hash, "clave") {
+			t.Error("las claves se guardan hasheadas")
+		}
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	a := newTestAuth(t)
+
+	principal, err := authenticate(a, "X-API-Key", "clave-bot")
+	if err != nil || principal.Subject != "whatsapp" || principal.Role != AuthRoleChannel || principal.Method != AuthMethodAPIKey {
+		t.Fatalf("X-API-Key: %+v, %v", principal, err)
+	}
+	if principal, err := authenticate(a, "Authorization", "Bearer clave-admin"); err != nil || principal.Role != AuthRoleAdmin {
+		t.Errorf("API key como Bearer: %+v, %v", principal, err)
+	}
+	if _, err := authenticate(a, "X-API-Key", "otra"); err != ErrAuthInvalid {
+		t.Errorf("clave desconocida: %v", err)
+	}
+	if _, err := authenticate(a, "", ""); err != ErrAuthMissing {
+		t.Errorf("sin credenciales: %v", err)
+	}
+	if _, err := authenticate(a, "Authorization", "Basic abc"); err != ErrAuthInvalid {
+		t.Errorf("esquema distinto de Bearer: %v", err)
+	}
+}
+
+func TestJWTSignAndVerify(t *testing.T) {
+	a := newTestAuth(t)
+
+	token, expiresAt, err := a.IssueToken("ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(expiresAt) <= 0 || time.Until(expiresAt) > time.Hour {
+		t.Errorf("vencimiento inesperado: %v", expiresAt)
+	}
+
+	principal, err := authenticate(a, "Authorization", "Bearer "+token)
+	if err != nil {
+		t.Fatalf("token válido rechazado: %v", err)
+	}
+	if principal.Subject != "ana" || principal.Role != AuthRoleSpecialist || principal.SpecialistID != "sp-ana" || principal.Method != AuthMethodJWT {
+		t.Errorf("principal inesperado: %+v", principal)
+	}
+
+	parts := strings.Split(token, ".")
+	forge := func(header, claims string) string {
+		unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+		return unsigned + "." + a.sign(unsigned)
+	}
+	now := time.Now().Unix()
+
+	other := newTestAuth(t)
+	other.secret = []byte("otro-secreto-otro-secreto-otro-se")
+	foreign, _, _ := other.IssueToken("ana")
+
+	invalid := map[string]string{
+		"firma alterada":   parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])),
+		"otro secreto":     foreign,
+		"alg none":         base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".",
+		"vencido":          forge(`{"alg":"HS256"}`, `{"sub":"ana","iss":"bob-backend","exp":`+strconv.FormatInt(now-1, 10)+`}`),
+		"otro emisor":      forge(`{"alg":"HS256"}`, `{"sub":"ana","iss":"otro","exp":`+strconv.FormatInt(now+60, 10)+`}`),
+		"usuario inactivo": forge(`{"alg":"HS256"}`, `{"sub":"luis","iss":"bob-backend","exp":`+strconv.FormatInt(now+60, 10)+`}`),
+		"usuario borrado":  forge(`{"alg":"HS256"}`, `{"sub":"nadie","iss":"bob-backend","exp":`+strconv.FormatInt(now+60, 10)+`}`),
+	}
+	for name, token := range invalid {
+		if _, err := authenticate(a, "Authorization", "Bearer "+token); err != ErrAuthInvalid {
+			t.Errorf("%s: se esperaba ErrAuthInvalid, fue %v", name, err)
+		}
+	}
+
+	// El rol sale del archivo de usuarios, no del token: cambiarlo aplica de inmediato
+	a.users["ana"].Role = AuthRoleAdmin
+	if principal, _ := authenticate(a, "Authorization", "Bearer "+token); principal == nil || principal.Role != AuthRoleAdmin {
+		t.Errorf("el rol debía tomarse del usuario actual: %+v", principal)
+	}
+
+	a.secret = nil
+	if _, _, err := a.IssueToken("ana"); err != ErrLoginUnavailable {
+		t.Errorf("sin secreto no se firman tokens: %v", err)
+	}
+	if _, err := authenticate(a, "Authorization", "Bearer "+token); err != ErrAuthInvalid {
+		t.Errorf("sin secreto no se aceptan tokens: %v", err)
+	}
+}
+
+func TestAuthAllows(t *testing.T) {
+	a := newTestAuth(t)
+	specialist := &models.AuthPrincipal{Role: AuthRoleSpecialist}
+	admin := &models.AuthPrincipal{Role: AuthRoleAdmin}
+	channel := &models.AuthPrincipal{Role: AuthRoleChannel}
+
+	cases := []struct {
+		name      string
+		principal *models.AuthPrincipal
+		roles     []string
+		want      bool
+	}{
+		{"sin credenciales", nil, []string{AuthRoleSpecialist}, false},
+		{"rol permitido", specialist, []string{AuthRoleSpecialist}, true},
+		{"rol no permitido", channel, []string{AuthRoleSpecialist}, false},
+		{"admin siempre pasa", admin, []string{AuthRoleChannel}, true},
+		{"admin sin roles", admin, nil, true},
+	}
+	for _, c := range cases {
+		if got := a.Allows(c.principal, c.roles...); got != c.want {
+			t.Errorf("%s: Allows = %v", c.name, got)
+		}
+	}
+
+	if !a.CanWriteSession(nil, "web") || a.CanWriteSession(nil, "whatsapp") || a.CanWriteSession(specialist, "whatsapp") || !a.CanWriteSession(channel, "whatsapp") {
+		t.Error("CanWriteSession: solo el canal web es público y solo las integraciones escriben en otros canales")
+	}
+	if !a.CanReadSession(specialist, "whatsapp") || a.CanReadSession(nil, "whatsapp") {
+		t.Error("CanReadSession: los especialistas leen los historiales de otros canales")
+	}
+}
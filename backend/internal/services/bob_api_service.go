@@ -3,6 +3,7 @@ package services
 import (
 	"bob-hackathon/internal/config"
 	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/telemetry"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,11 +15,11 @@ import (
 )
 
 type BOBAPIService struct {
-	baseURL      string
-	cache        []models.Vehicle
-	lastFetch    time.Time
+	baseURL       string
+	cache         []models.Vehicle
+	lastFetch     time.Time
 	cacheDuration time.Duration
-	mu           sync.RWMutex
+	mu            sync.RWMutex
 }
 
 var bobAPIServiceInstance *BOBAPIService
@@ -41,9 +42,11 @@ func (b *BOBAPIService) GetSublots(forceRefresh bool) ([]models.Vehicle, error)
 
 	// Verificar cache
 	if !forceRefresh && time.Since(b.lastFetch) < b.cacheDuration && len(b.cache) > 0 {
+		telemetry.CountBOBAPICache(true)
 		log.Printf("Usando cache de vehículos (%d items)", len(b.cache))
 		return b.cache, nil
 	}
+	telemetry.CountBOBAPICache(false)
 
 	// Fetch desde API
 	url := fmt.Sprintf("%s/sublots/details", b.baseURL)
@@ -60,14 +63,14 @@ func (b *BOBAPIService) GetSublots(forceRefresh bool) ([]models.Vehicle, error)
 
 	var apiResponse struct {
 		Data []struct {
-			ID           string  `json:"id"`
-			Brand        string  `json:"brand"`
-			Model        string  `json:"model"`
-			Year         string  `json:"year"`
-			StartPrice   float64 `json:"start_price"`
-			AuctionType  string  `json:"auction_type"`
-			Status       string  `json:"status"`
-			Image        string  `json:"image"`
+			ID          string  `json:"id"`
+			Brand       string  `json:"brand"`
+			Model       string  `json:"model"`
+			Year        string  `json:"year"`
+			StartPrice  float64 `json:"start_price"`
+			AuctionType string  `json:"auction_type"`
+			Status      string  `json:"status"`
+			Image       string  `json:"image"`
 		} `json:"data"`
 	}
 
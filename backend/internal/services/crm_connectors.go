@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CRMConnector envía eventos de leads a un sistema externo. Push debe ser idempotente
+// respecto de event.IdempotencyKey: el outbox puede reintentar el mismo evento.
+type CRMConnector interface {
+	Name() string
+	Push(event *models.CRMEvent) error
+}
+
+// permanentError marca errores que no se arreglan reintentando (ej. 400, 401)
+type permanentError struct{ err error }
+
+func (p *permanentError) Error() string { return p.err.Error() }
+func (p *permanentError) Unwrap() error { return p.err }
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// crmResponseError es una respuesta no exitosa del sistema externo
+type crmResponseError struct {
+	status int
+	msg    string
+}
+
+func (e *crmResponseError) Error() string { return e.msg }
+
+// responseStatus devuelve el código HTTP del error, o 0 si no vino de una respuesta
+func responseStatus(err error) int {
+	var r *crmResponseError
+	if errors.As(err, &r) {
+		return r.status
+	}
+	return 0
+}
+
+// sendCRMRequest hace la llamada HTTP y clasifica la respuesta: 2xx ok, 408/429/5xx reintentable, resto permanente
+func sendCRMRequest(client *http.Client, req *http.Request, event *models.CRMEvent, okStatuses ...int) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", event.IdempotencyKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	for _, code := range okStatuses {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = &crmResponseError{
+		status: resp.StatusCode,
+		msg:    fmt.Sprintf("respuesta %d de %s: %s", resp.StatusCode, req.URL.Host, strings.TrimSpace(string(body))),
+	}
+	if resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return &permanentError{err}
+}
+
+// webhookConnector envía el evento completo como JSON a una URL
+type webhookConnector struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookConnector) Name() string { return "webhook" }
+
+func (w *webhookConnector) Push(event *models.CRMEvent) error {
+	if w.url == "" {
+		return &permanentError{fmt.Errorf("CRM_WEBHOOK_URL no configurado")}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return &permanentError{err}
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("X-BOB-Event", event.Type)
+
+	return sendCRMRequest(w.client, req, event)
+}
+
+// hubSpotConnector sincroniza el lead como contacto usando la API REST de objetos estilo HubSpot.
+// El contacto se identifica por la propiedad única bob_session_id.
+type hubSpotConnector struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (h *hubSpotConnector) Name() string { return "hubspot" }
+
+func (h *hubSpotConnector) Push(event *models.CRMEvent) error {
+	if h.token == "" {
+		return &permanentError{fmt.Errorf("CRM_HUBSPOT_TOKEN no configurado")}
+	}
+
+	data, err := json.Marshal(map[string]any{"properties": hubSpotProperties(&event.Lead)})
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	base := strings.TrimRight(h.baseURL, "/") + "/crm/v3/objects/contacts"
+	if event.Type != LeadCreated {
+		err := h.send(http.MethodPatch, base+"/"+url.PathEscape(event.SessionID)+"?idProperty=bob_session_id", data, event)
+		if responseStatus(err) != http.StatusNotFound {
+			return err
+		}
+		// El contacto no existe (lead.created quedó en dead o el conector se activó después):
+		// se crea con el estado actual del lead
+		log.Printf("🔗 Contacto %s no existe en hubspot, se crea", event.SessionID)
+	}
+
+	// 409 en la creación: el contacto ya existe (reintento de un envío que sí llegó)
+	return h.send(http.MethodPost, base, data, event, http.StatusConflict)
+}
+
+func (h *hubSpotConnector) send(method, target string, data []byte, event *models.CRMEvent, okStatuses ...int) error {
+	req, err := http.NewRequest(method, target, bytes.NewReader(data))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Authorization", "Bearer "+h.token)
+	return sendCRMRequest(h.client, req, event, okStatuses...)
+}
+
+func hubSpotProperties(lead *models.Lead) map[string]string {
+	return map[string]string{
+		"bob_session_id":   lead.SessionID,
+		"bob_channel":      lead.Channel,
+		"bob_score":        strconv.Itoa(lead.Score),
+		"bob_category":     lead.Category,
+		"bob_stage":        lead.Stage,
+		"bob_status":       lead.Status,
+		"bob_assigned_to":  lead.AssignedTo,
+		"bob_vehicle_lots": strings.Join(lead.VehicleLots, ";"),
+		"bob_loss_reason":  lead.LossReason,
+		"bob_urgency":      lead.Urgency,
+		"bob_budget":       lead.Budget,
+		"bob_last_message": lead.LastMessage,
+	}
+}
+
+// csvConnector deja los eventos en un CSV diario para importarlos en herramientas sin API.
+// Cada evento es una fila identificada por idempotency_key: un reintento la reemplaza.
+type csvConnector struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var crmCSVHeader = []string{
+	"occurred_at", "event", "idempotency_key", "session_id", "channel", "score", "category",
+	"stage", "status", "assigned_to", "vehicle_lots", "loss_reason",
+}
+
+func (c *csvConnector) Name() string { return "csv" }
+
+func (c *csvConnector) Push(event *models.CRMEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(c.dir, "leads-"+event.OccurredAt.Format("2006-01-02")+".csv")
+
+	rows := [][]string{crmCSVHeader}
+	if file, err := os.Open(path); err == nil {
+		existing, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		if err != nil {
+			return &permanentError{fmt.Errorf("CSV inválido %s: %w", path, err)}
+		}
+		if len(existing) > 0 {
+			rows = existing
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	lead := event.Lead
+	row := []string{
+		event.OccurredAt.Format(time.RFC3339),
+		event.Type,
+		event.IdempotencyKey,
+		lead.SessionID,
+		lead.Channel,
+		strconv.Itoa(lead.Score),
+		lead.Category,
+		lead.Stage,
+		lead.Status,
+		lead.AssignedTo,
+		strings.Join(lead.VehicleLots, ";"),
+		lead.LossReason,
+	}
+
+	replaced := false
+	for i := 1; i < len(rows); i++ {
+		if len(rows[i]) > 2 && rows[i][2] == event.IdempotencyKey {
+			rows[i] = row
+			replaced = true
+		}
+	}
+	if !replaced {
+		rows = append(rows, row)
+	}
+
+	// Se escribe en un temporal y se renombra: un fallo a mitad no deja filas cortadas
+	tmp, err := os.CreateTemp(c.dir, ".leads-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := csv.NewWriter(tmp)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
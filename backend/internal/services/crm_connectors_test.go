@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testCRMEvent(eventType, key string, score int) *models.CRMEvent {
+	return &models.CRMEvent{
+		Type:           eventType,
+		IdempotencyKey: key,
+		SessionID:      "s-1",
+		Lead:           models.Lead{SessionID: "s-1", Channel: "web", Score: score, Category: "warm", Status: LeadStatusNew},
+		OccurredAt:     time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC),
+	}
+}
+
+func readCRMCSV(t *testing.T, dir string) [][]string {
+	t.Helper()
+	file, err := os.Open(filepath.Join(dir, "leads-2025-03-10.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestCSVConnectorUpsertsByIdempotencyKey(t *testing.T) {
+	dir := t.TempDir()
+	connector := &csvConnector{dir: dir}
+
+	for _, event := range []*models.CRMEvent{
+		testCRMEvent(LeadCreated, "k-1", 40),
+		testCRMEvent(LeadCreated, "k-1", 40), // reintento del mismo evento
+		testCRMEvent(LeadScoreChanged, "k-2", 70),
+		testCRMEvent(LeadScoreChanged, "k-2", 72),
+	} {
+		if err := connector.Push(event); err != nil {
+			t.Fatalf("push %s: %v", event.IdempotencyKey, err)
+		}
+	}
+
+	rows := readCRMCSV(t, dir)
+	if len(rows) != 3 {
+		t.Fatalf("se esperaban cabecera y 2 filas, hay %d: %v", len(rows), rows)
+	}
+	if strings.Join(rows[0], ",") != strings.Join(crmCSVHeader, ",") {
+		t.Errorf("cabecera inesperada: %v", rows[0])
+	}
+	if rows[1][2] != "k-1" || rows[2][2] != "k-2" {
+		t.Errorf("orden de filas inesperado: %v", rows)
+	}
+	if rows[2][5] != "72" {
+		t.Errorf("el reintento debía reemplazar la fila: score %s", rows[2][5])
+	}
+}
+
+// fakeHubSpot imita la API de contactos: PATCH de un contacto inexistente da 404 y crear
+// uno que ya existe da 409
+type fakeHubSpot struct {
+	mu       sync.Mutex
+	contacts map[string]map[string]string
+	methods  []string
+	status   int // si no es 0, todas las respuestas usan este código
+}
+
+func (f *fakeHubSpot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.methods = append(f.methods, r.Method)
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer dev" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Properties map[string]string `json:"properties"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch r.Method {
+	case http.MethodPost:
+		id := body.Properties["bob_session_id"]
+		if _, ok := f.contacts[id]; ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.contacts[id] = body.Properties
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPatch:
+		id := strings.TrimPrefix(r.URL.Path, "/crm/v3/objects/contacts/")
+		contact, ok := f.contacts[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range body.Properties {
+			contact[k] = v
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func newFakeHubSpot(t *testing.T) (*fakeHubSpot, *hubSpotConnector) {
+	fake := &fakeHubSpot{contacts: make(map[string]map[string]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, &hubSpotConnector{baseURL: server.URL, token: "dev", client: server.Client()}
+}
+
+func TestHubSpotPatchFallsBackToCreate(t *testing.T) {
+	fake, connector := newFakeHubSpot(t)
+
+	// El lead.created nunca llegó: la actualización crea el contacto
+	if err := connector.Push(testCRMEvent(LeadScoreChanged, "k-2", 70)); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if got := fake.contacts["s-1"]["bob_score"]; got != "70" {
+		t.Fatalf("el contacto no se creó con el score: %q", got)
+	}
+	if strings.Join(fake.methods, ",") != "PATCH,POST" {
+		t.Errorf("llamadas inesperadas: %v", fake.methods)
+	}
+
+	// Con el contacto creado, la siguiente actualización es solo un PATCH
+	fake.methods = nil
+	if err := connector.Push(testCRMEvent(LeadScoreChanged, "k-3", 88)); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if got := fake.contacts["s-1"]["bob_score"]; got != "88" {
+		t.Errorf("score no actualizado: %q", got)
+	}
+	if strings.Join(fake.methods, ",") != "PATCH" {
+		t.Errorf("llamadas inesperadas: %v", fake.methods)
+	}
+}
+
+func TestHubSpotCreateIsIdempotent(t *testing.T) {
+	_, connector := newFakeHubSpot(t)
+
+	for i := 0; i < 2; i++ {
+		if err := connector.Push(testCRMEvent(LeadCreated, "k-1", 40)); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+}
+
+func TestHubSpotErrorClassification(t *testing.T) {
+	fake, connector := newFakeHubSpot(t)
+
+	cases := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusServiceUnavailable, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusBadRequest, true},
+		{http.StatusUnauthorized, true},
+	}
+	for _, c := range cases {
+		fake.status = c.status
+		err := connector.Push(testCRMEvent(LeadCreated, "k-1", 40))
+		if err == nil {
+			t.Fatalf("%d: se esperaba error", c.status)
+		}
+		if isPermanent(err) != c.permanent {
+			t.Errorf("%d: permanente=%v, se esperaba %v", c.status, isPermanent(err), c.permanent)
+		}
+		if responseStatus(err) != c.status {
+			t.Errorf("%d: responseStatus=%d", c.status, responseStatus(err))
+		}
+	}
+}
+
+func TestCRMIdempotencyKeyScoreRoundTrip(t *testing.T) {
+	at := time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC)
+	lead := func(score int, category string, minutes int) *models.Lead {
+		return &models.Lead{SessionID: "s-1", Score: score, Category: category, UpdatedAt: at.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	first := crmIdempotencyKey(LeadScoreChanged, lead(60, "warm", 0))
+	if again := crmIdempotencyKey(LeadScoreChanged, lead(60, "warm", 0)); again != first {
+		t.Error("el mismo cambio debe dar la misma clave")
+	}
+	hot := crmIdempotencyKey(LeadScoreChanged, lead(80, "hot", 5))
+	back := crmIdempotencyKey(LeadScoreChanged, lead(60, "warm", 10))
+	if back == first || back == hot {
+		t.Error("volver a 60/warm es un cambio nuevo y necesita otra clave")
+	}
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de una entrada del outbox
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+const (
+	crmMaxAttempts = 8
+	crmBaseBackoff = 30 * time.Second
+	crmMaxBackoff  = time.Hour
+)
+
+type CRMService struct {
+	connectors map[string]CRMConnector
+	outbox     map[string]*models.CRMOutboxEntry
+	keys       map[string]bool // connector|idempotencyKey ya encolados
+	interval   time.Duration
+	dataFile   string
+	startOnce  sync.Once
+	syncMu     sync.Mutex // evita dos sincronizaciones en paralelo
+	mu         sync.RWMutex
+}
+
+var crmServiceInstance *CRMService
+var crmServiceOnce sync.Once
+
+func GetCRMService() *CRMService {
+	crmServiceOnce.Do(func() {
+		client := &http.Client{Timeout: 15 * time.Second}
+		available := map[string]CRMConnector{
+			"webhook": &webhookConnector{url: config.AppConfig.CRMWebhookURL, client: client},
+			"hubspot": &hubSpotConnector{baseURL: config.AppConfig.CRMHubSpotURL, token: config.AppConfig.CRMHubSpotToken, client: client},
+			"csv":     &csvConnector{dir: config.AppConfig.CRMCSVDir},
+		}
+
+		crmServiceInstance = &CRMService{
+			connectors: make(map[string]CRMConnector),
+			outbox:     make(map[string]*models.CRMOutboxEntry),
+			keys:       make(map[string]bool),
+			interval:   time.Duration(config.AppConfig.CRMSyncSeconds) * time.Second,
+			dataFile:   filepath.Join("data", "crm_outbox.json"),
+		}
+		for _, name := range strings.Split(config.AppConfig.CRMConnectors, ",") {
+			name = strings.TrimSpace(strings.ToLower(name))
+			if name == "" {
+				continue
+			}
+			if connector, ok := available[name]; ok {
+				crmServiceInstance.connectors[name] = connector
+			} else {
+				log.Printf("⚠️ Conector CRM desconocido: %s", name)
+			}
+		}
+		crmServiceInstance.loadFromDisk()
+
+		GetSessionService().OnLeadChange(crmServiceInstance.Enqueue)
+	})
+	return crmServiceInstance
+}
+
+// RegisterConnector agrega un conector en tiempo de ejecución
+func (c *CRMService) RegisterConnector(connector CRMConnector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connectors[connector.Name()] = connector
+}
+
+// GetConnectors devuelve los nombres de los conectores activos
+func (c *CRMService) GetConnectors() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.connectors))
+	for name := range c.connectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start lanza la sincronización periódica del outbox
+func (c *CRMService) Start() {
+	if len(c.GetConnectors()) == 0 {
+		log.Println("Sincronización CRM desactivada (CRM_CONNECTORS vacío)")
+		return
+	}
+	if c.interval <= 0 {
+		log.Println("Sincronización CRM automática desactivada (CRM_SYNC_SECONDS <= 0)")
+		return
+	}
+
+	c.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(c.interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				c.Sync()
+			}
+		}()
+		log.Printf("Sincronización CRM activa (%s, cada %s)", strings.Join(c.GetConnectors(), ", "), c.interval)
+	})
+}
+
+// Enqueue agrega el cambio del lead al outbox de cada conector. Es el LeadListener del SessionService.
+func (c *CRMService) Enqueue(eventType string, lead models.Lead) {
+	event := models.CRMEvent{
+		Type:           eventType,
+		IdempotencyKey: crmIdempotencyKey(eventType, &lead),
+		SessionID:      lead.SessionID,
+		Lead:           lead,
+		OccurredAt:     time.Now(),
+	}
+	// El snapshot no necesita el historial completo del lead
+	event.Lead.Audit = nil
+	event.Lead.Notes = nil
+	event.Lead.Assignments = nil
+
+	// Los sistemas externos no reciben datos personales del texto de la conversación
+	event.Lead.LastMessage = RedactPII(event.Lead.LastMessage)
+	if len(lead.Reasons) > 0 {
+		event.Lead.Reasons = make([]string, len(lead.Reasons))
+		for i, reason := range lead.Reasons {
+			event.Lead.Reasons[i] = RedactPII(reason)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	added := 0
+	for name := range c.connectors {
+		key := name + "|" + event.IdempotencyKey
+		if c.keys[key] {
+			continue
+		}
+		entry := &models.CRMOutboxEntry{
+			ID:            uuid.New().String(),
+			Connector:     name,
+			Event:         event,
+			Status:        OutboxPending,
+			NextAttemptAt: event.OccurredAt,
+			CreatedAt:     event.OccurredAt,
+		}
+		c.outbox[entry.ID] = entry
+		c.keys[key] = true
+		added++
+	}
+	if added > 0 {
+		c.saveToDisk()
+	}
+}
+
+// crmIdempotencyKey identifica el cambio concreto: el mismo cambio genera siempre la misma clave.
+// Cada cambio lleva su fecha, así volver a un score o estado anterior es un evento nuevo.
+func crmIdempotencyKey(eventType string, lead *models.Lead) string {
+	parts := []string{eventType, lead.SessionID}
+	switch eventType {
+	case LeadScoreChanged:
+		parts = append(parts, strconv.Itoa(lead.Score), lead.Category, lead.UpdatedAt.Format(time.RFC3339Nano))
+	case LeadStatusChanged:
+		parts = append(parts, lead.Status, lead.StatusTimestamps[lead.Status].Format(time.RFC3339Nano))
+	case LeadAssigned:
+		at := ""
+		if lead.AssignedAt != nil {
+			at = lead.AssignedAt.Format(time.RFC3339Nano)
+		}
+		parts = append(parts, lead.AssignedTo, at)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Sync envía las entradas pendientes cuyo próximo intento ya venció. Devuelve enviadas y fallidas.
+func (c *CRMService) Sync() (int, int) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	now := time.Now()
+
+	// Los eventos de un mismo lead y conector se envían en orden: si uno está
+	// esperando reintento, los posteriores esperan con él
+	waiting := make(map[string]time.Time) // connector|session -> creación del primer evento en espera
+
+	c.mu.RLock()
+	var due []*models.CRMOutboxEntry
+	for _, entry := range c.outbox {
+		if entry.Status != OutboxPending {
+			continue
+		}
+		if now.Before(entry.NextAttemptAt) {
+			lane := crmLane(entry)
+			if first, ok := waiting[lane]; !ok || entry.CreatedAt.Before(first) {
+				waiting[lane] = entry.CreatedAt
+			}
+			continue
+		}
+		copied := *entry
+		due = append(due, &copied)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+
+	sent, failed := 0, 0
+	for _, entry := range due {
+		lane := crmLane(entry)
+		if first, ok := waiting[lane]; ok && entry.CreatedAt.After(first) {
+			continue
+		}
+
+		c.mu.RLock()
+		connector, ok := c.connectors[entry.Connector]
+		c.mu.RUnlock()
+
+		var err error
+		if !ok {
+			err = &permanentError{fmt.Errorf("conector %s no está activo", entry.Connector)}
+		} else {
+			err = connector.Push(&entry.Event)
+		}
+
+		c.finish(entry.ID, err)
+		if err != nil {
+			if !isPermanent(err) {
+				waiting[lane] = entry.CreatedAt
+			}
+			failed++
+		} else {
+			sent++
+		}
+	}
+
+	if sent+failed > 0 {
+		log.Printf("🔄 Sincronización CRM: %d enviados, %d con error", sent, failed)
+	}
+	return sent, failed
+}
+
+func crmLane(entry *models.CRMOutboxEntry) string {
+	return entry.Connector + "|" + entry.Event.SessionID
+}
+
+// finish guarda el resultado de un intento, con backoff exponencial para los reintentos
+func (c *CRMService) finish(id string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.outbox[id]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	entry.Attempts++
+
+	switch {
+	case err == nil:
+		entry.Status = OutboxSent
+		entry.SentAt = &now
+		entry.LastError = ""
+	case isPermanent(err) || entry.Attempts >= crmMaxAttempts:
+		entry.Status = OutboxDead
+		entry.LastError = err.Error()
+		log.Printf("❌ Evento CRM %s (%s) descartado tras %d intentos: %v", entry.Event.Type, entry.Connector, entry.Attempts, err)
+	default:
+		backoff := crmBaseBackoff << (entry.Attempts - 1)
+		if backoff > crmMaxBackoff {
+			backoff = crmMaxBackoff
+		}
+		entry.NextAttemptAt = now.Add(backoff)
+		entry.LastError = err.Error()
+		log.Printf("⚠️ Error enviando evento CRM a %s (intento %d, reintento en %s): %v", entry.Connector, entry.Attempts, backoff, err)
+	}
+
+	c.saveToDisk()
+}
+
+// Retry vuelve a poner en cola una entrada descartada o adelanta un reintento
+func (c *CRMService) Retry(id string) (*models.CRMOutboxEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.outbox[id]
+	if !ok {
+		return nil, fmt.Errorf("entrada de outbox no encontrada: %s", id)
+	}
+	if entry.Status == OutboxSent {
+		return nil, fmt.Errorf("la entrada ya fue enviada")
+	}
+
+	entry.Status = OutboxPending
+	entry.Attempts = 0
+	entry.NextAttemptAt = time.Now()
+	c.saveToDisk()
+
+	return entry, nil
+}
+
+// GetOutbox lista las entradas del outbox, las más recientes primero
+func (c *CRMService) GetOutbox(status, connector string) []*models.CRMOutboxEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []*models.CRMOutboxEntry
+	for _, entry := range c.outbox {
+		if status != "" && entry.Status != status {
+			continue
+		}
+		if connector != "" && entry.Connector != connector {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+func (c *CRMService) loadFromDisk() {
+	data, err := os.ReadFile(c.dataFile)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal(data, &c.outbox); err != nil {
+		log.Printf("Error al cargar outbox CRM: %v", err)
+		return
+	}
+	for _, entry := range c.outbox {
+		c.keys[entry.Connector+"|"+entry.Event.IdempotencyKey] = true
+	}
+	log.Printf("%d eventos CRM cargados desde disco", len(c.outbox))
+}
+
+func (c *CRMService) saveToDisk() {
+	if data, err := json.MarshalIndent(c.outbox, "", "  "); err == nil {
+		if err := os.WriteFile(c.dataFile, data, 0644); err != nil {
+			log.Printf("Error al guardar outbox CRM: %v", err)
+		}
+	}
+}
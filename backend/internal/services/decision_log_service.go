@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DecisionLogService guarda cada decisión del orchestrator para analizar intenciones y ruteo.
+// Las decisiones se agregan a un archivo JSONL (una por línea) en vez de reescribir todo el
+// archivo en cada mensaje; en memoria se conservan las últimas DECISION_LOG_MAX.
+type DecisionLogService struct {
+	decisions  []models.OrchestratorDecision // de la más antigua a la más reciente
+	maxEntries int
+	dataFile   string
+	mu         sync.RWMutex
+}
+
+var decisionLogServiceInstance *DecisionLogService
+var decisionLogServiceOnce sync.Once
+
+func GetDecisionLogService() *DecisionLogService {
+	decisionLogServiceOnce.Do(func() {
+		maxEntries := config.AppConfig.DecisionLogMax
+		if maxEntries <= 0 {
+			maxEntries = 20000
+		}
+
+		decisionLogServiceInstance = &DecisionLogService{
+			maxEntries: maxEntries,
+			dataFile:   filepath.Join("data", "orchestrator_decisions.jsonl"),
+		}
+		decisionLogServiceInstance.loadFromDisk()
+	})
+	return decisionLogServiceInstance
+}
+
+// Record agrega una decisión al registro
+func (d *DecisionLogService) Record(decision models.OrchestratorDecision) {
+	if decision.ID == "" {
+		decision.ID = uuid.New().String()
+	}
+	if decision.CreatedAt.IsZero() {
+		decision.CreatedAt = time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.decisions = append(d.decisions, decision)
+	if len(d.decisions) > d.maxEntries {
+		d.decisions = append([]models.OrchestratorDecision(nil), d.decisions[len(d.decisions)-d.maxEntries:]...)
+	}
+	d.appendToDisk(decision)
+}
+
+// List devuelve las decisiones que cumplen el filtro, de la más reciente a la más antigua
+func (d *DecisionLogService) List(filter models.DecisionFilter) []models.OrchestratorDecision {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := []models.OrchestratorDecision{}
+	for i := len(d.decisions) - 1; i >= 0; i-- {
+		decision := d.decisions[i]
+		if filter.Channel != "" && decision.Channel != filter.Channel {
+			continue
+		}
+		if filter.Intent != "" && (decision.Error != "" || NormalizeIntent(decision.Intent) != NormalizeIntent(filter.Intent)) {
+			continue
+		}
+		if filter.SessionID != "" && decision.SessionID != filter.SessionID {
+			continue
+		}
+		if !filter.From.IsZero() && decision.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !decision.CreatedAt.Before(filter.To) {
+			continue
+		}
+
+		result = append(result, decision)
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+	return result
+}
+
+func (d *DecisionLogService) loadFromDisk() {
+	file, err := os.Open(d.dataFile)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var decision models.OrchestratorDecision
+		if err := json.Unmarshal(scanner.Bytes(), &decision); err != nil {
+			continue
+		}
+		lines++
+		d.decisions = append(d.decisions, decision)
+		if len(d.decisions) > 2*d.maxEntries {
+			d.decisions = d.decisions[len(d.decisions)-d.maxEntries:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error al cargar decisiones del orchestrator: %v", err)
+	}
+	if len(d.decisions) > d.maxEntries {
+		d.decisions = d.decisions[len(d.decisions)-d.maxEntries:]
+	}
+
+	// El archivo solo crece: compactarlo cuando duplica lo que se conserva
+	if lines > 2*d.maxEntries {
+		d.rewriteToDisk()
+	}
+	log.Printf("%d decisiones del orchestrator cargadas desde disco", len(d.decisions))
+}
+
+func (d *DecisionLogService) appendToDisk(decision models.OrchestratorDecision) {
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(d.dataFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Error al guardar decisión del orchestrator: %v", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Printf("Error al guardar decisión del orchestrator: %v", err)
+	}
+}
+
+func (d *DecisionLogService) rewriteToDisk() {
+	tmp := d.dataFile + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.Printf("Error al compactar decisiones del orchestrator: %v", err)
+		return
+	}
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, decision := range d.decisions {
+		encoder.Encode(decision)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		log.Printf("Error al compactar decisiones del orchestrator: %v", err)
+		return
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, d.dataFile); err != nil {
+		log.Printf("Error al compactar decisiones del orchestrator: %v", err)
+	}
+}
@@ -0,0 +1,512 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Disparadores soportados por las reglas de escalación
+const (
+	TriggerCategory          = "category"
+	TriggerSpecialistRequest = "specialist_request"
+	TriggerFrustration       = "frustration"
+	TriggerHighValue         = "high_value"
+)
+
+// Estados de una escalación
+const (
+	EscalationOpen     = "open"
+	EscalationBreached = "breached"
+	EscalationResolved = "resolved"
+)
+
+// EscalationRule define cuándo escalar un lead, con qué prioridad, SLA y a quién avisar
+type EscalationRule struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Trigger    string   `json:"trigger"`
+	Enabled    bool     `json:"enabled"`
+	Category   string   `json:"category,omitempty"` // trigger category
+	MinCount   int      `json:"minCount,omitempty"` // trigger frustration: mensajes frustrados
+	MinValue   float64  `json:"minValue,omitempty"` // trigger high_value: precio/presupuesto
+	Repeat     bool     `json:"repeat"`             // volver a escalar una vez atendida la anterior
+	SLAMinutes int      `json:"slaMinutes"`
+	Priority   string   `json:"priority"`
+	Notifiers  []string `json:"notifiers"`
+}
+
+// EscalationInput es lo observado en un turno, después del intent y del scoring
+type EscalationInput struct {
+	Session      *models.Session
+	Message      string
+	Intent       string
+	Category     string
+	MessageSlots map[string]string // detectados en el mensaje actual
+	Slots        map[string]string // acumulados en la sesión
+}
+
+type EscalationService struct {
+	rules       []EscalationRule
+	escalations map[string]*models.Escalation
+	notifiers   map[string]Notifier
+	dataFile    string
+	mu          sync.RWMutex
+}
+
+var escalationServiceInstance *EscalationService
+var escalationServiceOnce sync.Once
+
+var (
+	frustrationRegex = regexp.MustCompile(`(?i)(no entiendo|no me (ayuda|sirve|entiendes)|no sirve|in[uú]til|p[eé]sim[oa]|harto|molest[oa]|ya te dije|otra vez lo mismo|nadie (me )?responde|qu[eé] mal servicio|es una estafa|😡|🤬|😤)`)
+	amountRegex      = regexp.MustCompile(`(?i)(\d[\d.,]*)\s*(mil|k|lucas)?`)
+)
+
+// Reglas por defecto, usadas si no existe el archivo de reglas
+var defaultEscalationRules = []EscalationRule{
+	{ID: "hot-lead", Name: "Lead caliente", Trigger: TriggerCategory, Enabled: true, Category: "hot", SLAMinutes: 60, Priority: "high", Notifiers: []string{"log", "webhook"}},
+	{ID: "specialist-request", Name: "Solicitó especialista", Trigger: TriggerSpecialistRequest, Enabled: true, Repeat: true, SLAMinutes: 30, Priority: "high", Notifiers: []string{"log", "webhook", "whatsapp_group"}},
+	{ID: "frustration", Name: "Usuario frustrado", Trigger: TriggerFrustration, Enabled: true, Repeat: true, MinCount: 2, SLAMinutes: 30, Priority: "medium", Notifiers: []string{"log", "webhook"}},
+	{ID: "high-value", Name: "Vehículo de alto valor", Trigger: TriggerHighValue, Enabled: true, MinValue: 50000, SLAMinutes: 120, Priority: "medium", Notifiers: []string{"log", "email"}},
+}
+
+func GetEscalationService() *EscalationService {
+	escalationServiceOnce.Do(func() {
+		client := &http.Client{Timeout: 10 * time.Second}
+		escalationServiceInstance = &EscalationService{
+			escalations: make(map[string]*models.Escalation),
+			dataFile:    filepath.Join("data", "escalations.json"),
+		}
+		escalationServiceInstance.notifiers = map[string]Notifier{
+			"log":     logNotifier{},
+			"webhook": &webhookNotifier{url: config.AppConfig.EscalationWebhookURL, client: client},
+			"email":   &emailNotifier{to: config.AppConfig.EscalationEmailTo},
+			"whatsapp_group": &whatsAppGroupNotifier{
+				group:  config.AppConfig.EscalationWhatsAppGroup,
+				sender: &whatsAppSender{url: config.AppConfig.WhatsAppSendURL, client: client},
+			},
+		}
+		escalationServiceInstance.loadRules(config.AppConfig.EscalationRulesFile)
+		escalationServiceInstance.loadFromDisk()
+	})
+	return escalationServiceInstance
+}
+
+func (e *EscalationService) loadRules(file string) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Printf("Sin archivo de reglas de escalación (%s), usando reglas por defecto", file)
+		e.rules = defaultEscalationRules
+		return
+	}
+
+	var rules []EscalationRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("⚠️ Reglas de escalación inválidas en %s: %v. Usando reglas por defecto", file, err)
+		e.rules = defaultEscalationRules
+		return
+	}
+
+	e.rules = rules
+	log.Printf("%d reglas de escalación cargadas desde %s", len(rules), file)
+}
+
+// RegisterNotifier agrega o reemplaza un canal de notificación
+func (e *EscalationService) RegisterNotifier(n Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.notifiers[n.Name()] = n
+}
+
+// GetRules devuelve las reglas configuradas
+func (e *EscalationService) GetRules() []EscalationRule {
+	return e.rules
+}
+
+// Evaluate aplica las reglas a un turno y crea las escalaciones que correspondan.
+// Una sesión no se escala dos veces por la misma regla mientras siga pendiente,
+// ni después de atendida si la regla no tiene Repeat. in.Session se lee sin lock: debe
+// ser una copia (SessionService.Snapshot).
+func (e *EscalationService) Evaluate(in EscalationInput) []*models.Escalation {
+	if in.Session == nil || in.Session.IsHumanMode() {
+		return nil
+	}
+
+	var created []*models.Escalation
+	for _, rule := range e.rules {
+		if !rule.Enabled {
+			continue
+		}
+		reason, matched := e.matchRule(rule, in)
+		if !matched {
+			continue
+		}
+		if esc := e.create(rule, in.Session, reason, true); esc != nil {
+			created = append(created, esc)
+		}
+	}
+
+	return created
+}
+
+// Escalate crea una escalación fuera de la evaluación por turno (ej. un seguimiento vencido)
+func (e *EscalationService) Escalate(rule EscalationRule, session *models.Session, reason string) *models.Escalation {
+	return e.create(rule, session, reason, false)
+}
+
+func (e *EscalationService) matchRule(rule EscalationRule, in EscalationInput) (string, bool) {
+	switch rule.Trigger {
+	case TriggerCategory:
+		if in.Category != "" && in.Category == rule.Category {
+			return fmt.Sprintf("%s: categoría %s", rule.Name, in.Category), true
+		}
+	case TriggerSpecialistRequest:
+		if req := in.MessageSlots[SlotSpecialist]; req != "" {
+			return fmt.Sprintf("%s: \"%s\"", rule.Name, req), true
+		}
+	case TriggerFrustration:
+		if !frustrationRegex.MatchString(in.Message) {
+			return "", false
+		}
+		count := 0
+		for _, msg := range in.Session.Messages {
+			if msg.Role == "user" && frustrationRegex.MatchString(msg.Content) {
+				count++
+			}
+		}
+		if count >= max(rule.MinCount, 1) {
+			return fmt.Sprintf("%s: %d mensajes con frustración", rule.Name, count), true
+		}
+	case TriggerHighValue:
+		if budget := parseAmount(in.Slots[SlotBudget]); budget > 0 && budget >= rule.MinValue {
+			return fmt.Sprintf("%s: presupuesto %s", rule.Name, in.Slots[SlotBudget]), true
+		}
+		if v := findMentionedVehicle(in.Message, in.Slots[SlotBrand], rule.MinValue); v != nil {
+			return fmt.Sprintf("%s: %s %s (desde %.0f)", rule.Name, v.Marca, v.Modelo, v.PrecioInicio), true
+		}
+	}
+	return "", false
+}
+
+// findMentionedVehicle busca en el catálogo un vehículo nombrado en el mensaje con precio >= minValue
+func findMentionedVehicle(message, brand string, minValue float64) *models.Vehicle {
+	vehicles, err := GetBOBAPIService().GetSublots(false)
+	if err != nil {
+		return nil
+	}
+
+	lower := strings.ToLower(message)
+	for i, v := range vehicles {
+		// Modelos muy cortos ("X", "S") darían falsos positivos
+		if v.PrecioInicio < minValue || len(strings.TrimSpace(v.Modelo)) < 3 {
+			continue
+		}
+		if brand != "" && !strings.EqualFold(v.Marca, brand) {
+			continue
+		}
+		if strings.Contains(lower, strings.ToLower(v.Modelo)) {
+			return &vehicles[i]
+		}
+	}
+	return nil
+}
+
+// parseAmount interpreta montos como "$20 mil", "15,000 dólares", "15.5k" o "USD 12.000,50"
+func parseAmount(text string) float64 {
+	match := amountRegex.FindStringSubmatch(text)
+	if match == nil {
+		return 0
+	}
+
+	value, err := strconv.ParseFloat(normalizeDecimal(match[1]), 64)
+	if err != nil {
+		return 0
+	}
+	if match[2] != "" {
+		value *= 1000
+	}
+	return value
+}
+
+// normalizeDecimal deja el número con punto decimal y sin separador de miles. Con punto y coma
+// el último es el decimal; con uno solo, es de miles si se repite o lo siguen 3 dígitos.
+func normalizeDecimal(number string) string {
+	number = strings.TrimRight(number, ".,")
+
+	lastDot, lastComma := strings.LastIndex(number, "."), strings.LastIndex(number, ",")
+	decimal := ""
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastDot > lastComma {
+			decimal = "."
+		} else {
+			decimal = ","
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		sep := "."
+		if lastComma >= 0 {
+			sep = ","
+		}
+		last := strings.LastIndex(number, sep)
+		if strings.Count(number, sep) == 1 && len(number)-last-1 != 3 {
+			decimal = sep
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range number {
+		switch {
+		case string(r) == decimal:
+			b.WriteByte('.')
+		case r == '.' || r == ',':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// alreadyEscalated indica si la sesión ya tiene una escalación por la regla. Requiere el lock tomado.
+func (e *EscalationService) alreadyEscalated(sessionID string, rule EscalationRule) bool {
+	for _, esc := range e.escalations {
+		if esc.SessionID != sessionID || esc.RuleID != rule.ID {
+			continue
+		}
+		if esc.Status != EscalationResolved || !rule.Repeat {
+			return true
+		}
+	}
+	return false
+}
+
+// create guarda la escalación, avisa en segundo plano y devuelve una copia. Con dedupe no crea
+// nada si la sesión ya está escalada por la regla: la revisión y el alta van bajo el mismo
+// lock, así dos turnos simultáneos de la sesión no abren dos escalaciones.
+func (e *EscalationService) create(rule EscalationRule, session *models.Session, reason string, dedupe bool) *models.Escalation {
+	now := time.Now()
+	esc := &models.Escalation{
+		ID:        uuid.New().String(),
+		SessionID: session.SessionID,
+		Channel:   session.Channel,
+		RuleID:    rule.ID,
+		Reason:    reason,
+		Priority:  rule.Priority,
+		Status:    EscalationOpen,
+		CreatedAt: now,
+		Deadline:  now.Add(time.Duration(rule.SLAMinutes) * time.Minute),
+	}
+
+	e.mu.Lock()
+	if dedupe && e.alreadyEscalated(session.SessionID, rule) {
+		e.mu.Unlock()
+		return nil
+	}
+	e.escalations[esc.ID] = esc
+	e.saveToDisk()
+	created, snapshot := *esc, *esc
+	e.mu.Unlock()
+
+	log.Printf("🚨 Escalación creada: %s (%s) - SLA %d min", session.SessionID, rule.ID, rule.SLAMinutes)
+
+	go e.notify(rule, &snapshot)
+
+	return &created
+}
+
+// notify avisa por cada notifier de la regla y registra cuáles funcionaron
+func (e *EscalationService) notify(rule EscalationRule, esc *models.Escalation) {
+	var notified []string
+	for _, name := range rule.Notifiers {
+		e.mu.RLock()
+		notifier, ok := e.notifiers[name]
+		e.mu.RUnlock()
+
+		if !ok {
+			log.Printf("⚠️ Notifier desconocido en regla %s: %s", rule.ID, name)
+			continue
+		}
+		if err := notifier.Notify(esc); err != nil {
+			log.Printf("⚠️ Error notificando escalación %s por %s: %v", esc.ID, name, err)
+			continue
+		}
+		notified = append(notified, name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if stored, ok := e.escalations[esc.ID]; ok {
+		stored.Notified = notified
+		e.saveToDisk()
+	}
+}
+
+// Resolve marca la escalación como atendida y registra si se cumplió el SLA
+func (e *EscalationService) Resolve(id, specialist string) (*models.Escalation, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	esc, ok := e.escalations[id]
+	if !ok {
+		return nil, fmt.Errorf("escalación no encontrada: %s", id)
+	}
+	if esc.Status != EscalationResolved {
+		e.resolve(esc, specialist, time.Now())
+		e.saveToDisk()
+	}
+
+	resolved := *esc
+	return &resolved, nil
+}
+
+// ResolveSession atiende todas las escalaciones pendientes de una sesión (ej. al tomarla un especialista)
+func (e *EscalationService) ResolveSession(sessionID, specialist string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	count := 0
+	for _, esc := range e.escalations {
+		if esc.SessionID == sessionID && esc.Status != EscalationResolved {
+			e.resolve(esc, specialist, now)
+			count++
+		}
+	}
+	if count > 0 {
+		e.saveToDisk()
+	}
+
+	return count
+}
+
+func (e *EscalationService) resolve(esc *models.Escalation, specialist string, at time.Time) {
+	met := !at.After(esc.Deadline)
+	esc.Status = EscalationResolved
+	esc.ResolvedAt = &at
+	esc.ResolvedBy = specialist
+	esc.SLAMet = &met
+
+	log.Printf("✅ Escalación %s atendida por %s (SLA cumplido: %t)", esc.ID, specialist, met)
+}
+
+// markBreaches pasa a breached las escalaciones abiertas con el SLA vencido. Requiere el lock tomado.
+func (e *EscalationService) markBreaches() {
+	now := time.Now()
+	changed := false
+	for _, esc := range e.escalations {
+		if esc.Status == EscalationOpen && now.After(esc.Deadline) {
+			met := false
+			esc.Status = EscalationBreached
+			esc.SLAMet = &met
+			changed = true
+		}
+	}
+	if changed {
+		e.saveToDisk()
+	}
+}
+
+// GetEscalations lista las escalaciones más recientes primero, filtrando por estado y sesión.
+// Devuelve copias: notify sigue actualizando las guardadas.
+func (e *EscalationService) GetEscalations(status, sessionID string) []*models.Escalation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.markBreaches()
+
+	var result []*models.Escalation
+	for _, esc := range e.escalations {
+		if status != "" && esc.Status != status {
+			continue
+		}
+		if sessionID != "" && esc.SessionID != sessionID {
+			continue
+		}
+		copied := *esc
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return result
+}
+
+func (e *EscalationService) GetStats() *models.EscalationStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.markBreaches()
+
+	stats := &models.EscalationStats{
+		Total:  len(e.escalations),
+		ByRule: make(map[string]int),
+	}
+
+	var responseMinutes float64
+	for _, esc := range e.escalations {
+		stats.ByRule[esc.RuleID]++
+
+		switch esc.Status {
+		case EscalationOpen:
+			stats.Open++
+		case EscalationBreached:
+			stats.Breached++
+		case EscalationResolved:
+			stats.Resolved++
+			responseMinutes += esc.ResolvedAt.Sub(esc.CreatedAt).Minutes()
+		}
+
+		if esc.SLAMet != nil {
+			if *esc.SLAMet {
+				stats.SLAMet++
+			} else {
+				stats.SLAMissed++
+			}
+		}
+	}
+
+	if closed := stats.SLAMet + stats.SLAMissed; closed > 0 {
+		stats.SLAMetRate = float64(stats.SLAMet) / float64(closed)
+	}
+	if stats.Resolved > 0 {
+		stats.AvgResponseMinutes = responseMinutes / float64(stats.Resolved)
+	}
+
+	return stats
+}
+
+func (e *EscalationService) loadFromDisk() {
+	data, err := os.ReadFile(e.dataFile)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal(data, &e.escalations); err != nil {
+		log.Printf("Error al cargar escalaciones: %v", err)
+		return
+	}
+	log.Printf("%d escalaciones cargadas desde disco", len(e.escalations))
+}
+
+func (e *EscalationService) saveToDisk() {
+	if data, err := json.MarshalIndent(e.escalations, "", "  "); err == nil {
+		if err := os.WriteFile(e.dataFile, data, 0644); err != nil {
+			log.Printf("Error al guardar escalaciones: %v", err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		text string
+		want float64
+	}{
+		{"$20 mil", 20000},
+		{"15,000 dólares", 15000},
+		{"15.000 soles", 15000},
+		{"15.5k", 15500},
+		{"USD 12.000,50", 12000.50},
+		{"USD 12,000.50", 12000.50},
+		{"1.250.000", 1250000},
+		{"1,250,000", 1250000},
+		{"45,5 mil", 45500},
+		{"3 lucas", 3000},
+		{"80000.", 80000},
+		{"sin monto", 0},
+	}
+	for _, c := range cases {
+		if got := parseAmount(c.text); got != c.want {
+			t.Errorf("parseAmount(%q) = %v, se esperaba %v", c.text, got, c.want)
+		}
+	}
+}
+
+func TestNormalizeDecimal(t *testing.T) {
+	cases := map[string]string{
+		"12":        "12",
+		"12.5":      "12.5",
+		"12,5":      "12.5",
+		"12.000":    "12000",
+		"12,000":    "12000",
+		"1.234,56":  "1234.56",
+		"1,234.56":  "1234.56",
+		"1.234.567": "1234567",
+		"12.":       "12",
+	}
+	for in, want := range cases {
+		if got := normalizeDecimal(in); got != want {
+			t.Errorf("normalizeDecimal(%q) = %q, se esperaba %q", in, got, want)
+		}
+	}
+}
+
+func TestEvaluateConcurrentTurnsEscalateOnce(t *testing.T) {
+	e := &EscalationService{
+		rules:       []EscalationRule{{ID: "hot-lead", Name: "Lead caliente", Trigger: TriggerCategory, Enabled: true, Category: "hot", SLAMinutes: 60}},
+		escalations: make(map[string]*models.Escalation),
+		notifiers:   make(map[string]Notifier),
+		dataFile:    filepath.Join(t.TempDir(), "escalations.json"),
+	}
+	session := &models.Session{SessionID: "s-1", Channel: "web"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.Evaluate(EscalationInput{Session: session, Category: "hot"})
+		}()
+	}
+	wg.Wait()
+
+	escalations := e.GetEscalations("", "s-1")
+	if len(escalations) != 1 {
+		t.Fatalf("se esperaba una escalación, hay %d", len(escalations))
+	}
+	escalations[0].Status = EscalationResolved
+	if e.GetEscalations(EscalationOpen, "s-1")[0].Status != EscalationOpen {
+		t.Error("GetEscalations debe devolver copias")
+	}
+}
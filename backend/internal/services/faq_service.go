@@ -2,7 +2,9 @@ package services
 
 import (
 	"bob-hackathon/internal/models"
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,8 +13,9 @@ import (
 )
 
 type FAQService struct {
-	faqs []models.FAQ
-	mu   sync.RWMutex
+	faqs    []models.FAQ
+	version string // huella del contenido: cambia si cambia alguna FAQ
+	mu      sync.RWMutex
 }
 
 var faqServiceInstance *FAQService
@@ -23,44 +26,96 @@ func GetFAQService() *FAQService {
 		faqServiceInstance = &FAQService{
 			faqs: []models.FAQ{},
 		}
-		faqServiceInstance.loadFAQs()
+		if err := faqServiceInstance.Reload(); err != nil {
+			log.Printf("Error al cargar FAQs: %v", err)
+		}
 	})
 	return faqServiceInstance
 }
 
-func (f *FAQService) loadFAQs() {
+// Reload vuelve a leer data/faqs.csv; si el contenido cambió cambia Version, lo que
+// invalida las respuestas cacheadas
+func (f *FAQService) Reload() error {
+	faqs, err := loadFAQs()
+	if err != nil {
+		return err
+	}
+
+	version := FAQFingerprint(faqs)
+
+	f.mu.Lock()
+	changed := f.version != version
+	f.faqs = faqs
+	f.version = version
+	f.mu.Unlock()
+
+	if changed {
+		log.Printf("%d FAQs cargadas (versión %s)", len(faqs), version)
+	}
+	return nil
+}
+
+// Version devuelve la huella del contenido actual de las FAQs
+func (f *FAQService) Version() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.version
+}
+
+// FAQFingerprint resume preguntas y respuestas en una huella corta, para detectar cambios
+func FAQFingerprint(faqs []models.FAQ) string {
+	h := sha256.New()
+	for _, faq := range faqs {
+		h.Write([]byte(faq.Pregunta))
+		h.Write([]byte{0})
+		h.Write([]byte(faq.Respuesta))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func loadFAQs() ([]models.FAQ, error) {
 	file, err := os.Open(filepath.Join("data", "faqs.csv"))
 	if err != nil {
-		log.Printf("Error al abrir FAQs: %v", err)
-		return
+		return nil, err
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
 	records, err := reader.ReadAll()
 	if err != nil {
-		log.Printf("Error al leer FAQs: %v", err)
-		return
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []models.FAQ{}, nil
 	}
 
-	// Saltar header
-	for i, record := range records {
-		if i == 0 {
+	// Columnas por nombre del header: el CSV trae Id antes de Categoría
+	columns := map[string]int{"categoria": 0, "empresa": 1, "pregunta": 2, "respuesta": 3}
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer("í", "i").Replace(name)
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+
+	faqs := []models.FAQ{}
+	for _, record := range records[1:] {
+		if len(record) <= columns["respuesta"] || len(record) <= columns["pregunta"] {
 			continue
 		}
 
-		if len(record) >= 4 {
-			faq := models.FAQ{
-				Categoria: record[0],
-				Empresa:   record[1],
-				Pregunta:  record[2],
-				Respuesta: record[3],
-			}
-			f.faqs = append(f.faqs, faq)
+		faq := models.FAQ{
+			Categoria: record[columns["categoria"]],
+			Empresa:   record[columns["empresa"]],
+			Pregunta:  record[columns["pregunta"]],
+			Respuesta: record[columns["respuesta"]],
 		}
+		faqs = append(faqs, faq)
 	}
-
-	log.Printf("%d FAQs cargadas", len(f.faqs))
+	return faqs, nil
 }
 
 func (f *FAQService) SearchFAQs(query, categoria, empresa string) []models.FAQ {
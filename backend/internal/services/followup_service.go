@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de un seguimiento
+const (
+	FollowUpPending   = "pending"
+	FollowUpSent      = "sent"
+	FollowUpCancelled = "cancelled"
+	FollowUpFailed    = "failed"
+)
+
+// Acciones de un seguimiento
+const (
+	FollowUpActionMessage = "message"
+	FollowUpActionNotify  = "notify"
+)
+
+const (
+	followUpMaxAttempts = 3
+	followUpRetryDelay  = 15 * time.Minute
+)
+
+// FollowUpPlan es el seguimiento que corresponde a cada categoría del scoring
+type FollowUpPlan struct {
+	Delay  time.Duration
+	Action string
+}
+
+// Plan de seguimiento de la rúbrica: hot 4h (especialista), warm 24h, cold 1 mes
+var followUpPlans = map[string]FollowUpPlan{
+	"hot":  {Delay: 4 * time.Hour, Action: FollowUpActionNotify},
+	"warm": {Delay: 24 * time.Hour, Action: FollowUpActionMessage},
+	"cold": {Delay: 30 * 24 * time.Hour, Action: FollowUpActionMessage},
+}
+
+var followUpDelayRegex = regexp.MustCompile(`(?i)(\d+)\s*(h\b|hrs?\b|horas?|d\b|d[ií]as?|semanas?|mes(es)?)`)
+
+type FollowUpService struct {
+	followUps      map[string]*models.FollowUp
+	inFlight       map[string]bool // seguimientos que se están enviando
+	sessionService *SessionService
+	promptService  *PromptService
+	handoffService *HandoffService
+	escalations    *EscalationService
+	interval       time.Duration
+	dataFile       string
+	startOnce      sync.Once
+	mu             sync.RWMutex
+}
+
+var followUpServiceInstance *FollowUpService
+var followUpServiceOnce sync.Once
+
+func GetFollowUpService() *FollowUpService {
+	followUpServiceOnce.Do(func() {
+		followUpServiceInstance = &FollowUpService{
+			followUps:      make(map[string]*models.FollowUp),
+			inFlight:       make(map[string]bool),
+			sessionService: GetSessionService(),
+			promptService:  GetPromptService(),
+			handoffService: GetHandoffService(),
+			escalations:    GetEscalationService(),
+			interval:       time.Duration(config.AppConfig.FollowUpCheckSeconds) * time.Second,
+			dataFile:       filepath.Join("data", "followups.json"),
+		}
+		followUpServiceInstance.loadFromDisk()
+	})
+	return followUpServiceInstance
+}
+
+// Start lanza el chequeo periódico de seguimientos vencidos
+func (f *FollowUpService) Start() {
+	if f.interval <= 0 {
+		log.Println("Scheduler de seguimientos desactivado (FOLLOWUP_CHECK_SECONDS <= 0)")
+		return
+	}
+
+	f.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(f.interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				f.RunDue()
+			}
+		}()
+		log.Printf("Scheduler de seguimientos activo (cada %s)", f.interval)
+	})
+}
+
+// Schedule programa el seguimiento de la sesión según el resultado del scoring,
+// reemplazando el que estuviera pendiente
+func (f *FollowUpService) Schedule(session *models.Session, scoring *models.ScoringData) *models.FollowUp {
+	plan, ok := followUpPlans[scoring.Category]
+	if !ok {
+		return nil
+	}
+	if delay, ok := parseFollowUpDelay(scoring.TipoSeguimiento); ok {
+		plan.Delay = delay
+	}
+
+	f.CancelForSession(session.SessionID, "rescheduled")
+
+	now := time.Now()
+	followUp := &models.FollowUp{
+		ID:              uuid.New().String(),
+		SessionID:       session.SessionID,
+		Channel:         session.Channel,
+		Category:        scoring.Category,
+		Action:          plan.Action,
+		Status:          FollowUpPending,
+		DueAt:           now.Add(plan.Delay),
+		TiempoContacto:  scoring.TiempoContacto,
+		TipoSeguimiento: scoring.TipoSeguimiento,
+		CreatedAt:       now,
+	}
+
+	f.mu.Lock()
+	f.followUps[followUp.ID] = followUp
+	f.saveToDisk()
+	f.mu.Unlock()
+
+	log.Printf("⏰ Seguimiento %s programado para %s (%s, %s)",
+		followUp.Action, session.SessionID, followUp.DueAt.Format(time.RFC3339), scoring.Category)
+	return followUp
+}
+
+// parseFollowUpDelay lee un plazo explícito ("seguimiento 4h", "2 días", "1 mes") del texto del scoring
+func parseFollowUpDelay(text string) (time.Duration, bool) {
+	match := followUpDelayRegex.FindStringSubmatch(text)
+	if match == nil {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	unit := strings.ToLower(match[2])
+	switch {
+	case strings.HasPrefix(unit, "h"):
+		return time.Duration(n) * time.Hour, true
+	case strings.HasPrefix(unit, "d"):
+		return time.Duration(n) * 24 * time.Hour, true
+	case strings.HasPrefix(unit, "semana"):
+		return time.Duration(n) * 7 * 24 * time.Hour, true
+	case strings.HasPrefix(unit, "mes"):
+		return time.Duration(n) * 30 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// CancelForSession cancela los seguimientos pendientes de la sesión (ej. el prospecto respondió)
+func (f *FollowUpService) CancelForSession(sessionID, reason string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	count := 0
+	for _, followUp := range f.followUps {
+		if followUp.SessionID == sessionID && followUp.Status == FollowUpPending {
+			f.cancel(followUp, reason)
+			count++
+		}
+	}
+	if count > 0 {
+		f.saveToDisk()
+		log.Printf("⏰ %d seguimiento(s) cancelados en %s (%s)", count, sessionID, reason)
+	}
+
+	return count
+}
+
+// Cancel cancela un seguimiento puntual
+func (f *FollowUpService) Cancel(id, reason string) (*models.FollowUp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	followUp, ok := f.followUps[id]
+	if !ok {
+		return nil, fmt.Errorf("seguimiento no encontrado: %s", id)
+	}
+	if followUp.Status != FollowUpPending {
+		return nil, fmt.Errorf("el seguimiento ya está %s", followUp.Status)
+	}
+
+	f.cancel(followUp, reason)
+	f.saveToDisk()
+
+	return followUp, nil
+}
+
+func (f *FollowUpService) cancel(followUp *models.FollowUp, reason string) {
+	now := time.Now()
+	followUp.Status = FollowUpCancelled
+	followUp.CancelReason = reason
+	followUp.CancelledAt = &now
+}
+
+// RunDue ejecuta los seguimientos vencidos y devuelve cuántos se procesaron
+func (f *FollowUpService) RunDue() int {
+	now := time.Now()
+
+	f.mu.RLock()
+	var due []models.FollowUp
+	for _, followUp := range f.followUps {
+		if followUp.Overdue(now) {
+			due = append(due, *followUp)
+		}
+	}
+	f.mu.RUnlock()
+
+	processed := 0
+	for i := range due {
+		if f.fire(&due[i]) {
+			processed++
+		}
+	}
+
+	return processed
+}
+
+// claim vuelve a verificar bajo el lock que el seguimiento siga pendiente (pudo cancelarse
+// desde que se tomó la copia) y lo marca en envío para que otra corrida no lo repita
+func (f *FollowUpService) claim(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	followUp, ok := f.followUps[id]
+	if !ok || followUp.Status != FollowUpPending || f.inFlight[id] {
+		return false
+	}
+	f.inFlight[id] = true
+	return true
+}
+
+// fire ejecuta un seguimiento sobre una copia y guarda el resultado. Devuelve false si ya
+// no estaba pendiente.
+func (f *FollowUpService) fire(followUp *models.FollowUp) bool {
+	if !f.claim(followUp.ID) {
+		return false
+	}
+	defer f.release(followUp.ID)
+
+	session := f.sessionService.Snapshot(followUp.SessionID)
+
+	var message string
+	var err error
+	switch {
+	case session == nil:
+		f.finish(followUp.ID, FollowUpCancelled, "session_not_found", "", nil)
+		return true
+	case session.IsHumanMode():
+		// Con un especialista a cargo el bot no escribe por su cuenta
+		f.finish(followUp.ID, FollowUpCancelled, "human_mode", "", nil)
+		return true
+	case session.Stage == StageClosed:
+		f.finish(followUp.ID, FollowUpCancelled, "closed", "", nil)
+		return true
+	case followUp.Action == FollowUpActionNotify:
+		f.escalations.Escalate(EscalationRule{
+			ID:         "followup",
+			Name:       "Seguimiento " + followUp.Category,
+			SLAMinutes: 60,
+			Priority:   "high",
+			Notifiers:  []string{"log", "webhook"},
+		}, session, fmt.Sprintf("Seguimiento %s pendiente: %s", followUp.Category, followUp.TipoSeguimiento))
+	default:
+		message, err = f.sendMessage(session, followUp)
+	}
+
+	if err != nil {
+		f.finish(followUp.ID, FollowUpPending, "", "", err)
+		return true
+	}
+	f.finish(followUp.ID, FollowUpSent, "", message, nil)
+	return true
+}
+
+func (f *FollowUpService) release(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.inFlight, id)
+}
+
+// sendMessage envía el mensaje de reenganche por el canal de origen
+func (f *FollowUpService) sendMessage(session *models.Session, followUp *models.FollowUp) (string, error) {
+	text, ref, err := f.promptService.Render("followup_message", session.Channel, map[string]any{
+		"Category": followUp.Category,
+		"Channel":  session.Channel,
+		"Stage":    session.Stage,
+		"Slots":    session.Slots,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	trace := &models.ReplyTrace{Agent: "followup"}
+	trace.AddPrompt(&ref)
+
+	replies, err := f.handoffService.Deliver(session, "assistant", text, trace)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(replies, "\n\n"), nil
+}
+
+// finish guarda el resultado de un seguimiento. Los errores se reintentan hasta followUpMaxAttempts.
+func (f *FollowUpService) finish(id, status, reason, message string, fireErr error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	followUp, ok := f.followUps[id]
+	if !ok || followUp.Status != FollowUpPending {
+		return
+	}
+
+	now := time.Now()
+	followUp.Attempts++
+
+	switch {
+	case fireErr != nil:
+		followUp.LastError = fireErr.Error()
+		if followUp.Attempts >= followUpMaxAttempts {
+			followUp.Status = FollowUpFailed
+		} else {
+			followUp.DueAt = now.Add(followUpRetryDelay)
+		}
+		log.Printf("⚠️ Error en seguimiento %s (%d/%d): %v", id, followUp.Attempts, followUpMaxAttempts, fireErr)
+	case status == FollowUpCancelled:
+		f.cancel(followUp, reason)
+	default:
+		followUp.Status = status
+		followUp.Message = message
+		followUp.FiredAt = &now
+		log.Printf("⏰ Seguimiento %s ejecutado en %s", followUp.Action, followUp.SessionID)
+	}
+
+	f.saveToDisk()
+}
+
+// GetFollowUps lista seguimientos por estado ("overdue" = pendientes vencidos) y sesión, por fecha
+func (f *FollowUpService) GetFollowUps(status, sessionID string) []*models.FollowUp {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	now := time.Now()
+	var result []*models.FollowUp
+	for _, followUp := range f.followUps {
+		if status == "overdue" {
+			if !followUp.Overdue(now) {
+				continue
+			}
+		} else if status != "" && followUp.Status != status {
+			continue
+		}
+		if sessionID != "" && followUp.SessionID != sessionID {
+			continue
+		}
+		result = append(result, followUp)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DueAt.Before(result[j].DueAt)
+	})
+
+	return result
+}
+
+func (f *FollowUpService) loadFromDisk() {
+	data, err := os.ReadFile(f.dataFile)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal(data, &f.followUps); err != nil {
+		log.Printf("Error al cargar seguimientos: %v", err)
+		return
+	}
+	log.Printf("%d seguimientos cargados desde disco", len(f.followUps))
+}
+
+func (f *FollowUpService) saveToDisk() {
+	if data, err := json.MarshalIndent(f.followUps, "", "  "); err == nil {
+		if err := os.WriteFile(f.dataFile, data, 0644); err != nil {
+			log.Printf("Error al guardar seguimientos: %v", err)
+		}
+	}
+}
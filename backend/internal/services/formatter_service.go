@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Estilos de formato soportados por los canales
+const (
+	FormatMarkdown = "markdown"
+	FormatWhatsApp = "whatsapp"
+	FormatPlain    = "plain"
+)
+
+// ChannelFormat define cómo se post-procesa una respuesta para un canal
+type ChannelFormat struct {
+	Style         string `json:"style"`
+	MaxMessageLen int    `json:"maxMessageLen"` // 0 = sin límite
+	MaxMessages   int    `json:"maxMessages"`   // 0 = sin límite
+}
+
+type FormatterService struct {
+	channels map[string]ChannelFormat
+	fallback ChannelFormat
+	mu       sync.RWMutex
+}
+
+var formatterServiceInstance *FormatterService
+var formatterServiceOnce sync.Once
+
+var (
+	mdCodeBlockRegex  = regexp.MustCompile("(?s)```[a-zA-Z0-9]*\\n?(.*?)```")
+	mdInlineCodeRegex = regexp.MustCompile("`([^`\\n]+)`")
+	mdImageRegex      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	mdLinkRegex       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdBoldRegex       = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdItalicRegex     = regexp.MustCompile(`(^|[^\w*])\*(\S(?:[^*\n]*?\S)?)\*([^\w*]|$)`)
+	mdStrikeRegex     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdHeadingRegex    = regexp.MustCompile(`(?m)^[ \t]{0,3}#{1,6}[ \t]+(.+?)[ \t]*#*[ \t]*$`)
+	mdBulletRegex     = regexp.MustCompile(`(?m)^([ \t]*)[-*+][ \t]+`)
+	mdQuoteRegex      = regexp.MustCompile(`(?m)^[ \t]*>[ \t]?`)
+	mdRuleRegex       = regexp.MustCompile(`(?m)^[ \t]*([-*_][ \t]*){3,}$`)
+	mdTableSepRegex   = regexp.MustCompile(`(?m)^[ \t]*\|?[ \t]*:?-{2,}:?[ \t]*(\|[ \t]*:?-{2,}:?[ \t]*)*\|?[ \t]*$\n?`)
+	mdTableRowRegex   = regexp.MustCompile(`(?m)^[ \t]*\|(.+)\|[ \t]*$`)
+	htmlTagRegex      = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	blankLinesRegex   = regexp.MustCompile(`\n{3,}`)
+)
+
+// Marcador temporal para no confundir negritas con itálicas durante la conversión
+const boldMarker = "\x00"
+
+func GetFormatterService() *FormatterService {
+	formatterServiceOnce.Do(func() {
+		formatterServiceInstance = &FormatterService{
+			channels: map[string]ChannelFormat{
+				"web": {Style: FormatMarkdown, MaxMessageLen: 4000, MaxMessages: 1},
+				"whatsapp": {
+					Style:         FormatWhatsApp,
+					MaxMessageLen: config.AppConfig.WhatsAppMaxMessageLen,
+					MaxMessages:   config.AppConfig.WhatsAppMaxMessages,
+				},
+				"sms": {Style: FormatPlain, MaxMessageLen: 160, MaxMessages: 3},
+			},
+			fallback: ChannelFormat{Style: FormatPlain, MaxMessageLen: 1000, MaxMessages: 3},
+		}
+	})
+	return formatterServiceInstance
+}
+
+// GetChannelFormat devuelve la configuración de formato de un canal (o la genérica)
+func (f *FormatterService) GetChannelFormat(channel string) ChannelFormat {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if cf, ok := f.channels[strings.ToLower(channel)]; ok {
+		return cf
+	}
+	return f.fallback
+}
+
+// SetChannelFormat registra o reemplaza el formato de un canal
+func (f *FormatterService) SetChannelFormat(channel string, cf ChannelFormat) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.channels[strings.ToLower(channel)] = cf
+}
+
+// FormatReply convierte la respuesta al estilo del canal y la divide en mensajes
+// que respetan el largo máximo del canal
+func (f *FormatterService) FormatReply(channel, reply string) []string {
+	cf := f.GetChannelFormat(channel)
+
+	text := strings.ReplaceAll(reply, "\r\n", "\n")
+	switch cf.Style {
+	case FormatWhatsApp:
+		text = toWhatsApp(text)
+	case FormatPlain:
+		text = toPlainText(text)
+	}
+	text = strings.TrimSpace(blankLinesRegex.ReplaceAllString(text, "\n\n"))
+
+	if text == "" {
+		return []string{}
+	}
+
+	parts := splitMessage(text, cf.MaxMessageLen)
+	if cf.MaxMessages > 0 && len(parts) > cf.MaxMessages {
+		parts = parts[:cf.MaxMessages]
+		parts[len(parts)-1] = truncateRunes(parts[len(parts)-1], cf.MaxMessageLen-1) + "…"
+	}
+
+	return parts
+}
+
+// stripCommon elimina construcciones que ningún canal de chat renderiza (HTML, tablas, citas, separadores)
+func stripCommon(text string) string {
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	text = mdTableSepRegex.ReplaceAllString(text, "")
+	text = mdTableRowRegex.ReplaceAllStringFunc(text, func(row string) string {
+		cells := strings.Split(strings.Trim(strings.TrimSpace(row), "|"), "|")
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+		return strings.Join(cells, " - ")
+	})
+	text = mdQuoteRegex.ReplaceAllString(text, "")
+	text = mdRuleRegex.ReplaceAllString(text, "")
+	text = mdImageRegex.ReplaceAllString(text, "$2")
+	text = mdLinkRegex.ReplaceAllString(text, "$1 ($2)")
+	return text
+}
+
+// toWhatsApp convierte markdown al formato de WhatsApp (*negrita*, _itálica_, ~tachado~)
+func toWhatsApp(text string) string {
+	// Los bloques de código se mantienen: WhatsApp soporta ```monoespaciado```
+	text = stripCommon(text)
+	text = mdInlineCodeRegex.ReplaceAllString(text, "$1")
+	text = mdHeadingRegex.ReplaceAllString(text, boldMarker+"$1"+boldMarker)
+	text = mdBoldRegex.ReplaceAllString(text, boldMarker+"$2"+boldMarker)
+	text = mdItalicRegex.ReplaceAllString(text, "${1}_${2}_${3}")
+	text = mdStrikeRegex.ReplaceAllString(text, "~$1~")
+	text = mdBulletRegex.ReplaceAllString(text, "$1• ")
+	return strings.ReplaceAll(text, boldMarker, "*")
+}
+
+// toPlainText elimina todo el formato markdown
+func toPlainText(text string) string {
+	text = stripCommon(text)
+	text = mdCodeBlockRegex.ReplaceAllString(text, "$1")
+	text = mdInlineCodeRegex.ReplaceAllString(text, "$1")
+	text = mdHeadingRegex.ReplaceAllString(text, "$1")
+	text = mdBoldRegex.ReplaceAllString(text, "$2")
+	text = mdItalicRegex.ReplaceAllString(text, "${1}${2}${3}")
+	text = mdStrikeRegex.ReplaceAllString(text, "$1")
+	text = mdBulletRegex.ReplaceAllString(text, "$1- ")
+	return text
+}
+
+// splitMessage divide el texto en partes de como máximo maxLen runas,
+// cortando primero por párrafos, luego por oraciones y por último por palabras
+func splitMessage(text string, maxLen int) []string {
+	if maxLen <= 0 || runeLen(text) <= maxLen {
+		return []string{text}
+	}
+
+	var parts []string
+	current := ""
+
+	flush := func() {
+		if s := strings.TrimSpace(current); s != "" {
+			parts = append(parts, s)
+		}
+		current = ""
+	}
+
+	appendPiece := func(piece, sep string) bool {
+		candidate := piece
+		if current != "" {
+			candidate = current + sep + piece
+		}
+		if runeLen(strings.TrimSpace(candidate)) <= maxLen {
+			current = candidate
+			return true
+		}
+		return false
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if appendPiece(paragraph, "\n\n") {
+			continue
+		}
+		flush()
+		if appendPiece(paragraph, "") {
+			continue
+		}
+
+		for _, sentence := range splitSentences(paragraph) {
+			if appendPiece(sentence, "") {
+				continue
+			}
+			flush()
+			if appendPiece(sentence, "") {
+				continue
+			}
+
+			for _, word := range strings.Fields(sentence) {
+				if appendPiece(word, " ") {
+					continue
+				}
+				flush()
+				// Palabra más larga que el límite: se corta a la fuerza
+				for runeLen(word) > maxLen {
+					parts = append(parts, truncateRunes(word, maxLen))
+					word = string([]rune(word)[maxLen:])
+				}
+				current = word
+			}
+		}
+	}
+	flush()
+
+	return parts
+}
+
+// splitSentences separa un párrafo en oraciones conservando la puntuación
+// y el espacio o salto de línea que las sigue
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	runes := []rune(paragraph)
+	start := 0
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		isEnd := r == '.' || r == '!' || r == '?' || r == '…' || r == '\n'
+		if !isEnd {
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] != ' ' && runes[i+1] != '\n' {
+			continue
+		}
+		for i+1 < len(runes) && (runes[i+1] == ' ' || runes[i+1] == '\n') {
+			i++
+		}
+		sentences = append(sentences, string(runes[start:i+1]))
+		start = i + 1
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+
+	return sentences
+}
+
+func runeLen(s string) int {
+	return len([]rune(s))
+}
+
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if max <= 0 || len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
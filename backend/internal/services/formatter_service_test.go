@@ -0,0 +1,111 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToWhatsApp(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"negrita", "Es **importante** saberlo", "Es *importante* saberlo"},
+		{"negrita con guiones bajos", "Es __importante__", "Es *importante*"},
+		{"italica", "una *camioneta* usada", "una _camioneta_ usada"},
+		{"negrita e italica", "**Precio:** desde *USD 5,000*", "*Precio:* desde _USD 5,000_"},
+		{"tachado", "~~USD 9,000~~ USD 7,500", "~USD 9,000~ USD 7,500"},
+		{"titulo", "## Requisitos", "*Requisitos*"},
+		{"viñetas", "- DNI\n* Licencia\n+ Garantía", "• DNI\n• Licencia\n• Garantía"},
+		{"enlace", "Mira [el catálogo](https://somosbob.com/subastas)", "Mira el catálogo (https://somosbob.com/subastas)"},
+		{"codigo en linea", "usa el código `BOB10`", "usa el código BOB10"},
+		{"bloque de codigo se mantiene", "```\nlote 12\n```", "```\nlote 12\n```"},
+		{"html", "<b>Hola</b><br>", "Hola"},
+		{"cita", "> Importante: paga antes", "Importante: paga antes"},
+		{"tabla", "| Lote | Precio |\n|---|---|\n| 12 | 5000 |", "Lote - Precio\n12 - 5000"},
+		{"multiplicacion no es italica", "2 * 3 = 6", "2 * 3 = 6"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := toWhatsApp(c.in); got != c.want {
+				t.Errorf("toWhatsApp(%q) = %q, se esperaba %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestToPlainText(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"negrita e italica", "**Precio:** desde *USD 5,000*", "Precio: desde USD 5,000"},
+		{"titulo", "# Subastas", "Subastas"},
+		{"viñetas", "* uno\n* dos", "- uno\n- dos"},
+		{"bloque de codigo", "```go\nfmt.Println()\n```", "fmt.Println()\n"},
+		{"tachado", "~~antes~~ ahora", "antes ahora"},
+		{"imagen", "![foto](https://x.pe/a.jpg)", "https://x.pe/a.jpg"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := toPlainText(c.in); got != c.want {
+				t.Errorf("toPlainText(%q) = %q, se esperaba %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	cases := []struct {
+		name   string
+		text   string
+		maxLen int
+		want   []string
+	}{
+		{"sin limite", "hola mundo", 0, []string{"hola mundo"}},
+		{"entra entero", "hola mundo", 20, []string{"hola mundo"}},
+		{"por parrafos", "Primer párrafo.\n\nSegundo párrafo.", 20, []string{"Primer párrafo.", "Segundo párrafo."}},
+		{"junta parrafos que entran", "Uno.\n\nDos.\n\nTres aquí.", 12, []string{"Uno.\n\nDos.", "Tres aquí."}},
+		{"por oraciones", "Hola. ¿Cómo estás? Todo bien.", 20, []string{"Hola. ¿Cómo estás?", "Todo bien."}},
+		{"por palabras", "una oracion sin puntos bastante larga", 15, []string{"una oracion sin", "puntos bastante", "larga"}},
+		{"palabra mas larga que el limite", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"cuenta runas, no bytes", "ñañañañaña ñañañañaña", 10, []string{"ñañañañaña", "ñañañañaña"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := splitMessage(c.text, c.maxLen)
+			if strings.Join(got, "|") != strings.Join(c.want, "|") {
+				t.Errorf("splitMessage(%q, %d) = %q, se esperaba %q", c.text, c.maxLen, got, c.want)
+			}
+			for _, part := range got {
+				if c.maxLen > 0 && runeLen(part) > c.maxLen {
+					t.Errorf("parte de %d runas supera el límite %d: %q", runeLen(part), c.maxLen, part)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatReplyLimitsMessages(t *testing.T) {
+	f := &FormatterService{
+		channels: map[string]ChannelFormat{
+			"whatsapp": {Style: FormatWhatsApp, MaxMessageLen: 12, MaxMessages: 2},
+		},
+		fallback: ChannelFormat{Style: FormatPlain},
+	}
+
+	parts := f.FormatReply("WhatsApp", "**Uno.**\n\nDos.\n\n\n\nTres.\n\nCuatro.")
+	if len(parts) != 2 || parts[0] != "*Uno.*\n\nDos." || !strings.HasSuffix(parts[1], "…") {
+		t.Errorf("partes inesperadas: %q", parts)
+	}
+	for _, part := range parts {
+		if runeLen(part) > 12 {
+			t.Errorf("parte más larga que el límite: %q", part)
+		}
+	}
+
+	if parts := f.FormatReply("sms", "  **hola**  "); len(parts) != 1 || parts[0] != "hola" {
+		t.Errorf("canal desconocido usa el formato genérico: %q", parts)
+	}
+	if parts := f.FormatReply("web", "<br>"); len(parts) != 0 {
+		t.Errorf("una respuesta vacía no genera mensajes: %q", parts)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Etapas del embudo de ventas, en orden
+const (
+	StageGreeting        = "greeting"
+	StageDiscovery       = "discovery"
+	StageVehicleInterest = "vehicle_interest"
+	StageQualification   = "qualification"
+	StageHandoff         = "handoff"
+	StageClosed          = "closed"
+)
+
+// FunnelStages lista las etapas en el orden del embudo
+var FunnelStages = []string{
+	StageGreeting,
+	StageDiscovery,
+	StageVehicleInterest,
+	StageQualification,
+	StageHandoff,
+	StageClosed,
+}
+
+// Slots que se extraen de los mensajes del usuario
+const (
+	SlotVehicle    = "vehiculo"
+	SlotBrand      = "marca"
+	SlotBudget     = "presupuesto"
+	SlotUrgency    = "urgencia"
+	SlotBusiness   = "negocio"
+	SlotSpecialist = "especialista"
+	SlotRegion     = "region"
+)
+
+// FunnelEvent es lo que se observó en un turno y puede mover la etapa
+type FunnelEvent struct {
+	Intent   string
+	Slots    map[string]string
+	Category string
+}
+
+type FunnelService struct {
+	brands []brandPattern
+}
+
+type brandPattern struct {
+	name  string
+	regex *regexp.Regexp
+}
+
+var funnelServiceInstance *FunnelService
+var funnelServiceOnce sync.Once
+
+// "van" queda fuera de los tipos de vehículo: también es el verbo ("¿cuándo van a publicar?").
+// "humano" o "persona" solo piden especialista en una frase como "hablar con un humano".
+var (
+	vehicleTypeRegex = regexp.MustCompile(`(?i)\b(camionetas?|autos?|carros?|veh[ií]culos?|motos?|cami[oó]n|camiones|minivan|furgonetas?|furg[oó]n|suv|pick ?up|sed[aá]n|hatchback|bus|maquinaria|excavadora|cargador)\b`)
+	budgetRegex      = regexp.MustCompile(`(?i)((us\$|\$|s/\.?|usd|soles|d[oó]lares)\s*\d[\d.,]*(\s*mil)?|\d[\d.,]*\s*(mil|k)?\s*(soles|d[oó]lares|usd|lucas))|presupuesto\s+(de\s+)?\d[\d.,]*(\s*(mil|k))?(\s*(soles|d[oó]lares|usd))?`)
+	urgencyRegex     = regexp.MustCompile(`(?i)\b(urgente|urge|hoy|mañana|esta semana|este mes|inmediat[oa]|lo antes posible|cuanto antes|pronto|ya mismo)\b`)
+	businessRegex    = regexp.MustCompile(`(?i)\b(empresa|negocio|flota|mi compañ[ií]a|ruc|pyme|emprendimiento)\b`)
+	specialistRegex  = regexp.MustCompile(`(?i)\b(asesor|especialista|ll[aá]m[ae]me|ll[aá]menme|(?:hablar|comunicarme|atenderme) con (?:un |una )?(?:humano|persona|alguien)|ejecutivo|vendedor)\b`)
+	// Departamentos y ciudades principales de Perú (se buscan en el texto sin tildes), para
+	// asignar el lead a un especialista de la zona
+	regionRegex    = regexp.MustCompile(`\b(lima|callao|arequipa|trujillo|la libertad|chiclayo|lambayeque|piura|tumbes|cusco|cuzco|huancayo|junin|iquitos|loreto|chimbote|huaraz|ancash|tacna|moquegua|ica|puno|juliaca|cajamarca|ayacucho|huanuco|huancavelica|pucallpa|ucayali|tarapoto|san martin|abancay|apurimac|chachapoyas|amazonas|puerto maldonado|madre de dios|cerro de pasco|pasco)\b`)
+	accentReplacer = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u")
+)
+
+func GetFunnelService() *FunnelService {
+	funnelServiceOnce.Do(func() {
+		brands := []string{
+			"toyota", "hyundai", "kia", "chevrolet", "nissan", "mitsubishi", "suzuki", "mazda",
+			"honda", "ford", "volkswagen", "chery", "changan", "dfsk", "foton", "jac", "geely",
+			"peugeot", "renault", "subaru", "bmw", "mercedes", "volvo", "jetour", "great wall",
+			"caterpillar", "jeep", "audi", "mg", "baic",
+		}
+
+		funnelServiceInstance = &FunnelService{}
+		for _, brand := range brands {
+			funnelServiceInstance.brands = append(funnelServiceInstance.brands, brandPattern{
+				name:  brand,
+				regex: regexp.MustCompile(`\b` + regexp.QuoteMeta(brand) + `\b`),
+			})
+		}
+	})
+	return funnelServiceInstance
+}
+
+// StageIndex devuelve la posición de la etapa en el embudo (greeting si es desconocida)
+func StageIndex(stage string) int {
+	for i, s := range FunnelStages {
+		if s == stage {
+			return i
+		}
+	}
+	return 0
+}
+
+// ExtractSlots detecta datos útiles para el embudo en un mensaje del usuario
+func (f *FunnelService) ExtractSlots(message string) map[string]string {
+	slots := make(map[string]string)
+	lower := strings.ToLower(message)
+
+	for _, brand := range f.brands {
+		if brand.regex.MatchString(lower) {
+			slots[SlotBrand] = brand.name
+			break
+		}
+	}
+	if match := vehicleTypeRegex.FindString(message); match != "" {
+		slots[SlotVehicle] = strings.ToLower(match)
+	}
+	if match := budgetRegex.FindString(message); match != "" {
+		slots[SlotBudget] = strings.TrimSpace(match)
+	}
+	if match := urgencyRegex.FindString(message); match != "" {
+		slots[SlotUrgency] = strings.ToLower(match)
+	}
+	if match := businessRegex.FindString(message); match != "" {
+		slots[SlotBusiness] = strings.ToLower(match)
+	}
+	if match := specialistRegex.FindString(message); match != "" {
+		slots[SlotSpecialist] = strings.ToLower(match)
+	}
+	if region := regionRegex.FindString(accentReplacer.Replace(lower)); region != "" {
+		if region == "cuzco" {
+			region = "cusco"
+		}
+		slots[SlotRegion] = region
+	}
+
+	return slots
+}
+
+// NextStage aplica las transiciones del embudo. Solo avanza (nunca retrocede). El cierre no
+// sale de la conversación: lo pone el ciclo de vida del lead (won, lost o no_response) y es terminal.
+func (f *FunnelService) NextStage(current string, ev FunnelEvent) (string, string) {
+	if current == "" {
+		current = StageGreeting
+	}
+	if current == StageClosed {
+		return current, ""
+	}
+
+	// Spam y mensajes ambiguos no mueven el embudo
+	intent := strings.ToLower(ev.Intent)
+	if intent == "spam" || intent == "ambiguous" || intent == "ambiguo" {
+		return current, ""
+	}
+
+	next, trigger := current, ""
+	advance := func(stage, reason string) {
+		if StageIndex(stage) > StageIndex(next) {
+			next, trigger = stage, reason
+		}
+	}
+
+	hasVehicle := ev.Slots[SlotVehicle] != "" || ev.Slots[SlotBrand] != ""
+	hasQualifier := ev.Slots[SlotBudget] != "" || ev.Slots[SlotUrgency] != "" || ev.Slots[SlotBusiness] != ""
+
+	if intent != "" && intent != "general" {
+		advance(StageDiscovery, "intent:"+intent)
+	} else if intent == "general" && current != StageGreeting {
+		advance(StageDiscovery, "intent:general")
+	}
+	if intent == "auction" || intent == "subasta" || hasVehicle {
+		advance(StageVehicleInterest, triggerFor(intent, hasVehicle))
+	}
+	if hasQualifier && StageIndex(next) >= StageIndex(StageVehicleInterest) {
+		advance(StageQualification, "slots:calificacion")
+	}
+	if ev.Slots[SlotSpecialist] != "" {
+		advance(StageHandoff, "slots:especialista")
+	}
+	if ev.Category == "hot" {
+		advance(StageHandoff, "category:hot")
+	}
+
+	return next, trigger
+}
+
+func triggerFor(intent string, hasVehicle bool) string {
+	if hasVehicle {
+		return "slots:vehiculo"
+	}
+	return "intent:" + intent
+}
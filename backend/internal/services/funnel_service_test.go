@@ -0,0 +1,73 @@
+package services
+
+import "testing"
+
+func TestExtractSlots(t *testing.T) {
+	funnel := GetFunnelService()
+
+	cases := []struct {
+		name    string
+		message string
+		want    map[string]string
+	}{
+		{"marca y tipo", "Busco una camioneta Toyota", map[string]string{SlotBrand: "toyota", SlotVehicle: "camioneta"}},
+		{"presupuesto en dolares", "tengo un presupuesto de 15 mil dolares", map[string]string{SlotBudget: "presupuesto de 15 mil dolares"}},
+		{"presupuesto en soles", "unos S/ 40,000 para esta semana", map[string]string{SlotBudget: "S/ 40,000", SlotUrgency: "esta semana"}},
+		{"negocio", "es para la flota de mi empresa", map[string]string{SlotBusiness: "flota"}},
+		{"pide un humano", "quiero hablar con un humano", map[string]string{SlotSpecialist: "hablar con un humano"}},
+		{"pide un asesor", "pásame con un asesor", map[string]string{SlotSpecialist: "asesor"}},
+		{"pregunta si es humano", "¿eres humano?", map[string]string{}},
+		{"van como verbo", "¿cuándo van a publicar la subasta?", map[string]string{}},
+		{"minivan", "me interesa la minivan", map[string]string{SlotVehicle: "minivan"}},
+		{"region con tilde", "estoy en Huánuco", map[string]string{SlotRegion: "huanuco"}},
+		{"cuzco se normaliza", "vivo en Cuzco", map[string]string{SlotRegion: "cusco"}},
+		{"sin datos", "hola, buenas tardes", map[string]string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := funnel.ExtractSlots(c.message)
+			if len(got) != len(c.want) {
+				t.Fatalf("ExtractSlots(%q) = %v, se esperaba %v", c.message, got, c.want)
+			}
+			for k, v := range c.want {
+				if got[k] != v {
+					t.Errorf("slot %s = %q, se esperaba %q (todos: %v)", k, got[k], v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNextStage(t *testing.T) {
+	funnel := GetFunnelService()
+
+	cases := []struct {
+		name        string
+		current     string
+		ev          FunnelEvent
+		wantStage   string
+		wantTrigger string
+	}{
+		{"saludo se queda en saludo", StageGreeting, FunnelEvent{Intent: "general"}, StageGreeting, ""},
+		{"etapa vacia es saludo", "", FunnelEvent{}, StageGreeting, ""},
+		{"intencion faq pasa a descubrimiento", StageGreeting, FunnelEvent{Intent: "faq"}, StageDiscovery, "intent:faq"},
+		{"general despues del saludo", StageDiscovery, FunnelEvent{Intent: "general"}, StageDiscovery, ""},
+		{"vehiculo", StageGreeting, FunnelEvent{Intent: "faq", Slots: map[string]string{SlotVehicle: "auto"}}, StageVehicleInterest, "slots:vehiculo"},
+		{"subasta sin vehiculo", StageDiscovery, FunnelEvent{Intent: "auction"}, StageVehicleInterest, "intent:auction"},
+		{"calificacion requiere vehiculo", StageDiscovery, FunnelEvent{Intent: "general", Slots: map[string]string{SlotBudget: "$5000"}}, StageDiscovery, ""},
+		{"calificacion", StageVehicleInterest, FunnelEvent{Intent: "general", Slots: map[string]string{SlotBudget: "$5000"}}, StageQualification, "slots:calificacion"},
+		{"pide especialista", StageDiscovery, FunnelEvent{Intent: "general", Slots: map[string]string{SlotSpecialist: "asesor"}}, StageHandoff, "slots:especialista"},
+		{"lead caliente", StageQualification, FunnelEvent{Intent: "general", Category: "hot"}, StageHandoff, "category:hot"},
+		{"nunca retrocede", StageQualification, FunnelEvent{Intent: "faq"}, StageQualification, ""},
+		{"spam no mueve", StageGreeting, FunnelEvent{Intent: "spam", Slots: map[string]string{SlotVehicle: "auto"}}, StageGreeting, ""},
+		{"cerrado es terminal", StageClosed, FunnelEvent{Intent: "auction", Category: "hot"}, StageClosed, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stage, trigger := funnel.NextStage(c.current, c.ev)
+			if stage != c.wantStage || trigger != c.wantTrigger {
+				t.Errorf("NextStage(%q) = (%q, %q), se esperaba (%q, %q)", c.current, stage, trigger, c.wantStage, c.wantTrigger)
+			}
+		})
+	}
+}
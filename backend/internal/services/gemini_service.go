@@ -60,7 +60,15 @@ func (g *GeminiService) ProcessMessage(sessionID, userMessage string) (string, e
 	faqService := GetFAQService()
 	bobAPIService := GetBOBAPIService()
 
-	systemPrompt := g.buildSystemPrompt()
+	channel := ""
+	if session := sessionService.GetSession(sessionID); session != nil {
+		channel = session.Channel
+	}
+
+	systemPrompt, err := g.buildSystemPrompt(channel)
+	if err != nil {
+		return "", err
+	}
 	faqContext := faqService.GetFAQsContext()
 	vehiclesContext := bobAPIService.GetVehiclesContext(5)
 
@@ -119,34 +127,13 @@ func (g *GeminiService) CalculateScore(sessionID string) (*models.ScoreResponse,
 	}
 
 	// Construir prompt para scoring
-	var conversationText strings.Builder
-	for _, msg := range messages {
-		conversationText.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+	scoringPrompt, _, err := GetPromptService().Render("lead_score", DefaultPromptChannel, map[string]any{
+		"History": messages,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	scoringPrompt := fmt.Sprintf(`Analiza esta conversación y calcula un score de lead (0-100).
-
-Conversación:
-%s
-
-Responde SOLO con un JSON en este formato exacto:
-{
-  "score": número entre 0-100,
-  "category": "hot" (80-100) | "warm" (50-79) | "cold" (0-49),
-  "reasons": ["razón 1", "razón 2"],
-  "urgency": "high" | "medium" | "low" | "none",
-  "budget": "defined" | "exploring" | "undefined",
-  "businessType": "company" | "individual" | "unknown"
-}
-
-Criterios de scoring:
-- Necesidad clara y urgente: +30 puntos
-- Presupuesto definido: +25 puntos
-- Empresa/negocio: +20 puntos
-- Preguntas específicas sobre productos: +15 puntos
-- Intención de compra explícita: +10 puntos
-- Solo curiosidad o preguntas muy generales: -20 puntos`, conversationText.String())
-
 	resp, err := g.model.GenerateContent(ctx, genai.Text(scoringPrompt))
 	if err != nil {
 		return nil, fmt.Errorf("error al calcular score: %w", err)
@@ -163,7 +150,7 @@ Criterios de scoring:
 	jsonEnd := strings.LastIndex(responseText, "}")
 
 	if jsonStart == -1 || jsonEnd == -1 {
-		log.Printf("No se pudo parsear respuesta de scoring: %s", responseText)
+		log.Printf("No se pudo parsear respuesta de scoring: %s", RedactPII(responseText))
 		return &models.ScoreResponse{
 			Success:      true,
 			Score:        25,
@@ -213,30 +200,9 @@ Criterios de scoring:
 	return scoreResponse, nil
 }
 
-func (g *GeminiService) buildSystemPrompt() string {
-	return `Eres un asistente virtual de BOB Subastas, una plataforma líder en Perú para subastas de vehículos e inmuebles.
-
-PERSONALIDAD:
-- Amigable, profesional y conversacional
-- Breve y directo (máximo 3 líneas)
-- Usa emojis ocasionalmente
-- Tutea al usuario
-
-OBJETIVO:
-- Ayudar a encontrar vehículos en subasta
-- Responder preguntas sobre el proceso
-- Calificar el interés del lead
-
-REGLAS:
-1. Responde siempre en máximo 3 líneas
-2. Si preguntan por vehículos, usa los datos disponibles
-3. Si no sabes algo, revisa las FAQs
-4. Invita a dar más detalles sobre necesidades
-5. Sé conversacional, no uses bullet points
-
-Ejemplo:
-Usuario: "Busco un auto"
-Tú: "¡Perfecto! 🚗 Tenemos varias opciones en subasta. ¿Tienes alguna marca o modelo en mente? ¿Y qué presupuesto manejas?"`
+func (g *GeminiService) buildSystemPrompt(channel string) (string, error) {
+	prompt, _, err := GetPromptService().Render("assistant_system", channel, nil)
+	return prompt, err
 }
 
 func (g *GeminiService) Close() {
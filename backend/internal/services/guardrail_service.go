@@ -0,0 +1,718 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/telemetry"
+	"bufio"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Acciones de una regla de guardrail
+const (
+	GuardrailBlock  = "block"  // no se llama a los agentes, se responde con el texto de la regla
+	GuardrailWarn   = "warn"   // se responde normal, con la advertencia antes de la respuesta
+	GuardrailFlag   = "flag"   // solo se registra y se resta la penalidad al lead
+	GuardrailFilter = "filter" // respuestas: se quitan las oraciones que violan la regla
+)
+
+// Dirección del texto evaluado
+const (
+	GuardrailInput  = "input"
+	GuardrailOutput = "output"
+)
+
+// Reglas de guardrail
+const (
+	GuardrailRuleLength     = "length"
+	GuardrailRuleFlood      = "flood"
+	GuardrailRuleRepeat     = "repeat"
+	GuardrailRuleProfanity  = "profanity"
+	GuardrailRuleLinkSpam   = "link_spam"
+	GuardrailRuleInjection  = "prompt_injection"
+	GuardrailRulePromptLeak = "prompt_leak"
+	GuardrailRuleCompetitor = "competitor"
+	GuardrailRulePromise    = "promise"
+)
+
+// guardrailFallbackReply reemplaza una respuesta que no se puede enviar
+const guardrailFallbackReply = "Solo puedo ayudarte con consultas sobre las subastas de BOB. ¿Buscas algún vehículo o tienes dudas sobre el proceso?"
+
+// GuardrailRule define qué hacer cuando se dispara una regla
+type GuardrailRule struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Direction string `json:"direction"`
+	Enabled   bool   `json:"enabled"`
+	Action    string `json:"action"`
+	Penalty   int    `json:"penalty"`         // puntos que se restan al score del lead
+	Reply     string `json:"reply,omitempty"` // respuesta al bloquear o advertencia al avisar
+}
+
+var defaultGuardrailRules = []GuardrailRule{
+	{ID: GuardrailRuleLength, Name: "Mensaje demasiado largo", Direction: GuardrailInput, Enabled: true, Action: GuardrailBlock,
+		Reply: "Tu mensaje es muy largo. ¿Puedes resumirme en pocas líneas qué necesitas?"},
+	{ID: GuardrailRuleFlood, Name: "Demasiados mensajes seguidos", Direction: GuardrailInput, Enabled: true, Action: GuardrailBlock, Penalty: 5,
+		Reply: "Recibí varios mensajes seguidos. Dame un momento y cuéntame en un solo mensaje qué necesitas."},
+	{ID: GuardrailRuleRepeat, Name: "Mensaje repetido", Direction: GuardrailInput, Enabled: true, Action: GuardrailBlock, Penalty: 5,
+		Reply: "Ya recibí ese mensaje. ¿Puedes darme más detalles para ayudarte mejor?"},
+	{ID: GuardrailRuleProfanity, Name: "Lenguaje ofensivo", Direction: GuardrailInput, Enabled: true, Action: GuardrailWarn, Penalty: 10,
+		Reply: "Te pido mantener un trato respetuoso para poder ayudarte."},
+	{ID: GuardrailRuleLinkSpam, Name: "Enlaces de spam", Direction: GuardrailInput, Enabled: true, Action: GuardrailBlock, Penalty: 15,
+		Reply: "Este canal es solo para consultas sobre las subastas de BOB. Si buscas un vehículo o tienes dudas del proceso, con gusto te ayudo."},
+	{ID: GuardrailRuleInjection, Name: "Intento de prompt injection", Direction: GuardrailInput, Enabled: true, Action: GuardrailBlock, Penalty: 10,
+		Reply: guardrailFallbackReply},
+	{ID: GuardrailRulePromptLeak, Name: "Respuesta con instrucciones internas", Direction: GuardrailOutput, Enabled: true, Action: GuardrailBlock,
+		Reply: guardrailFallbackReply},
+	{ID: GuardrailRuleCompetitor, Name: "Mención de competidores", Direction: GuardrailOutput, Enabled: true, Action: GuardrailFilter},
+	{ID: GuardrailRulePromise, Name: "Promesas que BOB no puede cumplir", Direction: GuardrailOutput, Enabled: true, Action: GuardrailFilter},
+}
+
+var (
+	profanityRegex = regexp.MustCompile(`\b(mierda|carajo|conchatumadre|conchasumadre|ctm|csm|huevon|cojudo|imbecil|idiota|estupido|pendejo|puta|puto|jodete|hdp|hijo de puta|cabron)\b`)
+	urlRegex       = regexp.MustCompile(`(?i)\b((https?://|www\.)[^\s<>"]+|[a-z0-9-]+\.(com|net|org|xyz|info|link|ly|me|io|co|pe|site|online|top|click)(/[^\s<>"]*)?)`)
+	shortenerHosts = []string{"bit.ly", "tinyurl.com", "t.co", "cutt.ly", "rebrand.ly", "shorturl.at", "t.me", "is.gd"}
+
+	injectionPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(ignor[ae]r?|olvid[ae]r?|omit[ea]r?|descart[ae]r?)\s+(todas?\s+)?(las\s+|tus\s+|los\s+)?(instrucciones|reglas|indicaciones|prompts?)\b`),
+		regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+|any\s+)?(the\s+)?(previous|prior|above|your)\s+(instructions|prompts?|rules)\b`),
+		regexp.MustCompile(`(?i)\b(muestra|mu[eé]strame|revela|rev[eé]lame|dime|imprime|repite|escribe)\s+(tu|el|tus|las)\s+(prompt|instrucciones|reglas internas|mensaje de sistema|system prompt)\b`),
+		regexp.MustCompile(`(?i)(system prompt|prompt del sistema|developer mode|modo desarrollador|jailbreak|do anything now)`),
+		regexp.MustCompile(`\bDAN\b`),
+		regexp.MustCompile(`(?i)(a partir de ahora (eres|ser[aá]s|act[uú]as)|act[uú]a como si|finge (que eres|ser)|you are now|pretend (to be|you are))`),
+		regexp.MustCompile(`(?im)(^\s*(system|sistema|assistant)\s*:|<\|?(system|im_start|im_end)\|?>|\[/?INST\])`),
+		regexp.MustCompile(`(?i)"(routeTo|shouldRoute|intent|confidence)"\s*:`),
+	}
+
+	promptLeakRegex = regexp.MustCompile(`(?i)(eres el agente|an[aá]lisis requerido|formato de respuesta|responde solo con el json|mensaje del usuario:|instrucciones del sistema|system prompt|"shouldRoute"|"routeTo"|\{\{)`)
+	promiseRegex    = regexp.MustCompile(`(?i)(te (lo )?garantiz[oa]|garantizamos que|100\s?% garantizado|precio (final )?garantizado|seguro que (ganas|ganar[aá]s)|ganar[aá]s la subasta|sin ning[uú]n riesgo|reembolso total garantizado|te aseguro que)`)
+
+	zeroWidthReplacer = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\u200e", "", "\u200f", "", "\u2060", "", "\ufeff", "")
+	templateReplacer  = strings.NewReplacer("{{", "{ {", "}}", "} }")
+	roleLineRegex     = regexp.MustCompile(`(?im)^(\s*)(system|sistema|assistant|asistente|user|usuario|bob|cliente)\s*:`)
+	extraNewlines     = regexp.MustCompile(`\n{3,}`)
+	sentenceRegex     = regexp.MustCompile(`[^.!?\n]+[.!?]*\s*|\n+`)
+)
+
+// GuardrailResult es lo que se decidió sobre un mensaje entrante
+type GuardrailResult struct {
+	Blocked bool
+	Reply   string // respuesta al bloquear
+	Warning string // advertencia a anteponer a la respuesta
+	Rule    string // regla que bloqueó o advirtió
+	Message string // mensaje saneado para guardar y enviar a los agentes
+	Applied []string
+}
+
+// GuardrailService aplica reglas sobre los mensajes antes de llegar a los agentes (largo,
+// flood, repetición, lenguaje, enlaces, prompt injection) y sobre las respuestas antes de
+// enviarlas (instrucciones internas, competidores, promesas). Cada regla disparada se
+// registra en data/guardrail_events.jsonl.
+type GuardrailService struct {
+	rules           map[string]GuardrailRule
+	events          []models.GuardrailEvent // de la más antigua a la más reciente
+	recent          map[string][]time.Time  // mensajes recientes por sesión, para el flood
+	allowedDomains  []string
+	competitorRegex *regexp.Regexp
+	sessionService  *SessionService
+	maxEntries      int
+	dataFile        string
+	mu              sync.Mutex
+}
+
+var guardrailServiceInstance *GuardrailService
+var guardrailServiceOnce sync.Once
+
+func GetGuardrailService() *GuardrailService {
+	guardrailServiceOnce.Do(func() {
+		maxEntries := config.AppConfig.GuardrailLogMax
+		if maxEntries <= 0 {
+			maxEntries = 20000
+		}
+
+		guardrailServiceInstance = &GuardrailService{
+			recent:         make(map[string][]time.Time),
+			allowedDomains: splitList(config.AppConfig.GuardrailAllowedDomains),
+			sessionService: GetSessionService(),
+			maxEntries:     maxEntries,
+			dataFile:       filepath.Join("data", "guardrail_events.jsonl"),
+		}
+		if competitors := splitList(config.AppConfig.GuardrailCompetitors); len(competitors) > 0 {
+			quoted := make([]string, len(competitors))
+			for i, c := range competitors {
+				quoted[i] = regexp.QuoteMeta(c)
+			}
+			guardrailServiceInstance.competitorRegex = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+		}
+		guardrailServiceInstance.loadRules(config.AppConfig.GuardrailRulesFile)
+		guardrailServiceInstance.loadFromDisk()
+	})
+	return guardrailServiceInstance
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadRules parte de las reglas por defecto y aplica las del archivo, por id
+func (g *GuardrailService) loadRules(file string) {
+	g.rules = make(map[string]GuardrailRule)
+	for _, rule := range defaultGuardrailRules {
+		g.rules[rule.ID] = rule
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Printf("Sin archivo de reglas de guardrails (%s), usando reglas por defecto", file)
+		return
+	}
+
+	var rules []GuardrailRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("⚠️ Reglas de guardrails inválidas en %s: %v. Usando reglas por defecto", file, err)
+		return
+	}
+	for _, rule := range rules {
+		base, known := g.rules[rule.ID]
+		if !known {
+			log.Printf("⚠️ Regla de guardrail desconocida: %s", rule.ID)
+			continue
+		}
+		rule.Direction = base.Direction
+		if rule.Reply == "" {
+			rule.Reply = base.Reply
+		}
+		g.rules[rule.ID] = rule
+	}
+	log.Printf("%d reglas de guardrails cargadas desde %s", len(rules), file)
+}
+
+// Rules devuelve las reglas vigentes en el orden por defecto
+func (g *GuardrailService) Rules() []GuardrailRule {
+	rules := make([]GuardrailRule, 0, len(defaultGuardrailRules))
+	for _, rule := range defaultGuardrailRules {
+		rules = append(rules, g.rules[rule.ID])
+	}
+	return rules
+}
+
+// CheckInput evalúa un mensaje entrante. history son los mensajes previos de la sesión.
+func (g *GuardrailService) CheckInput(sessionID, channel, message string, history []models.Message) GuardrailResult {
+	result := GuardrailResult{Message: SanitizePromptText(message)}
+	if !config.AppConfig.GuardrailsEnabled {
+		return result
+	}
+
+	type hit struct{ rule, detail string }
+	var hits []hit
+
+	if max := config.AppConfig.GuardrailMaxMessageChars; max > 0 {
+		if n := utf8.RuneCountInString(message); n > max {
+			hits = append(hits, hit{GuardrailRuleLength, strconv.Itoa(n) + " caracteres"})
+		}
+	}
+	if count := g.trackFlood(sessionID); count > 0 {
+		hits = append(hits, hit{GuardrailRuleFlood, strconv.Itoa(count) + " mensajes en la ventana"})
+	}
+	if isRepeated(message, history) {
+		hits = append(hits, hit{GuardrailRuleRepeat, "mismo mensaje repetido"})
+	}
+
+	folded := foldText(message)
+	if match := profanityRegex.FindString(folded); match != "" {
+		hits = append(hits, hit{GuardrailRuleProfanity, match})
+	}
+	if detail := g.linkSpam(message); detail != "" {
+		hits = append(hits, hit{GuardrailRuleLinkSpam, detail})
+	}
+	for _, pattern := range injectionPatterns {
+		if match := pattern.FindString(message); match != "" {
+			hits = append(hits, hit{GuardrailRuleInjection, strings.TrimSpace(match)})
+			break
+		}
+	}
+
+	for _, h := range hits {
+		rule, ok := g.rules[h.rule]
+		if !ok || !rule.Enabled {
+			continue
+		}
+		g.record(sessionID, channel, GuardrailInput, rule, h.detail, message)
+		result.Applied = append(result.Applied, rule.ID+":"+rule.Action)
+
+		switch rule.Action {
+		case GuardrailBlock:
+			if !result.Blocked {
+				result.Blocked = true
+				result.Reply = rule.Reply
+				result.Rule = rule.ID
+			}
+		case GuardrailWarn:
+			if result.Warning == "" {
+				result.Warning = rule.Reply
+				if result.Rule == "" {
+					result.Rule = rule.ID
+				}
+			}
+		}
+	}
+	if result.Blocked && result.Reply == "" {
+		result.Reply = guardrailFallbackReply
+	}
+	return result
+}
+
+// FilterOutput revisa la respuesta antes de enviarla: si filtra instrucciones internas se
+// reemplaza entera, y se quitan las oraciones que mencionan competidores o prometen resultados
+func (g *GuardrailService) FilterOutput(sessionID, channel, reply string) (string, []string) {
+	if !config.AppConfig.GuardrailsEnabled || strings.TrimSpace(reply) == "" {
+		return reply, nil
+	}
+
+	var applied []string
+	if match := promptLeakRegex.FindString(reply); match != "" {
+		if rule := g.rules[GuardrailRulePromptLeak]; rule.Enabled {
+			g.record(sessionID, channel, GuardrailOutput, rule, match, reply)
+			applied = append(applied, rule.ID+":"+rule.Action)
+			if rule.Action == GuardrailBlock || rule.Action == GuardrailFilter {
+				return fallbackReply(rule), applied
+			}
+		}
+	}
+
+	checks := []struct {
+		rule  string
+		regex *regexp.Regexp
+	}{
+		{GuardrailRuleCompetitor, g.competitorRegex},
+		{GuardrailRulePromise, promiseRegex},
+	}
+	for _, check := range checks {
+		rule := g.rules[check.rule]
+		if check.regex == nil || !rule.Enabled {
+			continue
+		}
+
+		var kept strings.Builder
+		var removed []string
+		for _, sentence := range sentenceRegex.FindAllString(reply, -1) {
+			if check.regex.MatchString(foldText(sentence)) || check.regex.MatchString(sentence) {
+				removed = append(removed, strings.TrimSpace(sentence))
+				continue
+			}
+			kept.WriteString(sentence)
+		}
+		if len(removed) == 0 {
+			continue
+		}
+
+		g.record(sessionID, channel, GuardrailOutput, rule, strings.Join(removed, " | "), reply)
+		applied = append(applied, rule.ID+":"+rule.Action)
+		if rule.Action != GuardrailFilter {
+			if rule.Action == GuardrailBlock {
+				return fallbackReply(rule), applied
+			}
+			continue
+		}
+		reply = strings.TrimSpace(kept.String())
+		if reply == "" {
+			return fallbackReply(rule), applied
+		}
+	}
+	return reply, applied
+}
+
+func fallbackReply(rule GuardrailRule) string {
+	if rule.Reply != "" {
+		return rule.Reply
+	}
+	return guardrailFallbackReply
+}
+
+// Events devuelve los eventos que cumplen el filtro, del más reciente al más antiguo
+func (g *GuardrailService) Events(filter models.GuardrailFilter) []models.GuardrailEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	result := []models.GuardrailEvent{}
+	for i := len(g.events) - 1; i >= 0; i-- {
+		event := g.events[i]
+		if filter.SessionID != "" && event.SessionID != filter.SessionID {
+			continue
+		}
+		if filter.Rule != "" && event.Rule != filter.Rule {
+			continue
+		}
+		if filter.Action != "" && event.Action != filter.Action {
+			continue
+		}
+		result = append(result, event)
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Stats cuenta los eventos en memoria por regla y por acción
+func (g *GuardrailService) Stats() (byRule map[string]int, byAction map[string]int, sessions int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	byRule = make(map[string]int)
+	byAction = make(map[string]int)
+	seen := make(map[string]bool)
+	for _, event := range g.events {
+		byRule[event.Rule]++
+		byAction[event.Action]++
+		seen[event.SessionID] = true
+	}
+	return byRule, byAction, len(seen)
+}
+
+// record guarda el evento, lo cuenta en las métricas y aplica la penalidad al lead
+func (g *GuardrailService) record(sessionID, channel, direction string, rule GuardrailRule, detail, text string) {
+	event := models.GuardrailEvent{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Channel:   channel,
+		Direction: direction,
+		Rule:      rule.ID,
+		Action:    rule.Action,
+		Penalty:   rule.Penalty,
+		Detail:    excerpt(RedactPII(detail)),
+		Excerpt:   excerpt(RedactPII(text)),
+		CreatedAt: time.Now(),
+	}
+	log.Printf("🛡️ Guardrail %s (%s) en %s de %s: %s", rule.ID, rule.Action, direction, sessionID, event.Detail)
+	telemetry.CountGuardrail(direction, rule.ID, rule.Action)
+
+	g.mu.Lock()
+	g.events = append(g.events, event)
+	if len(g.events) > g.maxEntries {
+		g.events = append([]models.GuardrailEvent(nil), g.events[len(g.events)-g.maxEntries:]...)
+	}
+	g.appendToDisk(event)
+	g.mu.Unlock()
+
+	if rule.Penalty > 0 || rule.Action == GuardrailFlag {
+		g.sessionService.AddGuardrailFlag(sessionID, rule.ID, rule.Penalty)
+	}
+}
+
+// trackFlood registra el mensaje y devuelve cuántos hubo en la ventana si superan el límite
+func (g *GuardrailService) trackFlood(sessionID string) int {
+	limit := config.AppConfig.GuardrailFloodMessages
+	window := time.Duration(config.AppConfig.GuardrailFloodWindowSeconds) * time.Second
+	if limit <= 0 || window <= 0 {
+		return 0
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	recent := g.recent[sessionID][:0]
+	for _, t := range g.recent[sessionID] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	g.recent[sessionID] = recent
+
+	// Limpiar sesiones sin actividad para que el mapa no crezca sin límite
+	if len(g.recent) > 1000 {
+		for id, times := range g.recent {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= window {
+				delete(g.recent, id)
+			}
+		}
+	}
+
+	if len(recent) > limit {
+		return len(recent)
+	}
+	return 0
+}
+
+// isRepeated indica si el mensaje es igual a los últimos mensajes del usuario, tantas veces
+// que con este se llega a GUARDRAIL_REPEAT_LIMIT
+func isRepeated(message string, history []models.Message) bool {
+	limit := config.AppConfig.GuardrailRepeatLimit
+	if limit <= 1 {
+		return false
+	}
+
+	// El historial guarda los mensajes con los datos personales redactados o con tokens
+	normalized := normalizeMessage(RedactPII(message))
+	if normalized == "" {
+		return false
+	}
+	same := 0
+	for i := len(history) - 1; i >= 0 && same < limit-1; i-- {
+		if history[i].Role != "user" {
+			continue
+		}
+		if normalizeMessage(RedactPII(untokenize(history[i].Content))) != normalized {
+			return false
+		}
+		same++
+	}
+	return same >= limit-1
+}
+
+// linkSpam detecta enlaces acortados, o enlaces ajenos a BOB junto con frases de spam o en
+// cantidad. El dominio de un email no cuenta como enlace.
+func (g *GuardrailService) linkSpam(message string) string {
+	foreign := 0
+	for _, raw := range urlRegex.FindAllString(piiEmailRegex.ReplaceAllString(message, " "), -1) {
+		host := linkHost(raw)
+		if host == "" || g.allowedHost(host) {
+			continue
+		}
+		for _, shortener := range shortenerHosts {
+			if host == shortener {
+				return "enlace acortado: " + host
+			}
+		}
+		foreign++
+	}
+
+	if foreign >= 2 {
+		return strconv.Itoa(foreign) + " enlaces externos"
+	}
+	if foreign == 1 && intentSpamRegex.MatchString(message) {
+		return "enlace externo con frase de spam"
+	}
+	return ""
+}
+
+func linkHost(raw string) string {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+func (g *GuardrailService) allowedHost(host string) bool {
+	for _, domain := range g.allowedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizePromptText limpia el texto del usuario antes de guardarlo y de interpolarlo en los
+// prompts: quita caracteres de control e invisibles, neutraliza marcas de plantilla y líneas
+// que imitan un rol de la conversación (system:, assistant:, bob:)
+func SanitizePromptText(message string) string {
+	message = zeroWidthReplacer.Replace(message)
+	message = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, message)
+	message = templateReplacer.Replace(message)
+	message = roleLineRegex.ReplaceAllString(message, "${1}(${2})")
+	message = extraNewlines.ReplaceAllString(message, "\n\n")
+	return strings.TrimSpace(message)
+}
+
+// foldText pasa a minúsculas y sin tildes para comparar contra listas de palabras
+func foldText(text string) string {
+	return intentAccentReplacer.Replace(strings.ToLower(text))
+}
+
+// excerpt recorta el texto guardado en el evento
+func excerpt(text string) string {
+	if utf8.RuneCountInString(text) <= 200 {
+		return text
+	}
+	return truncateRunes(text, 200) + "…"
+}
+
+func (g *GuardrailService) loadFromDisk() {
+	file, err := os.Open(g.dataFile)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event models.GuardrailEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		lines++
+		g.events = append(g.events, event)
+		if len(g.events) > 2*g.maxEntries {
+			g.events = g.events[len(g.events)-g.maxEntries:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error al cargar eventos de guardrails: %v", err)
+	}
+	if len(g.events) > g.maxEntries {
+		g.events = g.events[len(g.events)-g.maxEntries:]
+	}
+
+	// El archivo solo crece: compactarlo cuando duplica lo que se conserva
+	if lines > 2*g.maxEntries {
+		g.rewriteToDisk()
+	}
+	log.Printf("%d eventos de guardrails cargados desde disco", len(g.events))
+}
+
+func (g *GuardrailService) appendToDisk(event models.GuardrailEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(g.dataFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Error al guardar evento de guardrail: %v", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Printf("Error al guardar evento de guardrail: %v", err)
+	}
+}
+
+func (g *GuardrailService) rewriteToDisk() {
+	tmp := g.dataFile + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.Printf("Error al compactar eventos de guardrails: %v", err)
+		return
+	}
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, event := range g.events {
+		encoder.Encode(event)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		log.Printf("Error al compactar eventos de guardrails: %v", err)
+		return
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, g.dataFile); err != nil {
+		log.Printf("Error al compactar eventos de guardrails: %v", err)
+	}
+}
+
+// AddGuardrailFlag suma la penalidad de una regla a la sesión y a su lead, si ya existe.
+// La penalidad acumulada no pasa de GuardrailMaxPenalty: flood y repeat se disparan en
+// cada mensaje bloqueado y no deben hundir el score de un lead real.
+func (s *SessionService) AddGuardrailFlag(sessionID, rule string, penalty int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return
+	}
+	if max := config.AppConfig.GuardrailMaxPenalty; max > 0 && session.GuardrailPenalty+penalty > max {
+		penalty = max - session.GuardrailPenalty
+		if penalty < 0 {
+			penalty = 0
+		}
+	}
+	session.GuardrailPenalty += penalty
+	session.GuardrailFlags = appendUnique(session.GuardrailFlags, rule)
+	// Una sesión sin scoring todavía (score 0, "cold" por defecto) no se recategoriza: el
+	// scoring aplica la penalidad acumulada cuando llegue
+	if penalty > 0 && session.LeadScore > 0 {
+		session.LeadScore = PenalizedScore(session.LeadScore, penalty)
+		session.Category = PenalizedCategory(session.Category, session.LeadScore)
+	}
+
+	if lead, ok := s.leads[sessionID]; ok {
+		lead.GuardrailPenalty = session.GuardrailPenalty
+		lead.GuardrailFlags = session.GuardrailFlags
+		if penalty > 0 {
+			lead.Score = PenalizedScore(lead.Score, penalty)
+			lead.Category = PenalizedCategory(lead.Category, lead.Score)
+		}
+	}
+	s.saveToDisk()
+}
+
+// PenalizedScore resta la penalidad sin bajar de 0
+func PenalizedScore(score, penalty int) int {
+	if score -= penalty; score < 0 {
+		return 0
+	}
+	return score
+}
+
+// categoryRanks ordena las categorías del scoring de peor a mejor
+var categoryRanks = map[string]int{"discarded": 0, "cold": 1, "warm": 2, "hot": 3}
+
+// CategoryForScore aplica los rangos del prompt de scoring (hot 85+, warm 65+, cold 45+)
+func CategoryForScore(score int) string {
+	switch {
+	case score >= 85:
+		return "hot"
+	case score >= 65:
+		return "warm"
+	case score >= 45:
+		return "cold"
+	}
+	return "discarded"
+}
+
+// PenalizedCategory baja la categoría si el score penalizado ya no alcanza su rango; nunca
+// la sube, para respetar la clasificación del modelo
+func PenalizedCategory(category string, score int) string {
+	byScore := CategoryForScore(score)
+	if rank, ok := categoryRanks[category]; ok && rank <= categoryRanks[byScore] {
+		return category
+	}
+	return byScore
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
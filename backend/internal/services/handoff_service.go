@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoleSpecialist es el rol de los mensajes escritos por un especialista humano
+const RoleSpecialist = "specialist"
+
+// ChannelSender entrega un mensaje al usuario por el canal de la sesión
+type ChannelSender interface {
+	Send(session *models.Session, text string) error
+}
+
+type HandoffService struct {
+	sessionService   *SessionService
+	formatterService *FormatterService
+	escalations      *EscalationService
+	specialists      *SpecialistService
+	senders          map[string]ChannelSender
+	webhookURL       string
+	httpClient       *http.Client
+	mu               sync.RWMutex
+}
+
+var handoffServiceInstance *HandoffService
+var handoffServiceOnce sync.Once
+
+func GetHandoffService() *HandoffService {
+	handoffServiceOnce.Do(func() {
+		client := &http.Client{Timeout: 10 * time.Second}
+		handoffServiceInstance = &HandoffService{
+			sessionService:   GetSessionService(),
+			formatterService: GetFormatterService(),
+			escalations:      GetEscalationService(),
+			specialists:      GetSpecialistService(),
+			senders: map[string]ChannelSender{
+				"whatsapp": &whatsAppSender{url: config.AppConfig.WhatsAppSendURL, client: client},
+			},
+			webhookURL: config.AppConfig.HandoffWebhookURL,
+			httpClient: client,
+		}
+	})
+	return handoffServiceInstance
+}
+
+// RegisterSender registra cómo entregar mensajes del especialista en un canal
+func (h *HandoffService) RegisterSender(channel string, sender ChannelSender) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.senders[strings.ToLower(channel)] = sender
+}
+
+// Claim pone la sesión en modo humano, la mueve a la etapa handoff del embudo
+// y da por atendidas sus escalaciones pendientes
+func (h *HandoffService) Claim(sessionID, specialist string) (*models.Session, error) {
+	session, err := h.sessionService.ClaimSession(sessionID, specialist)
+	if err != nil {
+		return nil, err
+	}
+
+	h.escalations.ResolveSession(sessionID, specialist)
+
+	// Quien toma la sesión queda como dueño del lead si está en el directorio
+	if lead := h.sessionService.GetLead(sessionID); lead != nil && lead.AssignedTo != specialist {
+		if _, err := h.specialists.GetSpecialist(specialist); err == nil {
+			h.specialists.Assign(sessionID, specialist, "claim", specialist)
+		}
+	}
+
+	if StageIndex(session.Stage) < StageIndex(StageHandoff) {
+		h.sessionService.UpdateStage(sessionID, StageHandoff, "claim:"+specialist)
+	}
+
+	return session, nil
+}
+
+// Release devuelve el control de la sesión al bot; specialist vacío la libera aunque la
+// tenga otro (admin)
+func (h *HandoffService) Release(sessionID, specialist string) (*models.Session, error) {
+	return h.sessionService.ReleaseSession(sessionID, specialist)
+}
+
+// Reply guarda la respuesta del especialista y la envía por el canal de la sesión.
+// Devuelve las partes enviadas, ya adaptadas al formato del canal.
+func (h *HandoffService) Reply(sessionID, specialist, message string) ([]string, error) {
+	session := h.sessionService.GetSession(sessionID)
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	if !session.IsHumanMode() {
+		return nil, ErrNotHumanMode
+	}
+	if session.Handoff.Specialist != specialist {
+		return nil, ErrSessionClaimed
+	}
+
+	replies, err := h.Deliver(session, RoleSpecialist, message, nil)
+	if err != nil {
+		return replies, err
+	}
+	h.sessionService.UpdatePending(sessionID, true)
+
+	// La primera respuesta del especialista cuenta como contacto
+	if lead := h.sessionService.GetLead(sessionID); lead != nil && lead.Status == LeadStatusNew {
+		h.sessionService.UpdateLeadLifecycle(sessionID, models.LeadUpdateRequest{
+			Actor:  specialist,
+			Status: LeadStatusContacted,
+		})
+	}
+
+	log.Printf("🙋 %s respondió en %s (%d mensajes)", specialist, sessionID, len(replies))
+	return replies, nil
+}
+
+// Deliver adapta el texto al canal, lo envía al usuario y, si el envío funcionó,
+// lo guarda en el historial con el rol indicado. En canales sin envío activo (web)
+// solo se guarda y el cliente lo lee del historial.
+func (h *HandoffService) Deliver(session *models.Session, role, text string, trace *models.ReplyTrace) ([]string, error) {
+	replies := h.formatterService.FormatReply(session.Channel, text)
+
+	h.mu.RLock()
+	sender, ok := h.senders[strings.ToLower(session.Channel)]
+	h.mu.RUnlock()
+
+	if ok {
+		for _, reply := range replies {
+			if err := sender.Send(session, reply); err != nil {
+				return replies, fmt.Errorf("error enviando mensaje por %s: %w", session.Channel, err)
+			}
+		}
+	}
+
+	h.sessionService.AddMessageWithTrace(session.SessionID, role, strings.Join(replies, "\n\n"), trace)
+	return replies, nil
+}
+
+// NotifyInbound avisa al especialista a cargo que llegó un mensaje del usuario
+func (h *HandoffService) NotifyInbound(sessionID, message string) {
+	session := h.sessionService.GetSession(sessionID)
+	if session == nil || !session.IsHumanMode() {
+		return
+	}
+
+	pending := h.sessionService.UpdatePending(sessionID, false)
+	log.Printf("📨 Mensaje para %s en %s (%d pendientes)", session.Handoff.Specialist, sessionID, pending)
+
+	if h.webhookURL == "" {
+		return
+	}
+
+	payload := map[string]any{
+		"event":      "inbound_message",
+		"sessionId":  sessionID,
+		"channel":    session.Channel,
+		"specialist": session.Handoff.Specialist,
+		"message":    RedactPII(message),
+		"pending":    pending,
+		"timestamp":  time.Now(),
+	}
+	go func() {
+		if err := postJSON(h.httpClient, h.webhookURL, payload); err != nil {
+			log.Printf("⚠️ Error notificando al especialista: %v", err)
+		}
+	}()
+}
+
+// whatsAppSender envía mensajes a través del engine de WhatsApp del bot
+type whatsAppSender struct {
+	url    string
+	client *http.Client
+}
+
+func (w *whatsAppSender) Send(session *models.Session, text string) error {
+	// Las sesiones de WhatsApp se crean como "wa-<jid>" desde el bot
+	return w.sendTo(strings.TrimPrefix(session.SessionID, "wa-"), text)
+}
+
+func (w *whatsAppSender) sendTo(recipient, text string) error {
+	if w.url == "" {
+		return fmt.Errorf("WHATSAPP_SEND_URL no configurado")
+	}
+
+	return postJSON(w.client, w.url, map[string]any{
+		"recipient": recipient,
+		"message":   text,
+	})
+}
+
+func postJSON(client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("respuesta %d de %s", resp.StatusCode, url)
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Origen de una predicción del clasificador local
+const (
+	IntentSourceRules = "rules"
+	IntentSourceModel = "model"
+)
+
+// Reglas del clasificador local
+const (
+	IntentRuleGreeting = "greeting"
+	IntentRuleThanks   = "thanks"
+	IntentRuleSpam     = "spam"
+	IntentRuleFAQMatch = "faq_match"
+)
+
+// faqMatchMinSimilarity es la similitud mínima (Jaccard de términos) entre el mensaje y la
+// pregunta de una FAQ para darla por obvia
+const faqMatchMinSimilarity = 0.6
+
+// IntentClassifier estima la intención de un mensaje sin llamar al LLM: primero con reglas
+// (saludos, agradecimientos, spam, preguntas casi idénticas a una FAQ) y después con un
+// modelo naive Bayes entrenado con las decisiones del orchestrator (cmd/trainintent).
+type IntentClassifier struct {
+	model      *models.IntentModel
+	modelFile  string
+	faqService *FAQService
+	guardrails *GuardrailService
+	mu         sync.RWMutex
+}
+
+var intentClassifierInstance *IntentClassifier
+var intentClassifierOnce sync.Once
+
+var (
+	intentAccentReplacer = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u")
+	intentNonWordRegex   = regexp.MustCompile(`[^a-zñ0-9]+`)
+	intentSpamRegex      = regexp.MustCompile(`(?i)(gana(r)? dinero|dinero f[aá]cil|trabaja desde casa|bitcoin|cripto|forex|sorteo|premio|haz clic|click aqu[ií]|prestamos? sin|multinivel|seguidores)`)
+)
+
+var (
+	greetingWords = wordSet("hola", "holi", "holaa", "buenas", "buenos", "buen", "hey", "saludos", "hi", "hello")
+	greetingExtra = wordSet("dias", "dia", "tardes", "noches", "que", "tal", "como", "estas", "esta", "bob")
+	thanksWords   = wordSet("gracias", "chau", "chao", "adios", "bye", "luego", "agradezco")
+	thanksExtra   = wordSet("muchas", "mil", "ok", "okey", "vale", "perfecto", "genial", "listo", "hasta", "muy",
+		"amable", "excelente", "de", "nada", "buenisimo", "por", "todo", "la", "info", "informacion", "te", "le", "lo", "bien")
+	intentStopwords = wordSet("el", "la", "los", "las", "un", "una", "unos", "unas", "de", "del", "al", "a", "en", "y", "o",
+		"que", "se", "es", "son", "por", "para", "con", "mi", "me", "mis", "tu", "su", "sus", "lo", "le", "hay", "como",
+		"cual", "cuales", "puedo", "pueden", "hola", "buenas", "quiero", "quisiera", "saber", "bob")
+)
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+func GetIntentClassifier() *IntentClassifier {
+	intentClassifierOnce.Do(func() {
+		intentClassifierInstance = &IntentClassifier{
+			modelFile:  config.AppConfig.IntentModelFile,
+			faqService: GetFAQService(),
+			guardrails: GetGuardrailService(),
+		}
+		if err := intentClassifierInstance.Reload(); err != nil {
+			log.Printf("Sin modelo de intenciones (%s), el clasificador local usa solo reglas", intentClassifierInstance.modelFile)
+		}
+	})
+	return intentClassifierInstance
+}
+
+// Reload vuelve a leer el modelo entrenado desde INTENT_MODEL_FILE
+func (c *IntentClassifier) Reload() error {
+	model, err := LoadIntentModel(c.modelFile)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.model = model
+	c.mu.Unlock()
+
+	log.Printf("🧠 Modelo de intenciones cargado: %d ejemplos, %d clases (entrenado %s)",
+		model.Examples, len(model.Classes), model.TrainedAt.Format("2006-01-02 15:04"))
+	return nil
+}
+
+// Classify devuelve la intención estimada y su confianza. Sin regla que aplique ni modelo
+// entrenado devuelve ambiguous con confianza 0, y el mensaje debe ir al LLM.
+func (c *IntentClassifier) Classify(message string) models.IntentPrediction {
+	tokens := intentTokens(message)
+
+	if rule := c.matchRule(message, tokens); rule != "" {
+		intent := "general"
+		if rule == IntentRuleSpam {
+			intent = IntentSpam
+		}
+		return models.IntentPrediction{Intent: intent, Confidence: 0.95, Source: IntentSourceRules, Rule: rule}
+	}
+
+	if c.matchFAQ(tokens) {
+		return models.IntentPrediction{Intent: "faq", Confidence: 0.95, Source: IntentSourceRules, Rule: IntentRuleFAQMatch}
+	}
+
+	c.mu.RLock()
+	model := c.model
+	c.mu.RUnlock()
+
+	if model != nil {
+		if intent, confidence := PredictIntent(model, message); intent != "" {
+			return models.IntentPrediction{Intent: intent, Confidence: confidence, Source: IntentSourceModel}
+		}
+	}
+	return models.IntentPrediction{Intent: IntentAmbiguous, Source: IntentSourceModel}
+}
+
+// matchRule aplica las reglas que no dependen de las FAQs. El spam sigue el mismo criterio
+// que la regla link_spam de guardrails (enlaces acortados, 2+ enlaces externos o un enlace
+// con frase de spam): un enlace suelto o una palabra como "cripto" pueden ser una consulta
+// legítima y van al LLM.
+func (c *IntentClassifier) matchRule(message string, tokens []string) string {
+	if c.guardrails != nil && c.guardrails.linkSpam(message) != "" {
+		return IntentRuleSpam
+	}
+	if len(tokens) == 0 || len(tokens) > 6 {
+		return ""
+	}
+	if onlyWords(tokens, greetingWords, greetingExtra) {
+		return IntentRuleGreeting
+	}
+	if onlyWords(tokens, thanksWords, thanksExtra) {
+		return IntentRuleThanks
+	}
+	return ""
+}
+
+// onlyWords indica si el mensaje tiene al menos una palabra clave y el resto son de relleno
+func onlyWords(tokens []string, keys, extra map[string]bool) bool {
+	found := false
+	for _, t := range tokens {
+		switch {
+		case keys[t]:
+			found = true
+		case extra[t]:
+		default:
+			return false
+		}
+	}
+	return found
+}
+
+// matchFAQ indica si el mensaje es prácticamente la pregunta de alguna FAQ: la misma
+// pregunta o casi los mismos términos
+func (c *IntentClassifier) matchFAQ(tokens []string) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	normalized := strings.Join(tokens, " ")
+	terms := contentTerms(tokens)
+
+	for _, faq := range c.faqService.GetAllFAQs() {
+		question := intentTokens(faq.Pregunta)
+		if strings.Join(question, " ") == normalized {
+			return true
+		}
+		if len(terms) >= 2 && jaccard(terms, contentTerms(question)) >= faqMatchMinSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+func contentTerms(tokens []string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, t := range tokens {
+		if len(t) > 2 && !intentStopwords[t] {
+			terms[t] = true
+		}
+	}
+	return terms
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// intentTokens pasa el mensaje a minúsculas sin tildes ni puntuación y lo separa en palabras
+func intentTokens(message string) []string {
+	text := intentAccentReplacer.Replace(strings.ToLower(message))
+	return strings.Fields(intentNonWordRegex.ReplaceAllString(text, " "))
+}
+
+// intentFeatures son las palabras y los pares de palabras consecutivas del mensaje
+func intentFeatures(message string) []string {
+	tokens := intentTokens(message)
+	features := append([]string(nil), tokens...)
+	for i := 1; i < len(tokens); i++ {
+		features = append(features, tokens[i-1]+"_"+tokens[i])
+	}
+	return features
+}
+
+// CanonicalIntent unifica las etiquetas que usa el LLM (auction, ambiguo) con las del clasificador
+func CanonicalIntent(intent string) string {
+	intent = NormalizeIntent(intent)
+	if intent == "auction" {
+		return "subasta"
+	}
+	return intent
+}
+
+// IntentExamplesFromDecisions arma el set de entrenamiento con las decisiones del LLM sin error
+// y con confianza mínima. Se descartan las del propio clasificador y las del modo degradado.
+func IntentExamplesFromDecisions(decisions []models.OrchestratorDecision, minConfidence float64) []models.IntentExample {
+	examples := []models.IntentExample{}
+	for _, d := range decisions {
+		if d.Error != "" || d.DecidedBy != "" || d.Confidence < minConfidence || strings.TrimSpace(d.Message) == "" {
+			continue
+		}
+		examples = append(examples, models.IntentExample{Message: d.Message, Intent: CanonicalIntent(d.Intent)})
+	}
+	return examples
+}
+
+// TrainIntentModel cuenta términos por intención para el naive Bayes multinomial
+func TrainIntentModel(examples []models.IntentExample) *models.IntentModel {
+	model := &models.IntentModel{
+		TrainedAt: time.Now(),
+		Classes:   make(map[string]*models.IntentClassStats),
+	}
+
+	vocabulary := make(map[string]bool)
+	for _, ex := range examples {
+		stats, ok := model.Classes[ex.Intent]
+		if !ok {
+			stats = &models.IntentClassStats{Counts: make(map[string]int)}
+			model.Classes[ex.Intent] = stats
+		}
+		stats.Docs++
+		for _, f := range intentFeatures(ex.Message) {
+			stats.Counts[f]++
+			stats.Tokens++
+			vocabulary[f] = true
+		}
+	}
+	model.Examples = len(examples)
+	model.Vocabulary = len(vocabulary)
+	return model
+}
+
+// PredictIntent devuelve la intención más probable y su probabilidad posterior. Solo usa
+// términos vistos en el entrenamiento; si el mensaje no tiene ninguno devuelve "".
+func PredictIntent(model *models.IntentModel, message string) (string, float64) {
+	if model == nil || model.Examples == 0 {
+		return "", 0
+	}
+
+	var known []string
+	for _, f := range intentFeatures(message) {
+		for _, stats := range model.Classes {
+			if stats.Counts[f] > 0 {
+				known = append(known, f)
+				break
+			}
+		}
+	}
+	if len(known) == 0 {
+		return "", 0
+	}
+
+	intents := make([]string, 0, len(model.Classes))
+	for intent := range model.Classes {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+
+	// Log-probabilidades con suavizado de Laplace
+	scores := make([]float64, len(intents))
+	maxScore := math.Inf(-1)
+	for i, intent := range intents {
+		stats := model.Classes[intent]
+		score := math.Log(float64(stats.Docs) / float64(model.Examples))
+		denominator := float64(stats.Tokens + model.Vocabulary)
+		for _, f := range known {
+			score += math.Log(float64(stats.Counts[f]+1) / denominator)
+		}
+		scores[i] = score
+		if score > maxScore {
+			maxScore = score
+		}
+	}
+
+	// Softmax para pasar a probabilidades
+	best, total := 0, 0.0
+	for i, score := range scores {
+		scores[i] = math.Exp(score - maxScore)
+		total += scores[i]
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	return intents[best], scores[best] / total
+}
+
+// LoadIntentModel lee un modelo guardado con SaveIntentModel
+func LoadIntentModel(file string) (*models.IntentModel, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var model models.IntentModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// SaveIntentModel guarda el modelo como JSON
+func SaveIntentModel(file string, model *models.IntentModel) error {
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
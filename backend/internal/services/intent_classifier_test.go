@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"testing"
+)
+
+func TestIntentRules(t *testing.T) {
+	classifier := &IntentClassifier{guardrails: &GuardrailService{allowedDomains: []string{"somosbob.com"}}}
+
+	cases := []struct {
+		message string
+		want    string
+	}{
+		{"hola", IntentRuleGreeting},
+		{"Buenas tardes, Bob!", IntentRuleGreeting},
+		{"muchas gracias", IntentRuleThanks},
+		{"ok chau", IntentRuleThanks},
+		{"hola, quiero saber cómo ofertar en la subasta", ""},
+		{"gana dinero fácil en www.ganafacil.xyz", IntentRuleSpam},
+		{"mira bit.ly/abc123", IntentRuleSpam},
+		{"entra a promo.com y tambien a oferta.net", IntentRuleSpam},
+		{"¿verifico la placa en www.sunarp.gob.pe?", ""},
+		{"¿aceptan cripto?", ""},
+		{"¿hay premio por referir?", ""},
+		{"mi correo es ana@gmail.com y el de mi socio luis@hotmail.com", ""},
+		{"vi el lote en somosbob.com/subastas y en www.somosbob.com/lotes", ""},
+	}
+	for _, c := range cases {
+		if got := classifier.matchRule(c.message, intentTokens(c.message)); got != c.want {
+			t.Errorf("matchRule(%q) = %q, se esperaba %q", c.message, got, c.want)
+		}
+	}
+}
+
+func TestPredictIntent(t *testing.T) {
+	model := TrainIntentModel([]models.IntentExample{
+		{Message: "como funciona la subasta", Intent: "faq"},
+		{Message: "que documentos necesito para ofertar", Intent: "faq"},
+		{Message: "cuales son las comisiones", Intent: "faq"},
+		{Message: "quiero ofertar por la camioneta toyota", Intent: "subasta"},
+		{Message: "me interesa el lote de camionetas", Intent: "subasta"},
+		{Message: "cuanto cuesta la hilux del lote 12", Intent: "subasta"},
+	})
+	if model.Examples != 6 || len(model.Classes) != 2 {
+		t.Fatalf("modelo inesperado: %d ejemplos, %d clases", model.Examples, len(model.Classes))
+	}
+
+	cases := []struct {
+		message string
+		want    string
+	}{
+		{"que comisiones cobran en la subasta", "faq"},
+		{"quiero ofertar por la hilux", "subasta"},
+		{"xyzzy plugh", ""},
+	}
+	for _, c := range cases {
+		intent, confidence := PredictIntent(model, c.message)
+		if intent != c.want {
+			t.Errorf("PredictIntent(%q) = %q (%.2f), se esperaba %q", c.message, intent, confidence, c.want)
+		}
+		if intent != "" && (confidence <= 0.5 || confidence > 1) {
+			t.Errorf("PredictIntent(%q): confianza fuera de rango %.2f", c.message, confidence)
+		}
+	}
+
+	if intent, _ := PredictIntent(nil, "hola"); intent != "" {
+		t.Errorf("sin modelo no debe predecir: %q", intent)
+	}
+}
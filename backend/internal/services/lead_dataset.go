@@ -0,0 +1,322 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Columnas reconocidas en datasets tabulares (encabezados sin tildes ni mayúsculas)
+var (
+	datasetIDColumns       = []string{"id", "session_id", "sessionid"}
+	datasetChannelColumns  = []string{"channel", "canal"}
+	datasetMessageColumns  = []string{"conversation", "conversacion", "messages", "mensajes", "transcript"}
+	datasetCategoryColumns = []string{"expected_category", "categoria_esperada", "label", "etiqueta"}
+	datasetScoreColumns    = []string{"expected_score", "score_esperado"}
+	datasetScoreMinColumns = []string{"expected_score_min", "score_min"}
+	datasetScoreMaxColumns = []string{"expected_score_max", "score_max"}
+	datasetResponseColumns = []string{"model_response", "respuesta_modelo"}
+)
+
+// Prefijos de rol en las conversaciones de texto ("cliente: hola", "bob: ¿en qué te ayudo?")
+var datasetRolePrefixes = map[string]string{
+	"user":      "user",
+	"usuario":   "user",
+	"cliente":   "user",
+	"lead":      "user",
+	"assistant": "assistant",
+	"asistente": "assistant",
+	"bob":       "assistant",
+	"bot":       "assistant",
+}
+
+// LoadLeadDataset lee leads o conversaciones desde XLSX, CSV o JSONL para scoring offline.
+// Los registros sin conversación se puntúan con un mensaje armado a partir de sus columnas
+// (ej. la planilla de contactos de BOB: nombres, DNI, ciudad).
+func LoadLeadDataset(filename string) ([]models.LeadRecord, error) {
+	var records []models.LeadRecord
+	var err error
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		var rows [][]string
+		if rows, err = readXLSXRows(filename); err == nil {
+			records, err = datasetFromRows(rows)
+		}
+	case ".csv":
+		var rows [][]string
+		if rows, err = readCSVRows(filename); err == nil {
+			records, err = datasetFromRows(rows)
+		}
+	case ".jsonl", ".ndjson":
+		records, err = readJSONLDataset(filename)
+	default:
+		return nil, fmt.Errorf("formato de dataset no soportado: %s (xlsx, csv, jsonl)", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	for i := range records {
+		if records[i].ID == "" {
+			records[i].ID = fmt.Sprintf("row-%d", i+1)
+		}
+		records[i].ExpectedCategory = strings.ToLower(strings.TrimSpace(records[i].ExpectedCategory))
+	}
+	return records, nil
+}
+
+func readCSVRows(filename string) ([][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(bufio.NewReader(file))
+	r.FieldsPerRecord = -1
+	return r.ReadAll()
+}
+
+// datasetFromRows convierte una tabla con encabezado en registros
+func datasetFromRows(rows [][]string) ([]models.LeadRecord, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("el archivo está vacío")
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, h := range rows[0] {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+	}
+	find := func(names []string) int {
+		for i, h := range header {
+			key := normalizeColumn(h)
+			for _, name := range names {
+				if key == name {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	idCol := find(datasetIDColumns)
+	channelCol := find(datasetChannelColumns)
+	messagesCol := find(datasetMessageColumns)
+	categoryCol := find(datasetCategoryColumns)
+	scoreCol := find(datasetScoreColumns)
+	scoreMinCol := find(datasetScoreMinColumns)
+	scoreMaxCol := find(datasetScoreMaxColumns)
+	responseCol := find(datasetResponseColumns)
+	known := map[int]bool{
+		idCol: true, channelCol: true, messagesCol: true, categoryCol: true,
+		scoreCol: true, scoreMinCol: true, scoreMaxCol: true, responseCol: true,
+	}
+
+	// Puntaje esperado por dimensión: columnas expected_<dimensión>
+	dimensionCols := make(map[string]int)
+	for _, dim := range ScoringDimensions {
+		if i := find([]string{"expected_" + dim.Key}); i >= 0 {
+			dimensionCols[dim.Key] = i
+			known[i] = true
+		}
+	}
+
+	var records []models.LeadRecord
+	for n, row := range rows[1:] {
+		cell := func(i int) string {
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		empty := true
+		for _, v := range row {
+			if strings.TrimSpace(v) != "" {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			continue
+		}
+
+		record := models.LeadRecord{
+			ID:               cell(idCol),
+			Channel:          cell(channelCol),
+			Messages:         parseConversation(cell(messagesCol)),
+			ExpectedCategory: cell(categoryCol),
+			ModelResponse:    cell(responseCol),
+			Fields:           make(map[string]string),
+		}
+
+		var err error
+		scores := []struct {
+			col    int
+			target **int
+		}{
+			{scoreCol, &record.ExpectedScore},
+			{scoreMinCol, &record.ExpectedScoreMin},
+			{scoreMaxCol, &record.ExpectedScoreMax},
+		}
+		for _, sc := range scores {
+			if *sc.target, err = parseOptionalInt(cell(sc.col)); err != nil {
+				return nil, fmt.Errorf("fila %d: %s inválido: %s", n+2, header[sc.col], cell(sc.col))
+			}
+		}
+		for key, col := range dimensionCols {
+			v, err := parseOptionalInt(cell(col))
+			if err != nil {
+				return nil, fmt.Errorf("fila %d: %s inválido: %s", n+2, header[col], cell(col))
+			}
+			if v != nil {
+				if record.ExpectedDimensions == nil {
+					record.ExpectedDimensions = make(map[string]int)
+				}
+				record.ExpectedDimensions[key] = *v
+			}
+		}
+
+		var profile []string
+		for i, h := range header {
+			if known[i] || h == "" || cell(i) == "" {
+				continue
+			}
+			record.Fields[h] = cell(i)
+			profile = append(profile, h+": "+cell(i))
+		}
+		if len(record.Messages) == 0 && len(profile) > 0 {
+			record.Messages = profileConversation(profile)
+		}
+		if record.ID == "" {
+			record.ID = fmt.Sprintf("row-%d", n+2)
+		}
+
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func parseOptionalInt(v string) (*int, error) {
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// readJSONLDataset lee un LeadRecord por línea; "conversation" puede venir como texto en vez de "messages"
+func readJSONLDataset(filename string) ([]models.LeadRecord, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []models.LeadRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var raw struct {
+			models.LeadRecord
+			Conversation string `json:"conversation"`
+		}
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("línea %d: %w", line, err)
+		}
+
+		record := raw.LeadRecord
+		if len(record.Messages) == 0 {
+			record.Messages = parseConversation(raw.Conversation)
+		}
+		if len(record.Messages) == 0 && len(record.Fields) > 0 {
+			keys := make([]string, 0, len(record.Fields))
+			for k := range record.Fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			profile := make([]string, len(keys))
+			for i, k := range keys {
+				profile[i] = k + ": " + record.Fields[k]
+			}
+			record.Messages = profileConversation(profile)
+		}
+		if record.ID == "" {
+			record.ID = fmt.Sprintf("line-%d", line)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// parseConversation separa una conversación en texto ("cliente: ...\nbob: ...") en mensajes.
+// Las líneas sin prefijo continúan el mensaje anterior; sin ningún prefijo todo es del usuario.
+func parseConversation(text string) []models.Message {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var messages []models.Message
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		role := ""
+		if colon := strings.Index(line, ":"); colon > 0 {
+			role = datasetRolePrefixes[strings.ToLower(strings.TrimSpace(line[:colon]))]
+			if role != "" {
+				line = strings.TrimSpace(line[colon+1:])
+			}
+		}
+
+		if role == "" && len(messages) > 0 {
+			last := &messages[len(messages)-1]
+			last.Content += "\n" + line
+			continue
+		}
+		if role == "" {
+			role = "user"
+		}
+		messages = append(messages, models.Message{Role: role, Content: line})
+	}
+	return messages
+}
+
+// profileConversation arma un mensaje del usuario con los datos del lead
+func profileConversation(profile []string) []models.Message {
+	return []models.Message{{
+		Role:    "user",
+		Content: "Hola, quiero información para participar en las subastas. Mis datos: " + strings.Join(profile, "; "),
+	}}
+}
+
+var columnAccents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// normalizeColumn pasa un encabezado a minúsculas, sin tildes y con guiones bajos
+func normalizeColumn(h string) string {
+	h = columnAccents.Replace(strings.ToLower(strings.TrimSpace(h)))
+	return strings.Join(strings.FieldsFunc(h, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "_")
+}
@@ -0,0 +1,43 @@
+package services
+
+import "bob-hackathon/internal/models"
+
+// Eventos emitidos cuando cambia un lead
+const (
+	LeadCreated       = "lead.created"
+	LeadScoreChanged  = "lead.score_changed"
+	LeadStatusChanged = "lead.status_changed"
+	LeadAssigned      = "lead.assigned"
+)
+
+// LeadListener recibe una copia del lead después de cada cambio. Se llama de forma
+// síncrona, así que debe ser rápido (encolar, no llamar a servicios externos).
+type LeadListener func(event string, lead models.Lead)
+
+// OnLeadChange registra un listener de cambios de leads
+func (s *SessionService) OnLeadChange(listener LeadListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// emitLeadEvent notifica a los listeners. No debe llamarse con el lock tomado.
+func (s *SessionService) emitLeadEvent(event string, lead *models.Lead) {
+	s.mu.RLock()
+	snapshot := *lead
+	listeners := s.listeners
+	s.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event, snapshot)
+	}
+}
+
+// emitLeadEventLocked es emitLeadEvent para cuando el lock ya está tomado
+func (s *SessionService) emitLeadEventLocked(event string, lead *models.Lead) {
+	snapshot := *lead
+	for _, listener := range s.listeners {
+		listener(event, snapshot)
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formatos de exportación de leads
+const (
+	ExportCSV    = "csv"
+	ExportXLSX   = "xlsx"
+	ExportNDJSON = "ndjson"
+)
+
+// ExportContentTypes mapea cada formato a su Content-Type
+var ExportContentTypes = map[string]string{
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportNDJSON: "application/x-ndjson",
+}
+
+// ScoringDimensions son las 7 dimensiones del ScoringAgent, en el orden del modelo de scoring
+var ScoringDimensions = []struct{ Key, Label string }{
+	{"perfil_demografico", "Perfil demográfico"},
+	{"comportamiento_digital", "Comportamiento digital"},
+	{"capacidad_financiera", "Capacidad financiera"},
+	{"necesidad_urgencia", "Necesidad y urgencia"},
+	{"experiencia_previa", "Experiencia previa"},
+	{"engagement_actual", "Engagement actual"},
+	{"contexto_compra", "Contexto de compra"},
+}
+
+// Datos de contacto: mismas columnas que datos_ficticios_completo_datos_de_leads.xlsx.
+// Se leen de la metadata del lead o de su sesión y, si se pidieron, de los datos que dio en la conversación.
+var leadContactColumns = []struct{ Key, Label string }{
+	{"nombres", "Nombres"},
+	{"apellidos", "Apellidos"},
+	{"dni", "DNI"},
+	{"telefono", "Teléfono"},
+	{"email", "Correo Electrónico"},
+	{"ciudad", "Ciudad"},
+}
+
+var leadExportColumns = []string{
+	"Session ID", "Canal", "Score", "Categoría", "Etapa", "Estado", "Asignado a",
+	"Lotes", "Motivo de pérdida", "Presupuesto", "Urgencia", "Último mensaje", "Creado", "Actualizado",
+}
+
+// leadExportRecord es una línea del NDJSON: el lead completo más su contacto
+type leadExportRecord struct {
+	*models.Lead
+	Contact map[string]string `json:"contact,omitempty"`
+}
+
+// ParseExportDate acepta AAAA-MM-DD o RFC3339. Con endOfDay una fecha sin hora
+// incluye el día completo (devuelve el inicio del día siguiente).
+func ParseExportDate(value string, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fecha inválida: %s (formato AAAA-MM-DD o RFC3339)", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// ExportLeads escribe los leads que cumplen el filtro en el formato pedido, del más antiguo
+// al más reciente, y devuelve cuántos exportó. contacts son los datos de contacto extraídos
+// de las conversaciones (PIIService.Contacts); nil los deja fuera.
+func (s *SessionService) ExportLeads(w io.Writer, format string, filter models.LeadFilter, contacts map[string]*models.LeadContact) (int, error) {
+	if _, ok := ExportContentTypes[format]; !ok {
+		return 0, fmt.Errorf("formato inválido: %s (válidos: csv, xlsx, ndjson)", format)
+	}
+
+	leads := s.GetAllLeads(filter)
+	sort.Slice(leads, func(i, j int) bool {
+		return leads[i].CreatedAt.Before(leads[j].CreatedAt)
+	})
+
+	// Copias bajo lock: el scoring puede modificar los leads mientras se escribe
+	s.mu.RLock()
+	records := make([]leadExportRecord, len(leads))
+	for i, lead := range leads {
+		copied := *lead
+		records[i] = leadExportRecord{Lead: &copied, Contact: s.leadContact(lead, contacts[lead.SessionID], contacts != nil)}
+	}
+	s.mu.RUnlock()
+
+	switch format {
+	case ExportNDJSON:
+		return exportNDJSON(w, records)
+	case ExportXLSX:
+		return exportXLSX(w, records)
+	default:
+		return exportCSV(w, records)
+	}
+}
+
+// leadContact junta los datos de contacto conocidos. En WhatsApp el teléfono sale del JID de la
+// sesión, pero solo con piiAccess: sin la clave PII_ACCESS_KEY el teléfono no se exporta.
+func (s *SessionService) leadContact(lead *models.Lead, extracted *models.LeadContact, piiAccess bool) map[string]string {
+	contact := make(map[string]string)
+	session := s.sessions[lead.SessionID]
+
+	for _, col := range leadContactColumns {
+		if v := lead.Metadata[col.Key]; v != "" {
+			contact[col.Key] = v
+		} else if session != nil && session.Metadata[col.Key] != "" {
+			contact[col.Key] = session.Metadata[col.Key]
+		}
+	}
+
+	if extracted != nil {
+		for key, values := range map[string][]string{"dni": extracted.DNI, "telefono": extracted.Phones, "email": extracted.Emails} {
+			if contact[key] == "" && len(values) > 0 {
+				contact[key] = values[len(values)-1]
+			}
+		}
+	}
+
+	if piiAccess && contact["telefono"] == "" && strings.HasPrefix(lead.SessionID, "wa-") {
+		jid := strings.TrimPrefix(lead.SessionID, "wa-")
+		if at := strings.Index(jid, "@"); at > 0 {
+			jid = jid[:at]
+		}
+		contact["telefono"] = jid
+	}
+
+	if len(contact) == 0 {
+		return nil
+	}
+	return contact
+}
+
+func leadExportHeader() []string {
+	header := make([]string, 0, len(leadContactColumns)+len(leadExportColumns)+len(ScoringDimensions))
+	for _, col := range leadContactColumns {
+		header = append(header, col.Label)
+	}
+	header = append(header, leadExportColumns...)
+	for _, dim := range ScoringDimensions {
+		header = append(header, dim.Label)
+	}
+	return header
+}
+
+// leadExportRow arma la fila tabular; los puntajes quedan como int para que la planilla los trate como números
+func leadExportRow(r leadExportRecord) []any {
+	row := make([]any, 0, len(leadContactColumns)+len(leadExportColumns)+len(ScoringDimensions))
+	for _, col := range leadContactColumns {
+		row = append(row, r.Contact[col.Key])
+	}
+
+	lead := r.Lead
+	row = append(row,
+		lead.SessionID,
+		lead.Channel,
+		lead.Score,
+		lead.Category,
+		lead.Stage,
+		lead.Status,
+		lead.AssignedTo,
+		strings.Join(lead.VehicleLots, ";"),
+		lead.LossReason,
+		lead.Budget,
+		lead.Urgency,
+		lead.LastMessage,
+		lead.CreatedAt.Format(time.RFC3339),
+		lead.UpdatedAt.Format(time.RFC3339),
+	)
+
+	for _, dim := range ScoringDimensions {
+		if score, ok := lead.Dimensions[dim.Key]; ok {
+			row = append(row, score)
+		} else {
+			row = append(row, "")
+		}
+	}
+	return row
+}
+
+func exportCSV(w io.Writer, records []leadExportRecord) (int, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(leadExportHeader()); err != nil {
+		return 0, err
+	}
+
+	for i, r := range records {
+		row := leadExportRow(r)
+		line := make([]string, len(row))
+		for j, cell := range row {
+			if text, ok := cell.(string); ok {
+				line[j] = csvSafeText(text)
+			} else {
+				line[j] = fmt.Sprint(cell)
+			}
+		}
+		if err := cw.Write(line); err != nil {
+			return i, err
+		}
+	}
+
+	cw.Flush()
+	return len(records), cw.Error()
+}
+
+// csvSafeText evita que Excel o Sheets interpreten como fórmula un texto escrito por el
+// usuario (ej. "=HYPERLINK(...)" en el último mensaje): lo antepone con una comilla simple
+func csvSafeText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// exportXLSX no necesita escapar fórmulas: los textos van como inlineStr y nunca se evalúan
+func exportXLSX(w io.Writer, records []leadExportRecord) (int, error) {
+	xw, err := newXLSXWriter(w, "Leads")
+	if err != nil {
+		return 0, err
+	}
+
+	header := leadExportHeader()
+	cells := make([]any, len(header))
+	for i, h := range header {
+		cells[i] = h
+	}
+	if err := xw.WriteRow(cells, true); err != nil {
+		return 0, err
+	}
+
+	for i, r := range records {
+		if err := xw.WriteRow(leadExportRow(r), false); err != nil {
+			return i, err
+		}
+	}
+
+	return len(records), xw.Close()
+}
+
+func exportNDJSON(w io.Writer, records []leadExportRecord) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	for i, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return i, err
+		}
+	}
+
+	return len(records), bw.Flush()
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"testing"
+)
+
+func TestLeadContactPhoneFromJIDNeedsPIIAccess(t *testing.T) {
+	s := &SessionService{sessions: make(map[string]*models.Session)}
+	lead := &models.Lead{SessionID: "wa-51987654321@s.whatsapp.net"}
+
+	if contact := s.leadContact(lead, nil, false); contact["telefono"] != "" {
+		t.Errorf("sin clave de PII no debe exportarse el teléfono: %v", contact)
+	}
+	if contact := s.leadContact(lead, nil, true); contact["telefono"] != "51987654321" {
+		t.Errorf("con clave de PII el teléfono sale del JID: %v", contact)
+	}
+}
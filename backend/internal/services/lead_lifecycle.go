@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Estados del ciclo de vida comercial de un lead
+const (
+	LeadStatusNew        = "new"
+	LeadStatusContacted  = "contacted"
+	LeadStatusQualified  = "qualified"
+	LeadStatusBidding    = "bidding"
+	LeadStatusWon        = "won"
+	LeadStatusLost       = "lost"
+	LeadStatusNoResponse = "no_response"
+)
+
+// LeadStatuses lista los estados en el orden del pipeline de ventas
+var LeadStatuses = []string{
+	LeadStatusNew,
+	LeadStatusContacted,
+	LeadStatusQualified,
+	LeadStatusBidding,
+	LeadStatusWon,
+	LeadStatusLost,
+	LeadStatusNoResponse,
+}
+
+// isFinalLeadStatus indica si el estado cierra la conversación del embudo
+func isFinalLeadStatus(status string) bool {
+	return status == LeadStatusWon || status == LeadStatusLost || status == LeadStatusNoResponse
+}
+
+func validLeadStatus(status string) bool {
+	for _, s := range LeadStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateLeadLifecycle aplica un cambio de estado, motivo de pérdida, lotes o nota,
+// dejando cada cambio en el audit trail del lead. Los estados finales cierran el embudo.
+func (s *SessionService) UpdateLeadLifecycle(sessionID string, req models.LeadUpdateRequest) (*models.Lead, error) {
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	if status != "" && !validLeadStatus(status) {
+		return nil, fmt.Errorf("estado inválido: %s (válidos: %s)", req.Status, strings.Join(LeadStatuses, ", "))
+	}
+
+	s.mu.Lock()
+	lead, exists := s.leads[sessionID]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("lead no encontrado: %s", sessionID)
+	}
+
+	lossReason := strings.TrimSpace(req.LossReason)
+	if status == LeadStatusLost && lossReason == "" && lead.LossReason == "" {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("lossReason es requerido para marcar un lead como lost")
+	}
+
+	now := time.Now()
+	audit := func(field, from, to string) {
+		lead.Audit = append(lead.Audit, models.LeadAuditEntry{
+			Actor: req.Actor,
+			Field: field,
+			From:  from,
+			To:    to,
+			At:    now,
+		})
+	}
+
+	closing := false
+	if status == lead.Status {
+		status = ""
+	}
+	if status != "" {
+		audit("status", lead.Status, status)
+		lead.Status = status
+		if lead.StatusTimestamps == nil {
+			lead.StatusTimestamps = make(map[string]time.Time)
+		}
+		if _, seen := lead.StatusTimestamps[status]; !seen {
+			lead.StatusTimestamps[status] = now
+		}
+		closing = isFinalLeadStatus(status)
+	}
+	if lossReason != "" && lossReason != lead.LossReason {
+		audit("lossReason", lead.LossReason, lossReason)
+		lead.LossReason = lossReason
+	}
+	if len(req.VehicleLots) > 0 {
+		from := strings.Join(lead.VehicleLots, ",")
+		lead.VehicleLots = mergeLots(lead.VehicleLots, req.VehicleLots)
+		if to := strings.Join(lead.VehicleLots, ","); to != from {
+			audit("vehicleLots", from, to)
+		}
+	}
+	if note := strings.TrimSpace(req.Note); note != "" {
+		lead.Notes = append(lead.Notes, models.LeadNote{Author: req.Actor, Text: note, At: now})
+		audit("note", "", note)
+	}
+
+	lead.UpdatedAt = now
+	s.saveToDisk()
+	s.mu.Unlock()
+
+	if closing {
+		s.UpdateStage(sessionID, StageClosed, "status:"+status)
+	}
+	if status != "" {
+		s.emitLeadEvent(LeadStatusChanged, lead)
+	}
+
+	log.Printf("📋 Lead %s actualizado por %s (estado: %s)", sessionID, req.Actor, lead.Status)
+	return lead, nil
+}
+
+// mergeLots agrega lotes sin duplicar, conservando el orden
+func mergeLots(current, added []string) []string {
+	seen := make(map[string]bool, len(current))
+	for _, lot := range current {
+		seen[lot] = true
+	}
+	for _, lot := range added {
+		lot = strings.TrimSpace(lot)
+		if lot != "" && !seen[lot] {
+			current = append(current, lot)
+			seen[lot] = true
+		}
+	}
+	return current
+}
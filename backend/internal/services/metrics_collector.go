@@ -0,0 +1,92 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SessionMetricsCollector calcula en cada scrape de /metrics las sesiones activas y los
+// leads por categoría a partir de los datos en memoria, sin contadores que mantener
+type SessionMetricsCollector struct {
+	sessionService *SessionService
+	activeWindow   time.Duration
+	sessions       *prometheus.Desc
+	leads          *prometheus.Desc
+}
+
+func NewSessionMetricsCollector() *SessionMetricsCollector {
+	minutes := config.AppConfig.MetricsActiveSessionMinutes
+	if minutes <= 0 {
+		minutes = 30
+	}
+
+	return &SessionMetricsCollector{
+		sessionService: GetSessionService(),
+		activeWindow:   time.Duration(minutes) * time.Minute,
+		sessions: prometheus.NewDesc(
+			"bob_sessions",
+			"Sesiones de chat por estado: total, active (con mensajes recientes) y human (atendidas por un especialista).",
+			[]string{"state"}, nil,
+		),
+		leads: prometheus.NewDesc(
+			"bob_leads",
+			"Leads por categoría del scoring.",
+			[]string{"category"}, nil,
+		),
+	}
+}
+
+func (c *SessionMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sessions
+	ch <- c.leads
+}
+
+func (c *SessionMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	total, active, human := c.sessionService.sessionCounts(time.Now().Add(-c.activeWindow))
+	ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(total), "total")
+	ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(active), "active")
+	ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(human), "human")
+
+	for category, count := range c.sessionService.leadsByCategory() {
+		ch <- prometheus.MustNewConstMetric(c.leads, prometheus.GaugeValue, float64(count), category)
+	}
+}
+
+// sessionCounts cuenta todas las sesiones, las que tuvieron actividad desde activeSince
+// y las que están en modo humano
+func (s *SessionService) sessionCounts(activeSince time.Time) (total, active, human int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		total++
+		if session.UpdatedAt.After(activeSince) {
+			active++
+		}
+		if session.IsHumanMode() {
+			human++
+		}
+	}
+	return total, active, human
+}
+
+// leadsByCategory cuenta los leads de cada categoría; siempre incluye las cuatro conocidas
+func (s *SessionService) leadsByCategory() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := emptyCategoryCounts()
+	for _, lead := range s.leads {
+		if lead.Category == "" {
+			continue
+		}
+		if _, ok := counts[lead.Category]; ok {
+			counts[lead.Category]++
+		} else {
+			counts["other"]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bob-hackathon/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notifier avisa a los especialistas de una escalación
+type Notifier interface {
+	Name() string
+	Notify(esc *models.Escalation) error
+}
+
+// logNotifier solo deja la escalación en el log del servidor
+type logNotifier struct{}
+
+func (logNotifier) Name() string { return "log" }
+
+func (logNotifier) Notify(esc *models.Escalation) error {
+	log.Printf("🚨 Escalación %s [%s] sesión %s: %s (SLA hasta %s)",
+		esc.ID, esc.Priority, esc.SessionID, RedactPII(esc.Reason), esc.Deadline.Format(time.RFC3339))
+	return nil
+}
+
+// webhookNotifier envía la escalación como JSON a una URL (Slack, n8n, CRM, etc.)
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookNotifier) Name() string { return "webhook" }
+
+func (w *webhookNotifier) Notify(esc *models.Escalation) error {
+	if w.url == "" {
+		return fmt.Errorf("ESCALATION_WEBHOOK_URL no configurado")
+	}
+	redacted := *esc
+	redacted.Reason = RedactPII(esc.Reason)
+	return postJSON(w.client, w.url, map[string]any{
+		"event":      "escalation",
+		"escalation": &redacted,
+	})
+}
+
+// emailNotifier es un stub: arma el correo y lo deja en el log hasta tener proveedor SMTP
+type emailNotifier struct {
+	to string
+}
+
+func (e *emailNotifier) Name() string { return "email" }
+
+func (e *emailNotifier) Notify(esc *models.Escalation) error {
+	if e.to == "" {
+		return fmt.Errorf("ESCALATION_EMAIL_TO no configurado")
+	}
+	log.Printf("📧 [email stub] Para: %s | Asunto: Lead %s requiere atención (%s) | %s",
+		e.to, esc.SessionID, esc.Priority, RedactPII(esc.Reason))
+	return nil
+}
+
+// whatsAppGroupNotifier publica la escalación en un grupo de WhatsApp de especialistas
+type whatsAppGroupNotifier struct {
+	group  string
+	sender *whatsAppSender
+}
+
+func (w *whatsAppGroupNotifier) Name() string { return "whatsapp_group" }
+
+func (w *whatsAppGroupNotifier) Notify(esc *models.Escalation) error {
+	if w.group == "" {
+		return fmt.Errorf("ESCALATION_WHATSAPP_GROUP no configurado")
+	}
+	text := fmt.Sprintf("🚨 *Lead para atender* (%s)\n%s\nSesión: %s (%s)\nAtender antes de: %s",
+		esc.Priority, RedactPII(esc.Reason), esc.SessionID, esc.Channel, esc.Deadline.Format("15:04"))
+
+	return w.sender.sendTo(w.group, text)
+}
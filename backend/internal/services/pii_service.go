@@ -0,0 +1,461 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/telemetry"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Modos de tratamiento de datos personales (PII_MODE)
+const (
+	PIIRedact   = "redact"   // [telefono]
+	PIITokenize = "tokenize" // [telefono:3f9a1c2e], el mismo valor siempre da el mismo token
+	PIIOff      = "off"
+)
+
+// Tipos de dato personal
+const (
+	PIIEmail = "email"
+	PIIPhone = "telefono"
+	PIIDNI   = "dni"
+	PIIRUC   = "ruc"
+	PIIPlate = "placa"
+	PIICard  = "tarjeta"
+)
+
+// maxContactValues es la cantidad máxima de valores de cada tipo que se guardan por lead
+const maxContactValues = 5
+
+var piiEmailRegex = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// piiDetectors van en orden de prioridad: un texto ya tomado por un tipo no se evalúa con
+// los siguientes (un RUC no es además un DNI, un teléfono con +51 no es un número suelto)
+var piiDetectors = []struct {
+	kind  string
+	regex *regexp.Regexp
+}{
+	{PIIEmail, piiEmailRegex},
+	{PIICard, regexp.MustCompile(`\b(?:\d{4}[ -]?){3}\d{1,7}\b|\b\d{13,19}\b`)},
+	{PIIRUC, regexp.MustCompile(`\b(?:10|15|16|17|20)\d{9}\b`)},
+	{PIIPhone, regexp.MustCompile(`(?:\+51[\s.-]?|\b51[\s.-]?|\b)9\d{2}[\s.-]?\d{3}[\s.-]?\d{3}\b|\+51[\s.-]?\(?0?1\)?[\s.-]?\d{3}[\s.-]?\d{4}\b|\(0\d{1,2}\)[\s.-]?\d{3}[\s.-]?\d{3,4}\b`)},
+	{PIIDNI, regexp.MustCompile(`\b\d{8}\b`)},
+	{PIIPlate, regexp.MustCompile(`(?i)\b[a-z][a-z0-9]{2}-?\d{3}\b`)},
+}
+
+// Prefijos que parecen placa pero son montos o medidas ("usd500", "km100")
+var notPlatePrefixes = []string{"usd", "pen", "sol", "eur", "kms"}
+
+var (
+	piiDigits     = regexp.MustCompile(`\D`)
+	piiTokenRegex = regexp.MustCompile(`\[(email|telefono|dni|ruc|placa|tarjeta):[0-9a-f]{8}\]`)
+)
+
+// PIIService detecta datos personales de Perú (DNI, RUC, teléfonos +51, emails, placas,
+// tarjetas) para redactarlos o reemplazarlos por tokens en las transcripciones guardadas,
+// los logs y los webhooks. Los datos de contacto que da el prospecto se guardan aparte en
+// data/lead_contacts.json y solo se leen con PII_ACCESS_KEY.
+type PIIService struct {
+	mode     string
+	secret   []byte
+	contacts map[string]*models.LeadContact
+	dataFile string
+	mu       sync.RWMutex
+}
+
+var piiServiceInstance *PIIService
+var piiServiceOnce sync.Once
+
+func GetPIIService() *PIIService {
+	piiServiceOnce.Do(func() {
+		mode := strings.ToLower(strings.TrimSpace(config.AppConfig.PIIMode))
+		switch mode {
+		case PIIRedact, PIITokenize, PIIOff:
+		default:
+			log.Printf("⚠️ PII_MODE desconocido (%s), se usa redact", mode)
+			mode = PIIRedact
+		}
+		if mode == PIITokenize && config.AppConfig.PIITokenSecret == "" {
+			log.Printf("⚠️ PII_MODE=tokenize sin PII_TOKEN_SECRET, se usa redact")
+			mode = PIIRedact
+		}
+
+		piiServiceInstance = &PIIService{
+			mode:     mode,
+			secret:   []byte(config.AppConfig.PIITokenSecret),
+			contacts: make(map[string]*models.LeadContact),
+			dataFile: filepath.Join("data", "lead_contacts.json"),
+		}
+		piiServiceInstance.loadFromDisk()
+	})
+	return piiServiceInstance
+}
+
+// Mode devuelve el modo vigente
+func (p *PIIService) Mode() string {
+	return p.mode
+}
+
+// DetectPII devuelve los datos personales del texto, en orden de aparición y sin superponerse.
+// Los tokens de PII_MODE=tokenize no cuentan, así redactar dos veces no cambia el texto.
+func DetectPII(text string) []models.PIIMatch {
+	var matches []models.PIIMatch
+	tokens := piiTokenRegex.FindAllStringIndex(text, -1)
+	taken := func(start, end int) bool {
+		for _, m := range matches {
+			if start < m.End && end > m.Start {
+				return true
+			}
+		}
+		for _, loc := range tokens {
+			if start < loc[1] && end > loc[0] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, detector := range piiDetectors {
+		for _, loc := range detector.regex.FindAllStringIndex(text, -1) {
+			value := text[loc[0]:loc[1]]
+			if taken(loc[0], loc[1]) || !validPII(detector.kind, value) {
+				continue
+			}
+			matches = append(matches, models.PIIMatch{Type: detector.kind, Value: value, Start: loc[0], End: loc[1]})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+// validPII descarta coincidencias que solo se parecen al formato
+func validPII(kind, value string) bool {
+	switch kind {
+	case PIICard:
+		return luhnValid(piiDigits.ReplaceAllString(value, ""))
+	case PIIPlate:
+		lower := strings.ToLower(value)
+		for _, prefix := range notPlatePrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func luhnValid(digits string) bool {
+	if len(digits) < 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// RedactPII reemplaza los datos personales por su tipo ([dni], [telefono]) sin importar
+// PII_MODE. Es lo que usan los logs y las trazas redactadas.
+func RedactPII(text string) string {
+	return replacePII(text, func(m models.PIIMatch) string { return "[" + m.Type + "]" })
+}
+
+// untokenize pasa los tokens ([dni:3f9a1c2e]) a su tipo ([dni]) para comparar textos
+// guardados con PII_MODE=tokenize contra textos redactados
+func untokenize(text string) string {
+	return piiTokenRegex.ReplaceAllString(text, "[$1]")
+}
+
+// Apply trata el texto según PII_MODE: redactado, con tokens o sin cambios
+func (p *PIIService) Apply(text string) string {
+	switch p.mode {
+	case PIIOff:
+		return text
+	case PIITokenize:
+		return replacePII(text, func(m models.PIIMatch) string { return "[" + m.Type + ":" + p.token(m) + "]" })
+	}
+	return RedactPII(text)
+}
+
+// token es un HMAC corto del valor normalizado: permite ver que dos mensajes tienen el mismo
+// teléfono sin guardar el teléfono
+func (p *PIIService) token(m models.PIIMatch) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(m.Type + ":" + normalizePII(m.Type, m.Value)))
+	return hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+func replacePII(text string, replacement func(models.PIIMatch) string) string {
+	matches := DetectPII(text)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(replacement(m))
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// normalizePII deja el valor en un formato único: teléfonos como +51987654321, placas en
+// mayúsculas con guion, emails en minúsculas
+func normalizePII(kind, value string) string {
+	switch kind {
+	case PIIEmail:
+		return strings.ToLower(value)
+	case PIIPhone:
+		digits := piiDigits.ReplaceAllString(value, "")
+		if len(digits) > 9 {
+			digits = strings.TrimPrefix(digits, "51")
+		}
+		return "+51" + strings.TrimPrefix(digits, "0")
+	case PIIPlate:
+		plate := strings.ToUpper(strings.ReplaceAll(value, "-", ""))
+		return plate[:3] + "-" + plate[3:]
+	}
+	return piiDigits.ReplaceAllString(value, "")
+}
+
+// ProcessMessage guarda los datos de contacto del mensaje en el lead y devuelve el texto
+// tratado según PII_MODE. Indica si se encontró algún dato de contacto nuevo.
+func (p *PIIService) ProcessMessage(sessionID, message string) (string, bool) {
+	matches := DetectPII(message)
+	if len(matches) == 0 {
+		return message, false
+	}
+	for _, m := range matches {
+		telemetry.CountPII(m.Type)
+	}
+
+	return p.Apply(message), p.saveContact(sessionID, matches)
+}
+
+func (p *PIIService) saveContact(sessionID string, matches []models.PIIMatch) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	contact, ok := p.contacts[sessionID]
+	if !ok {
+		contact = &models.LeadContact{SessionID: sessionID}
+	}
+
+	added := false
+	for _, m := range matches {
+		var field *[]string
+		switch m.Type {
+		case PIIEmail:
+			field = &contact.Emails
+		case PIIPhone:
+			field = &contact.Phones
+		case PIIDNI:
+			field = &contact.DNI
+		case PIIRUC:
+			field = &contact.RUC
+		case PIIPlate:
+			field = &contact.Plates
+		default:
+			// Las tarjetas solo se redactan, nunca se guardan
+			continue
+		}
+
+		value := normalizePII(m.Type, m.Value)
+		if containsString(*field, value) {
+			continue
+		}
+		*field = append(*field, value)
+		if len(*field) > maxContactValues {
+			*field = (*field)[len(*field)-maxContactValues:]
+		}
+		added = true
+	}
+	if !added {
+		return false
+	}
+
+	contact.UpdatedAt = time.Now()
+	p.contacts[sessionID] = contact
+	p.saveToDisk()
+	return true
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContact devuelve los datos de contacto de la sesión, o nil si no dio ninguno
+func (p *PIIService) GetContact(sessionID string) *models.LeadContact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	contact, ok := p.contacts[sessionID]
+	if !ok {
+		return nil
+	}
+	copied := *contact
+	return &copied
+}
+
+// Contacts devuelve una copia de todos los datos de contacto, por sesión
+func (p *PIIService) Contacts() map[string]*models.LeadContact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	contacts := make(map[string]*models.LeadContact, len(p.contacts))
+	for sessionID, contact := range p.contacts {
+		copied := *contact
+		contacts[sessionID] = &copied
+	}
+	return contacts
+}
+
+// HasContact indica si la sesión tiene datos de contacto guardados
+func (p *PIIService) HasContact(sessionID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	_, ok := p.contacts[sessionID]
+	return ok
+}
+
+// DeleteContact borra los datos de contacto de la sesión
+func (p *PIIService) DeleteContact(sessionID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.contacts[sessionID]; !ok {
+		return false
+	}
+	delete(p.contacts, sessionID)
+	p.saveToDisk()
+	return true
+}
+
+// CanAccess valida la clave para leer datos de contacto; sin PII_ACCESS_KEY nadie puede
+func (p *PIIService) CanAccess(key string) bool {
+	expected := config.AppConfig.PIIAccessKey
+	return expected != "" && hmac.Equal([]byte(key), []byte(expected))
+}
+
+func (p *PIIService) loadFromDisk() {
+	data, err := os.ReadFile(p.dataFile)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal(data, &p.contacts); err != nil {
+		log.Printf("Error al cargar datos de contacto: %v", err)
+		return
+	}
+	log.Printf("%d contactos de leads cargados desde disco", len(p.contacts))
+}
+
+func (p *PIIService) saveToDisk() {
+	if data, err := json.MarshalIndent(p.contacts, "", "  "); err == nil {
+		// Solo el usuario del servidor puede leer el archivo
+		if err := os.WriteFile(p.dataFile, data, 0600); err != nil {
+			log.Printf("Error al guardar datos de contacto: %v", err)
+		}
+	}
+}
+
+// RedactStoredSessions aplica PII_MODE a las sesiones y leads guardados antes de que se
+// redactaran los mensajes, y guarda los datos de contacto que tenían. Se corre al iniciar;
+// con todo ya redactado no encuentra nada y no reescribe los archivos.
+func (p *PIIService) RedactStoredSessions(s *SessionService) {
+	if p.mode == PIIOff {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	redacted := 0
+	for sessionID, session := range s.sessions {
+		for i := range session.Messages {
+			message := &session.Messages[i]
+			matches := DetectPII(message.Content)
+			if len(matches) == 0 {
+				continue
+			}
+			if message.Role == "user" && p.saveContact(sessionID, matches) {
+				if lead, ok := s.leads[sessionID]; ok {
+					lead.HasContact = true
+				}
+			}
+			message.Content = p.Apply(message.Content)
+			redacted++
+		}
+	}
+	for _, lead := range s.leads {
+		if len(DetectPII(lead.LastMessage)) > 0 {
+			lead.LastMessage = p.Apply(lead.LastMessage)
+			redacted++
+		}
+	}
+
+	if redacted > 0 {
+		s.saveToDisk()
+		log.Printf("🔒 %d mensajes guardados con datos personales quedaron redactados (PII_MODE=%s)", redacted, p.mode)
+	}
+}
+
+// SetLeadContact marca si el lead tiene datos de contacto guardados, si el lead ya existe
+func (s *SessionService) SetLeadContact(sessionID string, has bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lead, ok := s.leads[sessionID]
+	if !ok || lead.HasContact == has {
+		return
+	}
+	lead.HasContact = has
+	s.saveToDisk()
+}
+
+// AuditContactAccess deja en la auditoría del lead quién leyó o borró sus datos de contacto
+func (s *SessionService) AuditContactAccess(sessionID, actor, action string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("🔐 %s: datos de contacto de %s (%s)", actor, sessionID, action)
+	lead, ok := s.leads[sessionID]
+	if !ok {
+		return
+	}
+	lead.Audit = append(lead.Audit, models.LeadAuditEntry{
+		Actor: actor,
+		Field: "contact",
+		To:    action,
+		At:    time.Now(),
+	})
+	s.saveToDisk()
+}
@@ -0,0 +1,44 @@
+package services
+
+import "testing"
+
+func TestRedactPIIFormats(t *testing.T) {
+	cases := []struct {
+		name, text, want string
+	}{
+		{"celular", "llamame al 987654321", "llamame al [telefono]"},
+		{"celular con espacios", "mi cel 987 654 321", "mi cel [telefono]"},
+		{"celular con guiones", "mi cel 987-654-321.", "mi cel [telefono]."},
+		{"+51 con espacio", "escribe al +51 987654321", "escribe al [telefono]"},
+		{"+51 pegado (E.164)", "escribe al +51987654321", "escribe al [telefono]"},
+		{"51 pegado (whatsapp)", "mi numero es 51987654321", "mi numero es [telefono]"},
+		{"+51 con guiones", "+51-987-654-321", "[telefono]"},
+		{"fijo de lima con +51", "oficina +51 1 234 5678", "oficina [telefono]"},
+		{"fijo con codigo de ciudad", "fijo (01) 234-5678 o (044) 123-456", "fijo [telefono] o [telefono]"},
+		{"email", "correo Ana.Perez@Mail.com gracias", "correo [email] gracias"},
+		{"dni", "mi dni es 12345678", "mi dni es [dni]"},
+		{"ruc", "ruc 20123456789", "ruc [ruc]"},
+		{"placa con guion", "la placa es ABC-123", "la placa es [placa]"},
+		{"placa sin guion", "placa a1b234", "placa [placa]"},
+		{"tarjeta", "pago con 4111 1111 1111 1111", "pago con [tarjeta]"},
+		{"tarjeta que no pasa luhn", "codigo 4111 1111 1111 1112", "codigo 4111 1111 1111 1112"},
+		{"monto que parece placa", "tengo usd500", "tengo usd500"},
+		{"numero mas largo que un celular", "pedido 1987654321", "pedido 1987654321"},
+		{"token ya redactado", "mi dni es [dni:3f9a1c2e]", "mi dni es [dni:3f9a1c2e]"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := RedactPII(c.text); got != c.want {
+				t.Errorf("RedactPII(%q) = %q, se esperaba %q", c.text, got, c.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	for _, value := range []string{"987654321", "987 654 321", "+51 987654321", "+51987654321", "51987654321", "+51-987-654-321"} {
+		if got := normalizePII(PIIPhone, value); got != "+51987654321" {
+			t.Errorf("normalizePII(%q) = %q", value, got)
+		}
+	}
+}
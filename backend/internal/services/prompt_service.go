@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultPromptChannel es la variante usada cuando un canal no tiene plantilla propia
+const DefaultPromptChannel = "default"
+
+// PromptTemplate es una plantilla de prompt cargada desde disco
+type PromptTemplate struct {
+	Name    string `json:"name"`
+	Channel string `json:"channel"`
+	Version string `json:"version"`
+	File    string `json:"file"`
+	tmpl    *template.Template
+}
+
+// Ref devuelve la referencia que se guarda en el trace de cada respuesta
+func (p *PromptTemplate) Ref() models.PromptRef {
+	return models.PromptRef{
+		Name:    p.Name,
+		Channel: p.Channel,
+		Version: p.Version,
+	}
+}
+
+type PromptService struct {
+	dir       string
+	templates map[string]map[string]*PromptTemplate // name -> channel -> plantilla
+	mu        sync.RWMutex
+}
+
+var promptServiceInstance *PromptService
+var promptServiceOnce sync.Once
+
+// La versión se declara en la primera línea de la plantilla: {{/* version: 1.0.0 */}}
+var promptVersionRegex = regexp.MustCompile(`\{\{/\*\s*version:\s*([^\s*]+)\s*\*/\}\}`)
+
+var promptFuncs = template.FuncMap{
+	"inc":   func(i int) int { return i + 1 },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+func GetPromptService() *PromptService {
+	promptServiceOnce.Do(func() {
+		promptServiceInstance = &PromptService{
+			dir:       config.AppConfig.PromptsDir,
+			templates: make(map[string]map[string]*PromptTemplate),
+		}
+		if err := promptServiceInstance.Reload(); err != nil {
+			log.Fatalf("❌ Error cargando plantillas de prompts: %v", err)
+		}
+	})
+	return promptServiceInstance
+}
+
+// Reload vuelve a leer todas las plantillas del directorio.
+// Los archivos siguen el formato <nombre>.tmpl o <nombre>.<canal>.tmpl;
+// los que empiezan con "_" son bloques compartidos ({{define}}) disponibles en todas.
+func (p *PromptService) Reload() error {
+	files, err := filepath.Glob(filepath.Join(p.dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+
+	partials := make(map[string]string)
+	var mainFiles []string
+	for _, file := range files {
+		base := filepath.Base(file)
+		if !strings.HasPrefix(base, "_") {
+			mainFiles = append(mainFiles, file)
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error al leer plantilla %s: %w", file, err)
+		}
+		partials[base] = string(content)
+	}
+
+	templates := make(map[string]map[string]*PromptTemplate)
+	for _, file := range mainFiles {
+		pt, err := parsePromptFile(file, partials)
+		if err != nil {
+			return err
+		}
+		if templates[pt.Name] == nil {
+			templates[pt.Name] = make(map[string]*PromptTemplate)
+		}
+		templates[pt.Name][pt.Channel] = pt
+	}
+
+	p.mu.Lock()
+	p.templates = templates
+	p.mu.Unlock()
+
+	log.Printf("%d plantillas de prompts cargadas desde %s", len(mainFiles), p.dir)
+	return nil
+}
+
+func parsePromptFile(file string, partials map[string]string) (*PromptTemplate, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer plantilla %s: %w", file, err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+	name, channel := base, DefaultPromptChannel
+	if idx := strings.Index(base, "."); idx != -1 {
+		name, channel = base[:idx], base[idx+1:]
+	}
+
+	version := "0"
+	if match := promptVersionRegex.FindSubmatch(content); match != nil {
+		version = string(match[1])
+	}
+
+	tmpl, err := template.New(base).Funcs(promptFuncs).Option("missingkey=zero").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("error al parsear plantilla %s: %w", file, err)
+	}
+	for partialName, partial := range partials {
+		if _, err := tmpl.New(partialName).Parse(partial); err != nil {
+			return nil, fmt.Errorf("error al parsear plantilla %s: %w", partialName, err)
+		}
+	}
+
+	return &PromptTemplate{
+		Name:    name,
+		Channel: channel,
+		Version: version,
+		File:    filepath.Base(file),
+		tmpl:    tmpl,
+	}, nil
+}
+
+// Get obtiene la variante del canal o, si no existe, la variante por defecto
+func (p *PromptService) Get(name, channel string) (*PromptTemplate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	variants, exists := p.templates[name]
+	if !exists {
+		return nil, fmt.Errorf("plantilla no encontrada: %s", name)
+	}
+
+	if pt, ok := variants[strings.ToLower(channel)]; ok {
+		return pt, nil
+	}
+	if pt, ok := variants[DefaultPromptChannel]; ok {
+		return pt, nil
+	}
+
+	return nil, fmt.Errorf("plantilla %s sin variante para canal %s", name, channel)
+}
+
+// Render ejecuta la plantilla con los datos dados y devuelve el prompt junto con su referencia
+func (p *PromptService) Render(name, channel string, data any) (string, models.PromptRef, error) {
+	pt, err := p.Get(name, channel)
+	if err != nil {
+		return "", models.PromptRef{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := pt.tmpl.Execute(&buf, data); err != nil {
+		return "", pt.Ref(), fmt.Errorf("error al renderizar plantilla %s: %w", name, err)
+	}
+
+	// Quitar el comentario de versión y espacios iniciales
+	return strings.TrimSpace(buf.String()), pt.Ref(), nil
+}
+
+// List devuelve todas las plantillas cargadas ordenadas por nombre y canal
+func (p *PromptService) List() []*PromptTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var result []*PromptTemplate
+	for _, variants := range p.templates {
+		for _, pt := range variants {
+			result = append(result, pt)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Channel < result[j].Channel
+	})
+
+	return result
+}
+
+// SampleData carga los datos de ejemplo de una plantilla desde samples/<nombre>.json
+func (p *PromptService) SampleData(name string) (map[string]any, error) {
+	data, err := os.ReadFile(filepath.Join(p.dir, "samples", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("sin datos de ejemplo para %s: %w", name, err)
+	}
+
+	var sample map[string]any
+	if err := json.Unmarshal(data, &sample); err != nil {
+		return nil, fmt.Errorf("datos de ejemplo inválidos para %s: %w", name, err)
+	}
+
+	return sample, nil
+}
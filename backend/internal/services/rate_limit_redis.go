@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTokenBucket hace la recarga y el consumo en una sola operación atómica en redis.
+// Devuelve {permitido, ms hasta el próximo token, tokens restantes}.
+const redisTokenBucket = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 't', 'ts')
+local tokens = tonumber(data[1]) or capacity
+local ts = tonumber(data[2]) or now
+if now > ts then
+  tokens = math.min(capacity, tokens + (now - ts) * rate)
+end
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 't', tostring(tokens), 'ts', tostring(math.max(now, ts)))
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate) + 1000)
+return {allowed, wait, math.floor(tokens)}
+`
+
+// redisRefund devuelve un token al bucket sin pasar la capacidad
+const redisRefund = `
+local capacity = tonumber(ARGV[1])
+local tokens = tonumber(redis.call('HGET', KEYS[1], 't'))
+if tokens then
+  redis.call('HSET', KEYS[1], 't', tostring(math.min(capacity, tokens + 1)))
+end
+return 1
+`
+
+// redisRateStore comparte los buckets entre instancias del backend. Habla RESP con una
+// conexión que se reabre si falla, sin depender de un cliente externo.
+type redisRateStore struct {
+	addr     string
+	password string
+	db       int
+	useTLS   bool
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// newRedisRateStore lee redis://[:password@]host:port/db (rediss:// para TLS)
+func newRedisRateStore(rawURL string) (*redisRateStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("esquema %q, se espera redis:// o rediss://", u.Scheme)
+	}
+
+	store := &redisRateStore{
+		addr:    u.Host,
+		useTLS:  u.Scheme == "rediss",
+		timeout: 500 * time.Millisecond,
+	}
+	if u.Port() == "" {
+		store.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if password, ok := u.User.Password(); ok {
+		store.password = password
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if store.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("base de datos inválida: %s", db)
+		}
+	}
+	return store, nil
+}
+
+func (r *redisRateStore) Name() string { return "redis" }
+
+func (r *redisRateStore) Take(key string, limit RateLimit, now time.Time) (RateLimitDecision, error) {
+	reply, err := r.do("EVAL", redisTokenBucket, "1", key,
+		strconv.Itoa(limit.Burst),
+		strconv.FormatFloat(limit.perMs(), 'f', -1, 64),
+		strconv.FormatInt(now.UnixMilli(), 10),
+	)
+	if err != nil {
+		return RateLimitDecision{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 3 {
+		return RateLimitDecision{}, fmt.Errorf("respuesta inesperada de redis: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	remaining, _ := values[2].(int64)
+
+	return RateLimitDecision{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+	}, nil
+}
+
+func (r *redisRateStore) Refund(key string, limit RateLimit) error {
+	_, err := r.do("EVAL", redisRefund, "1", key, strconv.Itoa(limit.Burst))
+	return err
+}
+
+// do envía un comando; ante un error de red cierra la conexión para reabrirla en el próximo
+func (r *redisRateStore) do(args ...string) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := r.command(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		r.conn.Close()
+		r.conn = nil
+	}
+	return reply, err
+}
+
+func (r *redisRateStore) connect() error {
+	dialer := &net.Dialer{Timeout: r.timeout}
+	var conn net.Conn
+	var err error
+	if r.useTLS {
+		host, _, _ := net.SplitHostPort(r.addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", r.addr)
+	}
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.reader = bufio.NewReader(conn)
+
+	if r.password != "" {
+		if _, err := r.command("AUTH", r.password); err != nil {
+			r.conn.Close()
+			r.conn = nil
+			return err
+		}
+	}
+	if r.db != 0 {
+		if _, err := r.command("SELECT", strconv.Itoa(r.db)); err != nil {
+			r.conn.Close()
+			r.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *redisRateStore) command(args ...string) (interface{}, error) {
+	r.conn.SetDeadline(time.Now().Add(r.timeout))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(r.reader)
+}
+
+// redisError es un error devuelto por redis (la conexión sigue sirviendo)
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readRESP lee una respuesta del protocolo de redis
+func readRESP(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("respuesta vacía de redis")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, count)
+		for i := range values {
+			// Un error dentro del arreglo no corta la lectura, para no desincronizar la conexión
+			value, err := readRESP(reader)
+			var redisErr redisError
+			if err != nil && !errors.As(err, &redisErr) {
+				return nil, err
+			}
+			if err != nil {
+				value = err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("respuesta de redis no reconocida: %q", line)
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/telemetry"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alcances del límite de mensajes
+const (
+	RateLimitSession = "session"
+	RateLimitIP      = "ip"
+	RateLimitKey     = "key" // API key o usuario autenticado
+)
+
+// RateLimit es un token bucket: Burst mensajes seguidos y PerMinute de recarga
+type RateLimit struct {
+	Burst     int
+	PerMinute float64
+}
+
+func (r RateLimit) active() bool {
+	return r.Burst > 0 && r.PerMinute > 0
+}
+
+// perMs es la recarga en tokens por milisegundo
+func (r RateLimit) perMs() float64 {
+	return r.PerMinute / 60000
+}
+
+// RateLimitDecision es el resultado de pedir un token
+type RateLimitDecision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // cuánto falta para el próximo token cuando no se permite
+}
+
+// RateLimitCheck es un bucket a cobrar: alcance e id (session id, IP o API key)
+type RateLimitCheck struct {
+	Scope string
+	ID    string
+}
+
+// RateLimitStore guarda los buckets. La implementación en memoria alcanza con una sola
+// instancia del backend; con varias, un store compartido (redis) hace que el límite valga
+// para todas. Take y Refund deben ser atómicos por key.
+type RateLimitStore interface {
+	Name() string
+	Take(key string, limit RateLimit, now time.Time) (RateLimitDecision, error)
+	Refund(key string, limit RateLimit) error
+}
+
+// takeToken recarga el bucket según el tiempo transcurrido y consume un token si hay
+func takeToken(tokens float64, elapsed time.Duration, limit RateLimit) (float64, RateLimitDecision) {
+	if elapsed > 0 {
+		// En milisegundos con fracción: truncar perdería la recarga de requests muy seguidos
+		tokens = math.Min(float64(limit.Burst), tokens+float64(elapsed)/float64(time.Millisecond)*limit.perMs())
+	}
+	if tokens >= 1 {
+		tokens--
+		return tokens, RateLimitDecision{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := math.Ceil((1 - tokens) / limit.perMs())
+	return tokens, RateLimitDecision{RetryAfter: time.Duration(wait) * time.Millisecond}
+}
+
+// RateLimitService limita los mensajes al chat (cada uno dispara varias llamadas al LLM)
+// con token buckets por sesión, por IP y por API key. Si el store falla deja pasar: es
+// preferible atender de más a cortar el chat.
+type RateLimitService struct {
+	enabled bool
+	store   RateLimitStore
+	limits  map[string]RateLimit
+
+	errMu      sync.Mutex
+	lastErrLog time.Time
+}
+
+var rateLimitServiceInstance *RateLimitService
+var rateLimitServiceOnce sync.Once
+
+func GetRateLimitService() *RateLimitService {
+	rateLimitServiceOnce.Do(func() {
+		cfg := config.AppConfig
+
+		var store RateLimitStore
+		switch strings.ToLower(strings.TrimSpace(cfg.RateLimitStore)) {
+		case "redis":
+			redisStore, err := newRedisRateStore(cfg.RateLimitRedisURL)
+			if err != nil {
+				log.Printf("⚠️ RATE_LIMIT_REDIS_URL inválida (%v), se usa memoria", err)
+				store = newMemoryRateStore()
+			} else {
+				store = redisStore
+			}
+		case "", "memory":
+			store = newMemoryRateStore()
+		default:
+			log.Printf("⚠️ RATE_LIMIT_STORE desconocido (%s), se usa memoria", cfg.RateLimitStore)
+			store = newMemoryRateStore()
+		}
+
+		rateLimitServiceInstance = &RateLimitService{
+			enabled: cfg.RateLimitEnabled,
+			store:   store,
+			limits: map[string]RateLimit{
+				RateLimitSession: {Burst: cfg.RateLimitSessionBurst, PerMinute: cfg.RateLimitSessionPerMinute},
+				RateLimitIP:      {Burst: cfg.RateLimitIPBurst, PerMinute: cfg.RateLimitIPPerMinute},
+				RateLimitKey:     {Burst: cfg.RateLimitKeyBurst, PerMinute: cfg.RateLimitKeyPerMinute},
+			},
+		}
+		if rateLimitServiceInstance.enabled {
+			log.Printf("🚦 Límite de mensajes activo (store: %s)", store.Name())
+		}
+	})
+	return rateLimitServiceInstance
+}
+
+// Enabled indica si se aplican los límites
+func (r *RateLimitService) Enabled() bool {
+	return r.enabled
+}
+
+// AllowAll cobra un token de cada bucket, en orden. Si alguno rechaza, devuelve los tokens
+// ya cobrados a los anteriores (un mensaje rechazado no gasta el límite de la sesión) y el
+// alcance que rechazó. Remaining es el menor de los buckets.
+func (r *RateLimitService) AllowAll(checks ...RateLimitCheck) (RateLimitDecision, string) {
+	result := RateLimitDecision{Allowed: true, Remaining: -1}
+	for i, check := range checks {
+		decision := r.Allow(check.Scope, check.ID)
+		if !decision.Allowed {
+			for _, taken := range checks[:i] {
+				r.refund(taken.Scope, taken.ID)
+			}
+			return decision, check.Scope
+		}
+		if decision.Remaining >= 0 && (result.Remaining < 0 || decision.Remaining < result.Remaining) {
+			result.Remaining = decision.Remaining
+		}
+	}
+	return result, ""
+}
+
+// Allow consume un token del bucket del alcance e id (session id, IP o API key)
+func (r *RateLimitService) Allow(scope, id string) RateLimitDecision {
+	limit, ok := r.limits[scope]
+	if !r.enabled || !ok || !limit.active() || id == "" {
+		return RateLimitDecision{Allowed: true, Remaining: -1}
+	}
+
+	decision, err := r.store.Take(rateLimitKey(scope, id), limit, time.Now())
+	if err != nil {
+		r.logStoreError(err)
+		return RateLimitDecision{Allowed: true, Remaining: -1}
+	}
+	if !decision.Allowed {
+		telemetry.CountRateLimited(scope)
+	}
+	return decision
+}
+
+// refund devuelve el token cobrado por Allow
+func (r *RateLimitService) refund(scope, id string) {
+	limit, ok := r.limits[scope]
+	if !r.enabled || !ok || !limit.active() || id == "" {
+		return
+	}
+	if err := r.store.Refund(rateLimitKey(scope, id), limit); err != nil {
+		r.logStoreError(err)
+	}
+}
+
+// rateLimitKey hashea el id: los ids de sesión de WhatsApp llevan el número
+func rateLimitKey(scope, id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "bob:ratelimit:" + scope + ":" + hex.EncodeToString(sum[:12])
+}
+
+// logStoreError registra como mucho un error por minuto para no llenar el log si el store cae
+func (r *RateLimitService) logStoreError(err error) {
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+
+	if time.Since(r.lastErrLog) < time.Minute {
+		return
+	}
+	r.lastErrLog = time.Now()
+	log.Printf("⚠️ Error en el store de rate limit (%s), se deja pasar: %v", r.store.Name(), err)
+}
+
+// memoryRateStore guarda los buckets en memoria del proceso
+type memoryRateStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Duration // tiempo hasta recargarse por completo, para limpiar los inactivos
+}
+
+func newMemoryRateStore() *memoryRateStore {
+	return &memoryRateStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *memoryRateStore) Name() string { return "memory" }
+
+func (m *memoryRateStore) Take(key string, limit RateLimit, now time.Time) (RateLimitDecision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{
+			tokens:  float64(limit.Burst),
+			updated: now,
+			full:    time.Duration(float64(limit.Burst)/limit.perMs()) * time.Millisecond,
+		}
+		m.buckets[key] = bucket
+	}
+
+	tokens, decision := takeToken(bucket.tokens, now.Sub(bucket.updated), limit)
+	bucket.tokens = tokens
+	bucket.updated = now
+	return decision, nil
+}
+
+func (m *memoryRateStore) Refund(key string, limit RateLimit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if bucket, ok := m.buckets[key]; ok {
+		bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+1)
+	}
+	return nil
+}
+
+// sweep borra, una vez por minuto, los buckets que ya se recargaron por completo
+func (m *memoryRateStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, bucket := range m.buckets {
+		if now.Sub(bucket.updated) > bucket.full {
+			delete(m.buckets, key)
+		}
+	}
+}
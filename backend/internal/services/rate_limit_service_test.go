@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeToken(t *testing.T) {
+	limit := RateLimit{Burst: 2, PerMinute: 60} // un token por segundo
+
+	tokens, decision := takeToken(2, 0, limit)
+	if !decision.Allowed || decision.Remaining != 1 || tokens != 1 {
+		t.Fatalf("primer token: %+v, quedan %v", decision, tokens)
+	}
+	tokens, _ = takeToken(tokens, 0, limit)
+	tokens, decision = takeToken(tokens, 0, limit)
+	if decision.Allowed || decision.RetryAfter != time.Second {
+		t.Fatalf("bucket vacío: %+v", decision)
+	}
+
+	// La recarga no pasa de Burst
+	if tokens, _ := takeToken(0, time.Hour, limit); tokens != 1 {
+		t.Errorf("recarga tope: quedan %v, se esperaba 1", tokens)
+	}
+}
+
+func TestTakeTokenSubMillisecondRefill(t *testing.T) {
+	limit := RateLimit{Burst: 1, PerMinute: 60000} // un token por milisegundo
+
+	// Nueve llamadas separadas por 100µs recargan 0.9 tokens (truncando serían 0)
+	tokens := 0.0
+	for i := 0; i < 9; i++ {
+		tokens, _ = takeToken(tokens, 100*time.Microsecond, limit)
+	}
+	if tokens < 0.89 {
+		t.Errorf("la recarga de intervalos menores a 1ms se perdió: quedan %v tokens", tokens)
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := newMemoryRateStore()
+	limit := RateLimit{Burst: 2, PerMinute: 60}
+	now := time.Now()
+
+	for i, want := range []bool{true, true, false} {
+		if decision, _ := store.Take("k", limit, now); decision.Allowed != want {
+			t.Fatalf("take %d: %+v", i, decision)
+		}
+	}
+	if decision, _ := store.Take("k", limit, now.Add(time.Second)); !decision.Allowed {
+		t.Errorf("tras un segundo debía haber un token: %+v", decision)
+	}
+}
+
+func TestAllowAllRefundsEarlierBuckets(t *testing.T) {
+	r := &RateLimitService{
+		enabled: true,
+		store:   newMemoryRateStore(),
+		limits: map[string]RateLimit{
+			RateLimitSession: {Burst: 3, PerMinute: 0.001},
+			RateLimitIP:      {Burst: 1, PerMinute: 0.001},
+		},
+	}
+	checks := []RateLimitCheck{{Scope: RateLimitSession, ID: "s-1"}, {Scope: RateLimitIP, ID: "10.0.0.1"}}
+
+	decision, scope := r.AllowAll(checks...)
+	if !decision.Allowed || scope != "" || decision.Remaining != 0 {
+		t.Fatalf("primer mensaje: %+v (%s)", decision, scope)
+	}
+	for i := 0; i < 3; i++ {
+		if decision, scope := r.AllowAll(checks...); decision.Allowed || scope != RateLimitIP {
+			t.Fatalf("rechazo %d por ip: %+v (%s)", i, decision, scope)
+		}
+	}
+
+	// Los rechazos por ip no gastaron el bucket de la sesión: quedan 2 de 3
+	if decision := r.Allow(RateLimitSession, "s-1"); !decision.Allowed || decision.Remaining != 1 {
+		t.Errorf("bucket de la sesión: %+v", decision)
+	}
+}
+
+func TestAllowDisabledOrUnknownScope(t *testing.T) {
+	r := &RateLimitService{store: newMemoryRateStore(), limits: map[string]RateLimit{RateLimitIP: {Burst: 1, PerMinute: 1}}}
+	if decision := r.Allow(RateLimitIP, "x"); !decision.Allowed || decision.Remaining != -1 {
+		t.Errorf("deshabilitado: %+v", decision)
+	}
+
+	r.enabled = true
+	if decision := r.Allow(RateLimitSession, "x"); !decision.Allowed || decision.Remaining != -1 {
+		t.Errorf("alcance sin límite: %+v", decision)
+	}
+	if decision := r.Allow(RateLimitIP, ""); !decision.Allowed {
+		t.Errorf("id vacío: %+v", decision)
+	}
+}
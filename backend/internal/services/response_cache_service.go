@@ -0,0 +1,331 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bob-hackathon/internal/telemetry"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCacheService guarda las respuestas del FAQ agent para no volver a llamar al LLM
+// cuando otra persona hace la misma pregunta. La clave es el canal, la versión del prompt,
+// la etapa del embudo, las FAQs recuperadas y la pregunta normalizada; si no hay coincidencia
+// exacta se acepta una pregunta con términos casi iguales (FAQ_CACHE_SIMILARITY). Las
+// entradas vencen a las FAQ_CACHE_TTL_MINUTES y se descartan si cambian las FAQs.
+//
+// Solo se usan respuestas generadas sin datos del prospecto: si la sesión ya tiene slots
+// (presupuesto, marca, especialista) el prompt los incluye y la respuesta no sirve para otro.
+type ResponseCacheService struct {
+	entries    map[string]*models.CachedResponse
+	faqService *FAQService
+	ttl        time.Duration
+	maxEntries int
+	similarity float64
+	enabled    bool
+	hits       int
+	misses     int
+	dataFile   string
+	mu         sync.Mutex
+}
+
+var responseCacheServiceInstance *ResponseCacheService
+var responseCacheServiceOnce sync.Once
+
+func GetResponseCacheService() *ResponseCacheService {
+	responseCacheServiceOnce.Do(func() {
+		ttl := config.AppConfig.FAQCacheTTLMinutes
+		if ttl <= 0 {
+			ttl = 1440
+		}
+		maxEntries := config.AppConfig.FAQCacheMaxEntries
+		if maxEntries <= 0 {
+			maxEntries = 2000
+		}
+		similarity := config.AppConfig.FAQCacheSimilarity
+		if similarity <= 0 || similarity > 1 {
+			similarity = 0.85
+		}
+
+		responseCacheServiceInstance = &ResponseCacheService{
+			entries:    make(map[string]*models.CachedResponse),
+			faqService: GetFAQService(),
+			ttl:        time.Duration(ttl) * time.Minute,
+			maxEntries: maxEntries,
+			similarity: similarity,
+			enabled:    config.AppConfig.FAQCacheEnabled,
+			dataFile:   filepath.Join("data", "faq_cache.json"),
+		}
+		responseCacheServiceInstance.loadFromDisk()
+	})
+	return responseCacheServiceInstance
+}
+
+// cacheable indica si una respuesta generada con estos slots se puede compartir entre sesiones
+func cacheable(slots map[string]string) bool {
+	return len(slots) == 0
+}
+
+// Get busca una respuesta para la pregunta con las mismas FAQs, etapa y versión de prompt
+func (r *ResponseCacheService) Get(channel, stage string, slots map[string]string, question, faqSet, promptVersion string) (*models.CachedResponse, bool) {
+	if !r.enabled || !cacheable(slots) {
+		return nil, false
+	}
+
+	terms, normalized := questionTerms(question)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := r.entries[cacheKey(channel, promptVersion, stage, faqSet, normalized)]
+	if entry == nil || !r.valid(entry) {
+		entry = r.findSimilar(channel, stage, terms, func(e *models.CachedResponse) bool {
+			return e.FAQSet == faqSet && e.PromptVersion == promptVersion
+		})
+	}
+
+	telemetry.CountFAQCache(entry != nil)
+	if entry == nil {
+		r.misses++
+		return nil, false
+	}
+	r.hits++
+	entry.Hits++
+	copied := *entry
+	return &copied, true
+}
+
+// MatchesQuestion indica si ya hay una respuesta vigente para una pregunta equivalente en el
+// canal y la etapa, sin importar las FAQs recuperadas. El orchestrator lo usa para rutear
+// directo al FAQ agent, con las mismas condiciones que Get.
+func (r *ResponseCacheService) MatchesQuestion(channel, stage string, slots map[string]string, question string) bool {
+	if !r.enabled || !cacheable(slots) {
+		return false
+	}
+
+	terms, _ := questionTerms(question)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.findSimilar(channel, stage, terms, nil) != nil
+}
+
+// Put guarda la respuesta generada por el LLM; las generadas con slots de la sesión no se guardan
+func (r *ResponseCacheService) Put(channel, stage string, slots map[string]string, question, faqSet, promptVersion, response string, retrieved []string) {
+	if !r.enabled || !cacheable(slots) || strings.TrimSpace(response) == "" {
+		return
+	}
+
+	terms, normalized := questionTerms(question)
+	key := cacheKey(channel, promptVersion, stage, faqSet, normalized)
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[key] = &models.CachedResponse{
+		Key:           key,
+		Channel:       channel,
+		Stage:         stage,
+		Question:      question,
+		Terms:         terms,
+		FAQSet:        faqSet,
+		FAQVersion:    r.faqService.Version(),
+		PromptVersion: promptVersion,
+		Response:      response,
+		Retrieved:     retrieved,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(r.ttl),
+	}
+	r.evict()
+	r.saveToDisk()
+}
+
+// Invalidate vacía el cache y devuelve cuántas entradas había
+func (r *ResponseCacheService) Invalidate() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := len(r.entries)
+	r.entries = make(map[string]*models.CachedResponse)
+	r.saveToDisk()
+	log.Printf("🧹 Cache de FAQs vaciado (%d entradas)", removed)
+	return removed
+}
+
+// Purge descarta las entradas vencidas o de FAQs que ya cambiaron y devuelve cuántas quitó
+func (r *ResponseCacheService) Purge() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := r.purge()
+	if removed > 0 {
+		r.saveToDisk()
+	}
+	return removed
+}
+
+// Stats devuelve el tamaño del cache, aciertos desde el arranque y las top entradas más reutilizadas
+func (r *ResponseCacheService) Stats(top int) models.ResponseCacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := models.ResponseCacheStats{
+		Enabled:   r.enabled,
+		ByChannel: make(map[string]int),
+		Hits:      r.hits,
+		Misses:    r.misses,
+		Top:       []models.CachedResponse{},
+	}
+	if total := r.hits + r.misses; total > 0 {
+		stats.HitRate = float64(r.hits) / float64(total)
+	}
+
+	for _, entry := range r.entries {
+		if !r.valid(entry) {
+			continue
+		}
+		stats.Entries++
+		stats.ByChannel[entry.Channel]++
+		stats.Top = append(stats.Top, *entry)
+	}
+	sort.Slice(stats.Top, func(i, j int) bool {
+		if stats.Top[i].Hits != stats.Top[j].Hits {
+			return stats.Top[i].Hits > stats.Top[j].Hits
+		}
+		return stats.Top[i].CreatedAt.After(stats.Top[j].CreatedAt)
+	})
+	if top >= 0 && len(stats.Top) > top {
+		stats.Top = stats.Top[:top]
+	}
+	return stats
+}
+
+// findSimilar busca la entrada vigente del canal y la etapa con términos más parecidos a la pregunta
+func (r *ResponseCacheService) findSimilar(channel, stage string, terms []string, accept func(*models.CachedResponse) bool) *models.CachedResponse {
+	if len(terms) == 0 {
+		return nil
+	}
+	questionSet := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		questionSet[t] = true
+	}
+
+	var best *models.CachedResponse
+	bestScore := 0.0
+	for _, entry := range r.entries {
+		if entry.Channel != channel || entry.Stage != stage || !r.valid(entry) || (accept != nil && !accept(entry)) {
+			continue
+		}
+		entrySet := make(map[string]bool, len(entry.Terms))
+		for _, t := range entry.Terms {
+			entrySet[t] = true
+		}
+		if score := jaccard(questionSet, entrySet); score >= r.similarity && score > bestScore {
+			best, bestScore = entry, score
+		}
+	}
+	return best
+}
+
+// valid indica si la entrada no venció y se generó con las FAQs actuales
+func (r *ResponseCacheService) valid(entry *models.CachedResponse) bool {
+	return time.Now().Before(entry.ExpiresAt) && entry.FAQVersion == r.faqService.Version()
+}
+
+func (r *ResponseCacheService) purge() int {
+	removed := 0
+	for key, entry := range r.entries {
+		if !r.valid(entry) {
+			delete(r.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// evict quita las entradas inválidas y, si sigue sobrando, las más antiguas
+func (r *ResponseCacheService) evict() {
+	if len(r.entries) <= r.maxEntries {
+		return
+	}
+	r.purge()
+	if len(r.entries) <= r.maxEntries {
+		return
+	}
+
+	oldest := make([]*models.CachedResponse, 0, len(r.entries))
+	for _, entry := range r.entries {
+		oldest = append(oldest, entry)
+	}
+	sort.Slice(oldest, func(i, j int) bool { return oldest[i].CreatedAt.Before(oldest[j].CreatedAt) })
+	for _, entry := range oldest[:len(oldest)-r.maxEntries] {
+		delete(r.entries, entry.Key)
+	}
+}
+
+// questionTerms normaliza la pregunta (minúsculas, sin tildes, puntuación ni palabras vacías) y
+// ordena los términos, así "¿cuánto cobran de comisión?" y "comisión cuánto cobran" coinciden
+func questionTerms(question string) ([]string, string) {
+	tokens := intentTokens(question)
+	set := contentTerms(tokens)
+	if len(set) == 0 {
+		// Preguntas hechas solo de palabras vacías ("¿qué es bob?"): se comparan completas
+		set = make(map[string]bool, len(tokens))
+		for _, t := range tokens {
+			set[t] = true
+		}
+	}
+
+	terms := make([]string, 0, len(set))
+	for t := range set {
+		terms = append(terms, t)
+	}
+	sort.Strings(terms)
+	return terms, strings.Join(terms, " ")
+}
+
+func cacheKey(channel, promptVersion, stage, faqSet, normalized string) string {
+	return channel + "|" + promptVersion + "|" + stage + "|" + faqSet + "|" + normalized
+}
+
+func (r *ResponseCacheService) loadFromDisk() {
+	data, err := os.ReadFile(r.dataFile)
+	if err != nil {
+		return
+	}
+
+	var entries []*models.CachedResponse
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("Error al cargar cache de FAQs: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		r.entries[entry.Key] = entry
+	}
+	if removed := r.purge(); removed > 0 {
+		r.saveToDisk()
+	}
+	log.Printf("%d respuestas de FAQs en cache cargadas desde disco", len(r.entries))
+}
+
+func (r *ResponseCacheService) saveToDisk() {
+	entries := make([]*models.CachedResponse, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+
+	if data, err := json.MarshalIndent(entries, "", "  "); err == nil {
+		if err := os.WriteFile(r.dataFile, data, 0644); err != nil {
+			log.Printf("Error al guardar cache de FAQs: %v", err)
+		}
+	}
+}
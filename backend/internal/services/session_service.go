@@ -3,6 +3,8 @@ package services
 import (
 	"bob-hackathon/internal/models"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -12,12 +14,19 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	ErrSessionNotFound = errors.New("sesión no encontrada")
+	ErrSessionClaimed  = errors.New("sesión tomada por otro especialista")
+	ErrNotHumanMode    = errors.New("la sesión no está en modo humano")
+)
+
 type SessionService struct {
 	sessions     map[string]*models.Session
 	leads        map[string]*models.Lead
 	mu           sync.RWMutex
 	sessionsFile string
 	leadsFile    string
+	listeners    []LeadListener
 }
 
 var sessionServiceInstance *SessionService
@@ -61,6 +70,8 @@ func (s *SessionService) GetOrCreateSession(sessionID, channel string) *models.S
 		LeadScore: 0,
 		Category:  "cold",
 		Metadata:  make(map[string]string),
+		Stage:     StageGreeting,
+		Slots:     make(map[string]string),
 	}
 
 	s.sessions[sessionID] = session
@@ -71,6 +82,12 @@ func (s *SessionService) GetOrCreateSession(sessionID, channel string) *models.S
 }
 
 func (s *SessionService) AddMessage(sessionID, role, content string) {
+	s.AddMessageWithTrace(sessionID, role, content, nil)
+}
+
+// AddMessageWithTrace agrega un mensaje guardando cómo fue generado (agente, intent, prompts).
+// Guarda una copia del trace: el que queda en el controller se sigue completando.
+func (s *SessionService) AddMessageWithTrace(sessionID, role, content string, trace *models.ReplyTrace) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -84,6 +101,7 @@ func (s *SessionService) AddMessage(sessionID, role, content string) {
 		Role:      role,
 		Content:   content,
 		Timestamp: time.Now(),
+		Trace:     trace.Clone(),
 	}
 
 	session.Messages = append(session.Messages, message)
@@ -92,6 +110,35 @@ func (s *SessionService) AddMessage(sessionID, role, content string) {
 	s.saveToDisk()
 }
 
+// AddTracePrompt agrega un prompt al trace de la última respuesta del asistente (el scoring
+// corre después de guardarla)
+func (s *SessionService) AddTracePrompt(sessionID string, ref *models.PromptRef) {
+	if ref == nil || ref.Name == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return
+	}
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if session.Messages[i].Role != "assistant" {
+			continue
+		}
+		if session.Messages[i].Trace == nil {
+			return
+		}
+		trace := session.Messages[i].Trace.Clone()
+		trace.AddPrompt(ref)
+		session.Messages[i].Trace = trace
+		s.saveToDisk()
+		return
+	}
+}
+
 func (s *SessionService) GetSession(sessionID string) *models.Session {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -99,6 +146,45 @@ func (s *SessionService) GetSession(sessionID string) *models.Session {
 	return s.sessions[sessionID]
 }
 
+// Snapshot devuelve una copia de la sesión para leerla sin el lock mientras otros turnos la
+// modifican (mensajes, handoff, slots). Devuelve nil si no existe.
+func (s *SessionService) Snapshot(sessionID string) *models.Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil
+	}
+
+	snapshot := *session
+	snapshot.Messages = append([]models.Message(nil), session.Messages...)
+	snapshot.StageHistory = append([]models.StageChange(nil), session.StageHistory...)
+	snapshot.GuardrailFlags = append([]string(nil), session.GuardrailFlags...)
+	snapshot.Slots = copyStringMap(session.Slots)
+	snapshot.Metadata = copyStringMap(session.Metadata)
+	if session.Handoff != nil {
+		handoff := *session.Handoff
+		snapshot.Handoff = &handoff
+	}
+	if session.Usage != nil {
+		usage := *session.Usage
+		snapshot.Usage = &usage
+	}
+	return &snapshot
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
 func (s *SessionService) GetMessages(sessionID string) []models.Message {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -127,23 +213,231 @@ func (s *SessionService) UpdateScore(sessionID string, score int, category strin
 	s.saveToDisk()
 }
 
-func (s *SessionService) CreateOrUpdateLead(leadData *models.Lead) {
+// Slots devuelve una copia de los slots de la sesión (MergeSlots los modifica en cada turno)
+func (s *SessionService) Slots(sessionID string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil
+	}
+	return copyStringMap(session.Slots)
+}
+
+// MergeSlots agrega a la sesión los slots detectados en el último mensaje
+func (s *SessionService) MergeSlots(sessionID string, slots map[string]string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return slots
+	}
+
+	if session.Slots == nil {
+		session.Slots = make(map[string]string)
+	}
+	for k, v := range slots {
+		session.Slots[k] = v
+	}
+
+	merged := make(map[string]string, len(session.Slots))
+	for k, v := range session.Slots {
+		merged[k] = v
+	}
+	return merged
+}
+
+// UpdateStage mueve la sesión (y su lead, si existe) a otra etapa del embudo
+func (s *SessionService) UpdateStage(sessionID, stage, trigger string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists || session.Stage == stage {
+		return
+	}
+
+	from := session.Stage
+	if from == "" {
+		from = StageGreeting
+	}
+
+	now := time.Now()
+	session.StageHistory = append(session.StageHistory, models.StageChange{
+		From:    from,
+		To:      stage,
+		Trigger: trigger,
+		At:      now,
+	})
+	session.Stage = stage
+	session.UpdatedAt = now
+
+	if lead, ok := s.leads[sessionID]; ok {
+		lead.Stage = stage
+	}
+
+	s.saveToDisk()
+
+	log.Printf("Embudo %s: %s → %s (%s)", sessionID, from, stage, trigger)
+}
+
+// ClaimSession pone la sesión en modo humano a cargo del especialista.
+// Si ya la tiene otro especialista devuelve ErrSessionClaimed.
+func (s *SessionService) ClaimSession(sessionID, specialist string) (*models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	if session.IsHumanMode() {
+		if session.Handoff.Specialist != specialist {
+			return nil, ErrSessionClaimed
+		}
+		return session, nil
+	}
+
+	session.Handoff = &models.Handoff{
+		Active:     true,
+		Specialist: specialist,
+		ClaimedAt:  time.Now(),
+	}
+	session.UpdatedAt = time.Now()
+	s.saveToDisk()
+
+	log.Printf("🙋 Sesión %s tomada por %s", sessionID, specialist)
+	return session, nil
+}
+
+// ReleaseSession devuelve el control de la sesión al bot. Solo puede hacerlo el especialista
+// que la tomó; specialist vacío la libera igual (admin). Si no, devuelve ErrSessionClaimed.
+func (s *SessionService) ReleaseSession(sessionID, specialist string) (*models.Session, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	if !session.IsHumanMode() {
+		return nil, ErrNotHumanMode
+	}
+	if specialist != "" && session.Handoff.Specialist != specialist {
+		return nil, ErrSessionClaimed
+	}
+
+	now := time.Now()
+	session.Handoff.Active = false
+	session.Handoff.ReleasedAt = &now
+	session.Handoff.Pending = 0
+	session.UpdatedAt = now
+	s.saveToDisk()
+
+	log.Printf("🤖 Sesión %s devuelta al bot por %s", sessionID, session.Handoff.Specialist)
+	return session, nil
+}
+
+// UpdatePending ajusta el contador de mensajes del usuario sin respuesta del especialista.
+// Con reset=true lo deja en cero; devuelve el valor resultante.
+func (s *SessionService) UpdatePending(sessionID string, reset bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists || session.Handoff == nil {
+		return 0
+	}
+
+	if reset {
+		session.Handoff.Pending = 0
+	} else {
+		session.Handoff.Pending++
+	}
+	s.saveToDisk()
+
+	return session.Handoff.Pending
+}
+
+// GetHumanSessions devuelve las sesiones atendidas por especialistas y las que esperan uno
+// (etapa handoff sin tomar). Si se indica especialista, solo las suyas.
+func (s *SessionService) GetHumanSessions(specialist string) []*models.Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.Session
+	for _, session := range s.sessions {
+		if session.IsHumanMode() {
+			if specialist != "" && session.Handoff.Specialist != specialist {
+				continue
+			}
+			result = append(result, session)
+			continue
+		}
+		if specialist == "" && session.Handoff == nil && session.Stage == StageHandoff {
+			result = append(result, session)
+		}
+	}
+
+	return result
+}
+
+func (s *SessionService) CreateOrUpdateLead(leadData *models.Lead) {
+	s.mu.Lock()
+
 	leadData.UpdatedAt = time.Now()
+	event := LeadCreated
+
+	if session, ok := s.sessions[leadData.SessionID]; ok && leadData.Stage == "" {
+		leadData.Stage = session.Stage
+	}
 
-	if _, exists := s.leads[leadData.SessionID]; !exists {
+	if existing, exists := s.leads[leadData.SessionID]; !exists {
 		leadData.CreatedAt = time.Now()
+		leadData.Status = LeadStatusNew
+		leadData.StatusTimestamps = map[string]time.Time{LeadStatusNew: leadData.CreatedAt}
+	} else {
+		event = ""
+		if existing.Score != leadData.Score || existing.Category != leadData.Category {
+			event = LeadScoreChanged
+		}
+
+		// El scoring reemplaza el lead: conservar asignación y ciclo de vida comercial
+		if leadData.AssignedTo == "" {
+			leadData.AssignedTo = existing.AssignedTo
+			leadData.AssignedAt = existing.AssignedAt
+			leadData.Assignments = existing.Assignments
+		}
+		leadData.CreatedAt = existing.CreatedAt
+		leadData.FirstHotAt = existing.FirstHotAt
+		leadData.Status = existing.Status
+		leadData.StatusTimestamps = existing.StatusTimestamps
+		leadData.LossReason = existing.LossReason
+		leadData.VehicleLots = existing.VehicleLots
+		leadData.Notes = existing.Notes
+		leadData.Audit = existing.Audit
+		leadData.HasContact = leadData.HasContact || existing.HasContact
+	}
+
+	if leadData.Category == "hot" && leadData.FirstHotAt == nil {
+		hotAt := leadData.UpdatedAt
+		leadData.FirstHotAt = &hotAt
 	}
 
 	s.leads[leadData.SessionID] = leadData
 	s.saveToDisk()
+	s.mu.Unlock()
 
 	log.Printf("Lead actualizado: %s - Score: %d (%s)", leadData.SessionID, leadData.Score, leadData.Category)
+	if event != "" {
+		s.emitLeadEvent(event, leadData)
+	}
 }
 
-func (s *SessionService) GetAllLeads(category, channel string) []*models.Lead {
+func (s *SessionService) GetAllLeads(filter models.LeadFilter) []*models.Lead {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -151,12 +445,30 @@ func (s *SessionService) GetAllLeads(category, channel string) []*models.Lead {
 
 	for _, lead := range s.leads {
 		// Filtrar por categoría si se especifica
-		if category != "" && lead.Category != category {
+		if filter.Category != "" && lead.Category != filter.Category {
 			continue
 		}
 
 		// Filtrar por canal si se especifica
-		if channel != "" && lead.Channel != channel {
+		if filter.Channel != "" && lead.Channel != filter.Channel {
+			continue
+		}
+
+		// Filtrar por etapa del embudo si se especifica
+		if filter.Stage != "" && lead.Stage != filter.Stage {
+			continue
+		}
+
+		// Filtrar por estado comercial si se especifica
+		if filter.Status != "" && lead.Status != filter.Status {
+			continue
+		}
+
+		// Filtrar por fecha de creación si se especifica
+		if !filter.From.IsZero() && lead.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !lead.CreatedAt.Before(filter.To) {
 			continue
 		}
 
@@ -166,6 +478,42 @@ func (s *SessionService) GetAllLeads(category, channel string) []*models.Lead {
 	return result
 }
 
+// AssignLead registra el especialista a cargo del lead y guarda el historial de asignaciones
+func (s *SessionService) AssignLead(sessionID string, assignment models.Assignment) (*models.Lead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lead, exists := s.leads[sessionID]
+	if !exists {
+		return nil, fmt.Errorf("lead no encontrado: %s", sessionID)
+	}
+
+	assignment.Previous = lead.AssignedTo
+	lead.AssignedTo = assignment.SpecialistID
+	lead.AssignedAt = &assignment.At
+	lead.Assignments = append(lead.Assignments, assignment)
+	lead.UpdatedAt = assignment.At
+	s.saveToDisk()
+
+	log.Printf("👤 Lead %s asignado a %s (%s)", sessionID, assignment.SpecialistID, assignment.Strategy)
+	s.emitLeadEventLocked(LeadAssigned, lead)
+	return lead, nil
+}
+
+// GetLeadsBySpecialist devuelve los leads asignados a un especialista
+func (s *SessionService) GetLeadsBySpecialist(specialistID string) []*models.Lead {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.Lead
+	for _, lead := range s.leads {
+		if lead.AssignedTo == specialistID {
+			result = append(result, lead)
+		}
+	}
+	return result
+}
+
 func (s *SessionService) GetLead(sessionID string) *models.Lead {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -178,13 +526,20 @@ func (s *SessionService) GetLeadsStats() *models.LeadStats {
 	defer s.mu.RUnlock()
 
 	stats := &models.LeadStats{
-		Total:     len(s.leads),
-		Hot:       0,
-		Warm:      0,
-		Cold:      0,
-		AvgScore:  0,
-		ByChannel: make(map[string]int),
+		Total:      len(s.leads),
+		Hot:        0,
+		Warm:       0,
+		Cold:       0,
+		AvgScore:   0,
+		ByChannel:  make(map[string]int),
+		ByStage:    make(map[string]int),
+		ByStatus:   make(map[string]int),
+		Conversion: make(map[string]float64),
 	}
+	for _, status := range LeadStatuses {
+		stats.ByStatus[status] = 0
+	}
+	reached := make(map[string]int)
 
 	totalScore := 0
 
@@ -198,18 +553,64 @@ func (s *SessionService) GetLeadsStats() *models.LeadStats {
 			stats.Warm++
 		case "cold":
 			stats.Cold++
+		case "discarded":
+			stats.Discarded++
 		}
 
 		stats.ByChannel[lead.Channel]++
+
+		status := lead.Status
+		if status == "" {
+			status = LeadStatusNew
+		}
+		stats.ByStatus[status]++
+		countReachedStatuses(lead, reached)
 	}
 
 	if stats.Total > 0 {
 		stats.AvgScore = float64(totalScore) / float64(stats.Total)
+		for _, status := range LeadStatuses {
+			stats.Conversion[status] = float64(reached[status]) / float64(stats.Total)
+		}
+	}
+
+	// El embudo se mide sobre todas las sesiones, no solo las que ya tienen lead
+	for _, stage := range FunnelStages {
+		stats.ByStage[stage] = 0
+	}
+	for _, session := range s.sessions {
+		stage := session.Stage
+		if stage == "" {
+			stage = StageGreeting
+		}
+		stats.ByStage[stage]++
 	}
 
 	return stats
 }
 
+// countReachedStatuses suma los estados que alcanzó el lead. En el pipeline
+// new → contacted → qualified → bidding → won, llegar a un estado implica los anteriores.
+func countReachedStatuses(lead *models.Lead, reached map[string]int) {
+	pipeline := []string{LeadStatusNew, LeadStatusContacted, LeadStatusQualified, LeadStatusBidding, LeadStatusWon}
+
+	furthest := 0
+	for i, status := range pipeline {
+		if _, ok := lead.StatusTimestamps[status]; ok || lead.Status == status {
+			furthest = i
+		}
+	}
+	for _, status := range pipeline[:furthest+1] {
+		reached[status]++
+	}
+
+	for _, status := range []string{LeadStatusLost, LeadStatusNoResponse} {
+		if _, ok := lead.StatusTimestamps[status]; ok || lead.Status == status {
+			reached[status]++
+		}
+	}
+}
+
 func (s *SessionService) loadFromDisk() {
 	// Cargar sesiones
 	if data, err := os.ReadFile(s.sessionsFile); err == nil {
@@ -225,6 +626,12 @@ func (s *SessionService) loadFromDisk() {
 		if err := json.Unmarshal(data, &s.leads); err != nil {
 			log.Printf("Error al cargar leads: %v", err)
 		} else {
+			// Leads guardados antes del ciclo de vida comercial
+			for _, lead := range s.leads {
+				if lead.Status == "" {
+					lead.Status = LeadStatusNew
+				}
+			}
 			log.Printf("%d leads cargados desde disco", len(s.leads))
 		}
 	}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Estrategias de asignación de leads
+const (
+	StrategyRoundRobin = "round_robin"
+	StrategySkill      = "skill"
+	StrategyLoad       = "load"
+	StrategyManual     = "manual"
+)
+
+var (
+	ErrSpecialistNotFound    = errors.New("especialista no encontrado")
+	ErrNoSpecialistAvailable = errors.New("no hay especialistas disponibles")
+)
+
+type SpecialistService struct {
+	specialists    map[string]*models.Specialist
+	sessionService *SessionService
+	strategy       string
+	location       *time.Location
+	lastAssigned   string // cursor del round-robin
+	dataFile       string
+	mu             sync.RWMutex
+}
+
+var specialistServiceInstance *SpecialistService
+var specialistServiceOnce sync.Once
+
+func GetSpecialistService() *SpecialistService {
+	specialistServiceOnce.Do(func() {
+		location, err := time.LoadLocation(config.AppConfig.SpecialistTimezone)
+		if err != nil {
+			log.Printf("⚠️ Zona horaria %s no disponible, usando UTC-5", config.AppConfig.SpecialistTimezone)
+			location = time.FixedZone("UTC-5", -5*60*60)
+		}
+
+		specialistServiceInstance = &SpecialistService{
+			specialists:    make(map[string]*models.Specialist),
+			sessionService: GetSessionService(),
+			strategy:       config.AppConfig.AssignmentStrategy,
+			location:       location,
+			dataFile:       filepath.Join("data", "specialists.json"),
+		}
+		specialistServiceInstance.loadFromDisk()
+	})
+	return specialistServiceInstance
+}
+
+// GetSpecialists devuelve el directorio con la carga y disponibilidad actual de cada especialista
+func (s *SpecialistService) GetSpecialists() []models.SpecialistStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]models.SpecialistStatus, 0, len(s.specialists))
+	for _, id := range s.sortedIDs() {
+		sp := s.specialists[id]
+		result = append(result, models.SpecialistStatus{
+			Specialist: *sp,
+			Load:       s.load(id),
+			OnShift:    s.onShift(sp, now),
+		})
+	}
+	return result
+}
+
+func (s *SpecialistService) GetSpecialist(id string) (*models.Specialist, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sp, ok := s.specialists[id]
+	if !ok {
+		return nil, ErrSpecialistNotFound
+	}
+	return sp, nil
+}
+
+// SaveSpecialist crea o reemplaza un especialista del directorio
+func (s *SpecialistService) SaveSpecialist(sp *models.Specialist) error {
+	sp.ID = strings.TrimSpace(sp.ID)
+	if sp.ID == "" || strings.TrimSpace(sp.Name) == "" {
+		return fmt.Errorf("id y name son requeridos")
+	}
+	for _, hhmm := range []string{sp.WorkingHours.Start, sp.WorkingHours.End} {
+		if _, ok := parseClock(hhmm); hhmm != "" && !ok {
+			return fmt.Errorf("horario inválido: %s (formato HH:MM)", hhmm)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.specialists[sp.ID] = sp
+	s.saveToDisk()
+
+	log.Printf("👤 Especialista guardado: %s (%s)", sp.ID, sp.Name)
+	return nil
+}
+
+// GetQueue devuelve los leads abiertos del especialista, primero los de mayor score
+func (s *SpecialistService) GetQueue(id string) ([]*models.Lead, error) {
+	if _, err := s.GetSpecialist(id); err != nil {
+		return nil, err
+	}
+
+	var queue []*models.Lead
+	for _, lead := range s.sessionService.GetLeadsBySpecialist(id) {
+		if lead.Stage != StageClosed {
+			queue = append(queue, lead)
+		}
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		if queue[i].Score != queue[j].Score {
+			return queue[i].Score > queue[j].Score
+		}
+		return queue[i].UpdatedAt.After(queue[j].UpdatedAt)
+	})
+	return queue, nil
+}
+
+// AutoAssign asigna el lead si es hot o warm y todavía no tiene especialista
+func (s *SpecialistService) AutoAssign(sessionID string) (*models.Lead, error) {
+	lead := s.sessionService.GetLead(sessionID)
+	if lead == nil || lead.AssignedTo != "" || (lead.Category != "hot" && lead.Category != "warm") {
+		return lead, nil
+	}
+	return s.Assign(sessionID, "", "lead "+lead.Category, "")
+}
+
+// Assign asigna (o reasigna) el lead. Sin specialistID elige uno con la estrategia configurada.
+func (s *SpecialistService) Assign(sessionID, specialistID, reason, actor string) (*models.Lead, error) {
+	lead := s.sessionService.GetLead(sessionID)
+	if lead == nil {
+		return nil, fmt.Errorf("lead no encontrado: %s", sessionID)
+	}
+
+	strategy := StrategyManual
+	if specialistID == "" {
+		var err error
+		specialistID, strategy, err = s.pick(lead)
+		if err != nil {
+			log.Printf("⚠️ Lead %s sin asignar: %v", sessionID, err)
+			return nil, err
+		}
+	} else if _, err := s.GetSpecialist(specialistID); err != nil {
+		return nil, err
+	}
+
+	return s.sessionService.AssignLead(sessionID, models.Assignment{
+		SpecialistID: specialistID,
+		Strategy:     strategy,
+		Reason:       reason,
+		By:           actor,
+		At:           time.Now(),
+	})
+}
+
+// pick elige un especialista para el lead. Solo considera especialistas activos, del canal
+// del lead y con capacidad libre; prefiere a los que están en horario.
+func (s *SpecialistService) pick(lead *models.Lead) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slots := s.sessionService.Slots(lead.SessionID)
+
+	now := time.Now()
+	ids := s.sortedIDs()
+
+	// La carga recorre los leads: se calcula una vez por candidato y no dentro del sort
+	loads := make(map[string]int, len(ids))
+	var candidates, offShift []string
+	for _, id := range ids {
+		sp := s.specialists[id]
+		if id == lead.AssignedTo || !sp.Active || !matchesAny(sp.Channels, lead.Channel, true) {
+			continue
+		}
+		loads[id] = s.load(id)
+		if sp.Capacity > 0 && loads[id] >= sp.Capacity {
+			continue
+		}
+		if s.onShift(sp, now) {
+			candidates = append(candidates, id)
+		} else {
+			offShift = append(offShift, id)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = offShift
+	}
+	if len(candidates) == 0 {
+		return "", "", ErrNoSpecialistAvailable
+	}
+
+	// Orden del round-robin: el siguiente al último asignado va primero
+	rrOrder := make(map[string]int, len(ids))
+	start := sort.SearchStrings(ids, s.lastAssigned)
+	if start < len(ids) && ids[start] == s.lastAssigned {
+		start++
+	}
+	for i := range ids {
+		rrOrder[ids[(start+i)%len(ids)]] = i
+	}
+
+	skill := func(id string) int { return skillScore(s.specialists[id], slots) }
+	loadRatio := func(id string) float64 {
+		if c := s.specialists[id].Capacity; c > 0 {
+			return float64(loads[id]) / float64(c)
+		}
+		return float64(loads[id]) / 100
+	}
+
+	strategy := s.strategy
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if strategy == StrategySkill && skill(a) != skill(b) {
+			return skill(a) > skill(b)
+		}
+		if strategy != StrategyRoundRobin && loadRatio(a) != loadRatio(b) {
+			return loadRatio(a) < loadRatio(b)
+		}
+		return rrOrder[a] < rrOrder[b]
+	})
+
+	s.lastAssigned = candidates[0]
+	return candidates[0], strategy, nil
+}
+
+// skillScore cuenta cuántas habilidades del especialista coinciden con lo que busca el lead,
+// más uno si atiende la región que mencionó
+func skillScore(sp *models.Specialist, slots map[string]string) int {
+	score := 0
+	for _, skill := range sp.Skills {
+		skill = strings.ToLower(skill)
+		for key, value := range slots {
+			if key == SlotRegion {
+				continue
+			}
+			value = strings.ToLower(value)
+			if value == skill || strings.TrimSuffix(value, "s") == skill || (key == SlotBusiness && skill == "empresa") {
+				score++
+				break
+			}
+		}
+	}
+	if region := slots[SlotRegion]; region != "" && matchesAny(sp.Regions, region, false) {
+		score++
+	}
+	return score
+}
+
+func matchesAny(values []string, target string, emptyMatches bool) bool {
+	if len(values) == 0 {
+		return emptyMatches
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// load cuenta los leads abiertos asignados al especialista
+func (s *SpecialistService) load(id string) int {
+	count := 0
+	for _, lead := range s.sessionService.GetLeadsBySpecialist(id) {
+		if lead.Stage != StageClosed {
+			count++
+		}
+	}
+	return count
+}
+
+// onShift indica si el especialista está en su horario de atención
+func (s *SpecialistService) onShift(sp *models.Specialist, now time.Time) bool {
+	local := now.In(s.location)
+	wh := sp.WorkingHours
+
+	if len(wh.Days) > 0 {
+		working := false
+		for _, d := range wh.Days {
+			if d == int(local.Weekday()) {
+				working = true
+				break
+			}
+		}
+		if !working {
+			return false
+		}
+	}
+
+	start, okStart := parseClock(wh.Start)
+	end, okEnd := parseClock(wh.End)
+	if !okStart || !okEnd {
+		return true
+	}
+
+	minutes := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minutes >= start && minutes < end
+	}
+	// Turno que cruza la medianoche
+	return minutes >= start || minutes < end
+}
+
+// parseClock convierte "HH:MM" en minutos desde la medianoche
+func parseClock(hhmm string) (int, bool) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func (s *SpecialistService) sortedIDs() []string {
+	ids := make([]string, 0, len(s.specialists))
+	for id := range s.specialists {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *SpecialistService) loadFromDisk() {
+	data, err := os.ReadFile(s.dataFile)
+	if err != nil {
+		log.Printf("Sin directorio de especialistas (%s)", s.dataFile)
+		return
+	}
+
+	var specialists []*models.Specialist
+	if err := json.Unmarshal(data, &specialists); err != nil {
+		log.Printf("Error al cargar especialistas: %v", err)
+		return
+	}
+	for _, sp := range specialists {
+		s.specialists[sp.ID] = sp
+	}
+	log.Printf("%d especialistas cargados desde disco", len(s.specialists))
+}
+
+func (s *SpecialistService) saveToDisk() {
+	specialists := make([]*models.Specialist, 0, len(s.specialists))
+	for _, id := range s.sortedIDs() {
+		specialists = append(specialists, s.specialists[id])
+	}
+
+	if data, err := json.MarshalIndent(specialists, "", "  "); err == nil {
+		if err := os.WriteFile(s.dataFile, data, 0644); err != nil {
+			log.Printf("Error al guardar especialistas: %v", err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	traceFileRegex = regexp.MustCompile(`[^A-Za-z0-9._@-]`)
+)
+
+// TraceService guarda el trace de cada turno de chat en data/traces/<sessionId>.jsonl
+type TraceService struct {
+	enabled  bool
+	redact   bool // redactar antes de guardar
+	prompts  bool // guardar el texto completo de los prompts
+	maxTurns int
+	dir      string
+	counts   map[string]int // turnos en el archivo de cada sesión
+	mu       sync.Mutex
+}
+
+var traceServiceInstance *TraceService
+var traceServiceOnce sync.Once
+
+func GetTraceService() *TraceService {
+	traceServiceOnce.Do(func() {
+		maxTurns := config.AppConfig.TraceMaxTurns
+		if maxTurns <= 0 {
+			maxTurns = 200
+		}
+
+		traceServiceInstance = &TraceService{
+			enabled:  config.AppConfig.TraceEnabled,
+			redact:   config.AppConfig.TraceRedact,
+			prompts:  config.AppConfig.TracePrompts,
+			maxTurns: maxTurns,
+			dir:      filepath.Join("data", "traces"),
+			counts:   make(map[string]int),
+		}
+		if traceServiceInstance.enabled {
+			if err := os.MkdirAll(traceServiceInstance.dir, 0755); err != nil {
+				log.Printf("⚠️ No se pudo crear %s: %v", traceServiceInstance.dir, err)
+			}
+		}
+	})
+	return traceServiceInstance
+}
+
+// Record guarda el turno; completa ID y duración total
+func (t *TraceService) Record(turn *models.TurnTrace) {
+	if !t.enabled || turn == nil || turn.SessionID == "" {
+		return
+	}
+
+	if turn.ID == "" {
+		turn.ID = uuid.New().String()
+	}
+	turn.DurationMs = time.Since(turn.StartedAt).Milliseconds()
+	turn.Usage = turnUsage(turn)
+
+	stored := *turn
+	if !t.prompts {
+		stored.Orchestrator = withoutPrompt(stored.Orchestrator)
+		stored.SubAgent = withoutPrompt(stored.SubAgent)
+		stored.Scoring = withoutPrompt(stored.Scoring)
+	}
+	if t.redact {
+		stored = RedactTurn(stored)
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := t.path(turn.SessionID)
+	count, ok := t.counts[turn.SessionID]
+	if !ok {
+		count = len(t.readTurns(path))
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Error al guardar trace de %s: %v", turn.SessionID, err)
+		return
+	}
+	_, err = file.Write(append(data, '\n'))
+	file.Close()
+	if err != nil {
+		log.Printf("Error al guardar trace de %s: %v", turn.SessionID, err)
+		return
+	}
+	count++
+
+	// Se recorta con holgura para no reescribir el archivo en cada turno
+	if count >= 2*t.maxTurns {
+		turns := t.readTurns(path)
+		if len(turns) > t.maxTurns {
+			turns = turns[len(turns)-t.maxTurns:]
+		}
+		t.rewrite(path, turns)
+		count = len(turns)
+	}
+	t.counts[turn.SessionID] = count
+}
+
+// GetTurns devuelve los últimos turnos de la sesión (todos si limit es 0), del más antiguo
+// al más reciente. Con redact se ocultan los datos sensibles aunque se hayan guardado.
+func (t *TraceService) GetTurns(sessionID string, limit int, redact bool) []models.TurnTrace {
+	t.mu.Lock()
+	turns := t.readTurns(t.path(sessionID))
+	t.mu.Unlock()
+
+	if len(turns) > t.maxTurns {
+		turns = turns[len(turns)-t.maxTurns:]
+	}
+	if limit > 0 && len(turns) > limit {
+		turns = turns[len(turns)-limit:]
+	}
+	if redact {
+		for i := range turns {
+			turns[i] = RedactTurn(turns[i])
+		}
+	}
+	return turns
+}
+
+// RedactTurn reemplaza los datos personales (emails, teléfonos, DNI, RUC, placas, tarjetas) en los textos del turno
+func RedactTurn(turn models.TurnTrace) models.TurnTrace {
+	if turn.Redacted {
+		return turn
+	}
+
+	turn.Message = redactText(turn.Message)
+	turn.Reply = redactText(turn.Reply)
+	if len(turn.Replies) > 0 {
+		replies := make([]string, len(turn.Replies))
+		for i, reply := range turn.Replies {
+			replies[i] = redactText(reply)
+		}
+		turn.Replies = replies
+	}
+	if len(turn.Errors) > 0 {
+		errs := make([]string, len(turn.Errors))
+		for i, e := range turn.Errors {
+			errs[i] = redactText(e)
+		}
+		turn.Errors = errs
+	}
+	if turn.Decision != nil {
+		decision := *turn.Decision
+		decision.Reasoning = redactText(decision.Reasoning)
+		turn.Decision = &decision
+	}
+	turn.Orchestrator = redactCall(turn.Orchestrator)
+	turn.SubAgent = redactCall(turn.SubAgent)
+	turn.Scoring = redactCall(turn.Scoring)
+	turn.Redacted = true
+	return turn
+}
+
+func redactCall(call *models.AgentCall) *models.AgentCall {
+	if call == nil {
+		return nil
+	}
+	redacted := *call
+	redacted.PromptText = redactText(call.PromptText)
+	redacted.RawOutput = redactText(call.RawOutput)
+	redacted.Error = redactText(call.Error)
+	return &redacted
+}
+
+func redactText(text string) string {
+	return RedactPII(text)
+}
+
+// turnUsage suma los tokens de las llamadas del turno; nil si ninguna informó consumo
+func turnUsage(turn *models.TurnTrace) *models.UsageTotals {
+	var totals *models.UsageTotals
+	for _, call := range []*models.AgentCall{turn.Orchestrator, turn.SubAgent, turn.Scoring} {
+		if call == nil || call.Usage == nil {
+			continue
+		}
+		if totals == nil {
+			totals = &models.UsageTotals{}
+		}
+		totals.Add(*call.Usage)
+	}
+	return totals
+}
+
+func withoutPrompt(call *models.AgentCall) *models.AgentCall {
+	if call == nil {
+		return nil
+	}
+	stripped := *call
+	stripped.PromptText = ""
+	return &stripped
+}
+
+func (t *TraceService) path(sessionID string) string {
+	return filepath.Join(t.dir, traceFileRegex.ReplaceAllString(sessionID, "_")+".jsonl")
+}
+
+func (t *TraceService) readTurns(path string) []models.TurnTrace {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var turns []models.TurnTrace
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024) // los prompts pueden ser largos
+	for scanner.Scan() {
+		var turn models.TurnTrace
+		if err := json.Unmarshal(scanner.Bytes(), &turn); err == nil {
+			turns = append(turns, turn)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error al leer trace %s: %v", path, err)
+	}
+	return turns
+}
+
+func (t *TraceService) rewrite(path string, turns []models.TurnTrace) {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.Printf("Error al recortar trace %s: %v", path, err)
+		return
+	}
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, turn := range turns {
+		encoder.Encode(turn)
+	}
+	err = w.Flush()
+	file.Close()
+	if err != nil {
+		log.Printf("Error al recortar trace %s: %v", path, err)
+		return
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("Error al recortar trace %s: %v", path, err)
+	}
+}
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"bob-hackathon/internal/config"
+	"bob-hackathon/internal/models"
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Motivos del modo económico
+const (
+	BudgetSession = "session"
+	BudgetDaily   = "daily"
+)
+
+// defaultModelPrices son los precios públicos de Gemini en USD por millón de tokens.
+// LLM_PRICES_JSON los reemplaza o agrega modelos.
+var defaultModelPrices = map[string]models.ModelPrice{
+	"gemini-2.5-pro":        {Prompt: 1.25, Completion: 10},
+	"gemini-2.5-flash":      {Prompt: 0.30, Completion: 2.50},
+	"gemini-2.5-flash-lite": {Prompt: 0.10, Completion: 0.40},
+	"gemini-2.0-flash":      {Prompt: 0.10, Completion: 0.40},
+	"gemini-2.0-flash-lite": {Prompt: 0.075, Completion: 0.30},
+	"gemini-1.5-flash":      {Prompt: 0.075, Completion: 0.30},
+}
+
+// UsageService registra los tokens de cada llamada al LLM, estima su costo y controla los
+// presupuestos por sesión (tokens) y por día (USD). Las llamadas se agregan a un archivo
+// JSONL; en memoria quedan las últimas USAGE_LOG_MAX y el costo de cada día.
+type UsageService struct {
+	records        []models.UsageRecord // de la más antigua a la más reciente
+	dailyCost      map[string]float64   // AAAA-MM-DD → USD
+	prices         map[string]models.ModelPrice
+	sessionLimit   int
+	dailyLimit     float64
+	budgetModel    string // modelo económico al superar un presupuesto
+	budgetHistory  int    // mensajes de historial que reciben los agentes en modo económico
+	maxEntries     int
+	dataFile       string
+	sessionService *SessionService
+	mu             sync.RWMutex
+}
+
+var usageServiceInstance *UsageService
+var usageServiceOnce sync.Once
+
+func GetUsageService() *UsageService {
+	usageServiceOnce.Do(func() {
+		maxEntries := config.AppConfig.UsageLogMax
+		if maxEntries <= 0 {
+			maxEntries = 50000
+		}
+
+		usageServiceInstance = &UsageService{
+			dailyCost:      make(map[string]float64),
+			prices:         loadModelPrices(config.AppConfig.LLMPricesJSON),
+			sessionLimit:   config.AppConfig.LLMSessionTokenBudget,
+			dailyLimit:     config.AppConfig.LLMDailyBudgetUSD,
+			budgetModel:    config.AppConfig.LLMBudgetModel,
+			budgetHistory:  config.AppConfig.LLMBudgetHistory,
+			maxEntries:     maxEntries,
+			dataFile:       filepath.Join("data", "llm_usage.jsonl"),
+			sessionService: GetSessionService(),
+		}
+		usageServiceInstance.loadFromDisk()
+	})
+	return usageServiceInstance
+}
+
+func loadModelPrices(raw string) map[string]models.ModelPrice {
+	prices := make(map[string]models.ModelPrice, len(defaultModelPrices))
+	for model, price := range defaultModelPrices {
+		prices[model] = price
+	}
+	if strings.TrimSpace(raw) == "" {
+		return prices
+	}
+
+	var custom map[string]models.ModelPrice
+	if err := json.Unmarshal([]byte(raw), &custom); err != nil {
+		log.Printf("⚠️ LLM_PRICES_JSON inválido, se usan los precios por defecto: %v", err)
+		return prices
+	}
+	for model, price := range custom {
+		prices[strings.ToLower(model)] = price
+	}
+	return prices
+}
+
+// Record estima el costo de la llamada y la suma a la sesión y al día. Lo que consumieron
+// otros modelos en la misma llamada (usage.Other) se registra aparte, con su precio; en
+// usage.CostUSD queda el costo total.
+func (u *UsageService) Record(sessionID, channel, agent string, usage *models.TokenUsage, degraded bool) {
+	if usage == nil {
+		return
+	}
+
+	parts := append([]models.TokenUsage{*usage}, usage.Other...)
+	parts[0].Other = nil
+
+	total := 0.0
+	for i := range parts {
+		parts[i].CostUSD = u.Cost(parts[i])
+		total += parts[i].CostUSD
+		if i > 0 {
+			usage.Other[i-1].CostUSD = parts[i].CostUSD
+		}
+	}
+	usage.CostUSD = total
+
+	now := time.Now()
+	u.mu.Lock()
+	for _, part := range parts {
+		record := models.UsageRecord{
+			ID:         uuid.New().String(),
+			SessionID:  sessionID,
+			Channel:    channel,
+			Agent:      agent,
+			Degraded:   degraded,
+			CreatedAt:  now,
+			TokenUsage: part,
+		}
+		u.records = append(u.records, record)
+		u.dailyCost[usageDay(now)] += part.CostUSD
+		u.appendToDisk(record)
+	}
+	if len(u.records) > u.maxEntries {
+		u.records = append([]models.UsageRecord(nil), u.records[len(u.records)-u.maxEntries:]...)
+	}
+	u.mu.Unlock()
+
+	if sessionID != "" {
+		for _, part := range parts {
+			u.sessionService.AddUsage(sessionID, part)
+		}
+	}
+}
+
+// Cost estima el costo en USD. Los tokens de razonamiento no vienen en CompletionTokens
+// pero se cobran como salida: se toma lo que el total excede al prompt.
+func (u *UsageService) Cost(usage models.TokenUsage) float64 {
+	price, ok := u.priceFor(usage.Model)
+	if !ok {
+		return 0
+	}
+
+	completion := usage.CompletionTokens
+	if billed := usage.TotalTokens - usage.PromptTokens; billed > completion {
+		completion = billed
+	}
+	return (float64(usage.PromptTokens)*price.Prompt + float64(completion)*price.Completion) / 1e6
+}
+
+// priceFor busca el modelo exacto o, si no está, el prefijo más largo
+// (ej. gemini-2.5-flash-preview-05-20 usa el precio de gemini-2.5-flash)
+func (u *UsageService) priceFor(model string) (models.ModelPrice, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if price, ok := u.prices[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for name := range u.prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return models.ModelPrice{}, false
+	}
+	return u.prices[best], true
+}
+
+// Prices devuelve la tabla de precios vigente
+func (u *UsageService) Prices() map[string]models.ModelPrice {
+	return u.prices
+}
+
+// Exceeded indica qué presupuesto se superó (session o daily), o vacío si ninguno.
+// Con presupuesto superado el chat usa el modelo económico y omite el scoring.
+func (u *UsageService) Exceeded(sessionID string) string {
+	status := u.Budget(sessionID)
+	switch {
+	case status.DailyExceeded:
+		return BudgetDaily
+	case status.SessionExceeded:
+		return BudgetSession
+	}
+	return ""
+}
+
+// BudgetModel es el modelo que usan los agentes en modo económico (vacío = el mismo)
+func (u *UsageService) BudgetModel() string {
+	return u.budgetModel
+}
+
+// TrimHistory deja los últimos mensajes de la conversación para achicar los prompts en modo económico
+func (u *UsageService) TrimHistory(messages []models.Message) []models.Message {
+	if u.budgetHistory <= 0 || len(messages) <= u.budgetHistory {
+		return messages
+	}
+	return messages[len(messages)-u.budgetHistory:]
+}
+
+// Budget devuelve los límites configurados y el consumo actual de la sesión y del día
+func (u *UsageService) Budget(sessionID string) models.BudgetStatus {
+	u.mu.RLock()
+	today := u.dailyCost[usageDay(time.Now())]
+	u.mu.RUnlock()
+
+	status := models.BudgetStatus{
+		SessionTokenLimit: u.sessionLimit,
+		DailyCostLimitUSD: u.dailyLimit,
+		TodayCostUSD:      round6(today),
+		DailyExceeded:     u.dailyLimit > 0 && today >= u.dailyLimit,
+	}
+	if sessionID != "" {
+		status.SessionTokens = u.sessionService.sessionUsage(sessionID).TotalTokens
+		status.SessionExceeded = u.sessionLimit > 0 && status.SessionTokens >= u.sessionLimit
+	}
+	return status
+}
+
+// Summary agrupa el consumo por agente, canal, modelo, día y sesión (las top más caras)
+func (u *UsageService) Summary(filter models.UsageFilter, top int) models.UsageSummary {
+	summary := models.UsageSummary{
+		ByAgent:     make(map[string]models.UsageTotals),
+		ByChannel:   make(map[string]models.UsageTotals),
+		ByModel:     make(map[string]models.UsageTotals),
+		ByDay:       []models.DailyUsage{},
+		TopSessions: []models.SessionUsage{},
+		Budget:      u.Budget(filter.SessionID),
+	}
+	if !filter.From.IsZero() {
+		summary.From = &filter.From
+	}
+	if !filter.To.IsZero() {
+		summary.To = &filter.To
+	}
+
+	byDay := make(map[string]*models.DailyUsage)
+	bySession := make(map[string]*models.SessionUsage)
+
+	u.mu.RLock()
+	for _, record := range u.records {
+		if !usageMatches(record, filter) {
+			continue
+		}
+
+		summary.Totals.Add(record.TokenUsage)
+		addUsageTo(summary.ByAgent, record.Agent, record.TokenUsage)
+		addUsageTo(summary.ByChannel, record.Channel, record.TokenUsage)
+		addUsageTo(summary.ByModel, record.Model, record.TokenUsage)
+
+		day := usageDay(record.CreatedAt)
+		if byDay[day] == nil {
+			byDay[day] = &models.DailyUsage{Day: day}
+		}
+		byDay[day].Add(record.TokenUsage)
+
+		if bySession[record.SessionID] == nil {
+			bySession[record.SessionID] = &models.SessionUsage{SessionID: record.SessionID, Channel: record.Channel}
+		}
+		bySession[record.SessionID].Add(record.TokenUsage)
+	}
+	u.mu.RUnlock()
+
+	summary.Totals.CostUSD = round6(summary.Totals.CostUSD)
+	for _, groups := range []map[string]models.UsageTotals{summary.ByAgent, summary.ByChannel, summary.ByModel} {
+		for key, totals := range groups {
+			totals.CostUSD = round6(totals.CostUSD)
+			groups[key] = totals
+		}
+	}
+	for _, day := range byDay {
+		day.CostUSD = round6(day.CostUSD)
+		summary.ByDay = append(summary.ByDay, *day)
+	}
+	sort.Slice(summary.ByDay, func(i, j int) bool { return summary.ByDay[i].Day < summary.ByDay[j].Day })
+
+	for _, session := range bySession {
+		session.CostUSD = round6(session.CostUSD)
+		summary.TopSessions = append(summary.TopSessions, *session)
+	}
+	sort.Slice(summary.TopSessions, func(i, j int) bool {
+		if summary.TopSessions[i].CostUSD != summary.TopSessions[j].CostUSD {
+			return summary.TopSessions[i].CostUSD > summary.TopSessions[j].CostUSD
+		}
+		return summary.TopSessions[i].TotalTokens > summary.TopSessions[j].TotalTokens
+	})
+	if top >= 0 && len(summary.TopSessions) > top {
+		summary.TopSessions = summary.TopSessions[:top]
+	}
+
+	return summary
+}
+
+func usageMatches(record models.UsageRecord, filter models.UsageFilter) bool {
+	if filter.Channel != "" && record.Channel != filter.Channel {
+		return false
+	}
+	if filter.SessionID != "" && record.SessionID != filter.SessionID {
+		return false
+	}
+	if filter.Agent != "" && !strings.EqualFold(record.Agent, filter.Agent) {
+		return false
+	}
+	if !filter.From.IsZero() && record.CreatedAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !record.CreatedAt.Before(filter.To) {
+		return false
+	}
+	return true
+}
+
+func addUsageTo(groups map[string]models.UsageTotals, key string, usage models.TokenUsage) {
+	totals := groups[key]
+	totals.Add(usage)
+	groups[key] = totals
+}
+
+func usageDay(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02")
+}
+
+func round6(value float64) float64 {
+	return float64(int64(value*1e6+0.5)) / 1e6
+}
+
+// AddUsage suma los tokens de una llamada al LLM a la sesión
+func (s *SessionService) AddUsage(sessionID string, usage models.TokenUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return
+	}
+	if session.Usage == nil {
+		session.Usage = &models.UsageTotals{}
+	}
+	session.Usage.Add(usage)
+
+	s.saveToDisk()
+}
+
+func (s *SessionService) sessionUsage(sessionID string) models.UsageTotals {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if session, exists := s.sessions[sessionID]; exists && session.Usage != nil {
+		return *session.Usage
+	}
+	return models.UsageTotals{}
+}
+
+func (u *UsageService) loadFromDisk() {
+	file, err := os.Open(u.dataFile)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record models.UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		lines++
+		u.dailyCost[usageDay(record.CreatedAt)] += record.CostUSD
+		u.records = append(u.records, record)
+		if len(u.records) > 2*u.maxEntries {
+			u.records = u.records[len(u.records)-u.maxEntries:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error al cargar consumo de LLM: %v", err)
+	}
+	if len(u.records) > u.maxEntries {
+		u.records = u.records[len(u.records)-u.maxEntries:]
+	}
+
+	// El archivo solo crece: compactarlo cuando duplica lo que se conserva
+	if lines > 2*u.maxEntries {
+		u.rewriteToDisk()
+	}
+	log.Printf("%d registros de consumo de LLM cargados desde disco", len(u.records))
+}
+
+func (u *UsageService) appendToDisk(record models.UsageRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(u.dataFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Error al guardar consumo de LLM: %v", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Printf("Error al guardar consumo de LLM: %v", err)
+	}
+}
+
+func (u *UsageService) rewriteToDisk() {
+	tmp := u.dataFile + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.Printf("Error al compactar consumo de LLM: %v", err)
+		return
+	}
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, record := range u.records {
+		encoder.Encode(record)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		log.Printf("Error al compactar consumo de LLM: %v", err)
+		return
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, u.dataFile); err != nil {
+		log.Printf("Error al compactar consumo de LLM: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// readXLSXRows lee la primera hoja de un libro XLSX como filas de texto.
+// Soporta strings compartidos, strings inline y números; ignora estilos y fórmulas.
+func readXLSXRows(filename string) ([][]string, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readXLSXSharedStrings(f); err != nil {
+			return nil, fmt.Errorf("sharedStrings: %w", err)
+		}
+	}
+
+	sheetPath, err := firstXLSXSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("hoja no encontrada en el libro: %s", sheetPath)
+	}
+
+	return readXLSXSheet(sheet, shared)
+}
+
+// firstXLSXSheet resuelve la ruta de la primera hoja a partir de workbook.xml y sus relaciones
+func firstXLSXSheet(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	wbFile, ok := files["xl/workbook.xml"]
+	relsFile, okRels := files["xl/_rels/workbook.xml.rels"]
+	if !ok || !okRels {
+		return fallback, nil
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(wbFile, &workbook); err != nil {
+		return "", fmt.Errorf("workbook: %w", err)
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(relsFile, &rels); err != nil {
+		return "", fmt.Errorf("workbook rels: %w", err)
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("el libro no tiene hojas")
+	}
+
+	for _, rel := range rels.Items {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+func decodeZipXML(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func readXLSXSharedStrings(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var shared []string
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "si" {
+			text, err := readXLSXText(dec, "si")
+			if err != nil {
+				return nil, err
+			}
+			shared = append(shared, text)
+		}
+	}
+}
+
+// readXLSXText concatena los <t> hasta cerrar el elemento (texto con formato viene en varios <r><t>)
+func readXLSXText(dec *xml.Decoder, until string) (string, error) {
+	var sb strings.Builder
+	inText, inPhonetic := false, false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "rPh": // guía fonética, no es parte del valor
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			case until:
+				return sb.String(), nil
+			}
+		case xml.CharData:
+			if inText && !inPhonetic {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+func readXLSXSheet(f *zip.File, shared []string) ([][]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var rows [][]string
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "row":
+			// Las filas vacías no se escriben en el XML: rellenar según el número de fila
+			if n, err := strconv.Atoi(xmlAttr(start, "r")); err == nil {
+				for len(rows) < n-1 {
+					rows = append(rows, nil)
+				}
+			}
+			rows = append(rows, nil)
+		case "c":
+			if len(rows) == 0 {
+				continue
+			}
+			value, err := readXLSXCell(dec, start, shared)
+			if err != nil {
+				return nil, err
+			}
+
+			row := &rows[len(rows)-1]
+			col := len(*row)
+			if ref := xmlAttr(start, "r"); ref != "" {
+				col = xlsxColumnIndex(ref)
+			}
+			for len(*row) <= col {
+				*row = append(*row, "")
+			}
+			(*row)[col] = value
+		}
+	}
+}
+
+func readXLSXCell(dec *xml.Decoder, start xml.StartElement, shared []string) (string, error) {
+	cellType := xmlAttr(start, "t")
+	if cellType == "inlineStr" {
+		return readXLSXText(dec, "c")
+	}
+
+	var raw string
+	inValue := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			inValue = t.Name.Local == "v"
+		case xml.CharData:
+			if inValue {
+				raw += string(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "v" {
+				inValue = false
+			}
+			if t.Name.Local != "c" {
+				continue
+			}
+
+			switch cellType {
+			case "s":
+				idx, err := strconv.Atoi(strings.TrimSpace(raw))
+				if err != nil || idx < 0 || idx >= len(shared) {
+					return "", fmt.Errorf("índice de string compartido inválido: %q", raw)
+				}
+				return shared[idx], nil
+			case "b":
+				if raw == "1" {
+					return "TRUE", nil
+				}
+				return "FALSE", nil
+			case "", "n":
+				// Excel guarda enteros grandes (DNI, teléfono) como 9.09206689E8
+				if f, err := strconv.ParseFloat(raw, 64); err == nil && strings.ContainsAny(raw, "eE") {
+					return strconv.FormatFloat(f, 'f', -1, 64), nil
+				}
+			}
+			return raw, nil
+		}
+	}
+}
+
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// xlsxColumnIndex convierte una referencia de celda en índice de columna: "A1" -> 0, "AB12" -> 27
+func xlsxColumnIndex(ref string) int {
+	idx := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		idx = idx*26 + int(r-'A'+1)
+	}
+	return idx - 1
+}
@@ -0,0 +1,254 @@
+package telemetry
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry agrupa las métricas que expone GET /metrics (formato Prometheus)
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_http_requests_total",
+		Help: "Requests HTTP atendidos, por método, ruta y código de respuesta.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bob_http_request_duration_seconds",
+		Help:    "Latencia de los requests HTTP, por método y ruta.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40},
+	}, []string{"method", "route"})
+
+	llmCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_llm_calls_total",
+		Help: "Llamadas al LLM por agente y resultado (ok, error).",
+	}, []string{"agent", "status"})
+
+	llmDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bob_llm_call_duration_seconds",
+		Help:    "Latencia de las llamadas al LLM por agente.",
+		Buckets: []float64{0.25, 0.5, 1, 2, 3, 5, 8, 13, 20, 30, 60},
+	}, []string{"agent"})
+
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_llm_tokens_total",
+		Help: "Tokens consumidos por agente y tipo (prompt, completion).",
+	}, []string{"agent", "type"})
+
+	llmRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_llm_retries_total",
+		Help: "Reintentos de llamadas al LLM por agente tras un error transitorio.",
+	}, []string{"agent"})
+
+	llmFallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_llm_fallbacks_total",
+		Help: "Llamadas al LLM que pasaron al modelo de respaldo, por agente y modelo de respaldo.",
+	}, []string{"agent", "model"})
+
+	llmCircuit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bob_llm_circuit_state",
+		Help: "Estado del circuit breaker por modelo: 0 cerrado, 1 abierto, 2 semiabierto.",
+	}, []string{"model"})
+
+	llmDegraded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_llm_degraded_total",
+		Help: "Respuestas armadas sin LLM (modo degradado), por agente.",
+	}, []string{"agent"})
+
+	intents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_orchestrator_intents_total",
+		Help: "Intenciones detectadas por el orchestrator (error si la llamada falló).",
+	}, []string{"intent"})
+
+	intentFastPath = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_intent_fastpath_total",
+		Help: "Mensajes resueltos sin LLM orchestrator (cache de FAQs, rules, model) o enviados al LLM (llm).",
+	}, []string{"result"})
+
+	scoringFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_scoring_failures_total",
+		Help: "Scorings fallidos por motivo (llm_error, parse_error).",
+	}, []string{"reason"})
+
+	faqRetrievals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_faq_retrievals_total",
+		Help: "Búsquedas de FAQs del FAQ agent por resultado (hit, miss).",
+	}, []string{"result"})
+
+	faqCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_faq_cache_requests_total",
+		Help: "Consultas al cache de respuestas del FAQ agent por resultado (hit, miss).",
+	}, []string{"result"})
+
+	bobAPICache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_api_cache_requests_total",
+		Help: "Consultas al cache de vehículos de la API BOB por resultado (hit, miss).",
+	}, []string{"result"})
+
+	guardrails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_guardrail_events_total",
+		Help: "Reglas de guardrails disparadas por dirección (input, output), regla y acción.",
+	}, []string{"direction", "rule", "action"})
+
+	piiDetections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_pii_detections_total",
+		Help: "Datos personales encontrados en mensajes de usuarios por tipo (email, telefono, dni, ruc, placa, tarjeta).",
+	}, []string{"type"})
+
+	authRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_auth_requests_total",
+		Help: "Requests a rutas protegidas por método (api_key, jwt, none) y resultado (ok, missing, invalid, forbidden).",
+	}, []string{"method", "result"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_rate_limited_total",
+		Help: "Mensajes al chat rechazados con 429 por alcance del límite (session, ip, key).",
+	}, []string{"scope"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		llmCalls, llmDuration, llmTokens,
+		llmRetries, llmFallbacks, llmCircuit, llmDegraded,
+		intents, intentFastPath, scoringFailures, faqRetrievals, faqCache, bobAPICache,
+		guardrails, piiDetections, authRequests, rateLimited,
+	)
+
+	// Series en 0 desde el arranque para que las tasas y alertas no queden vacías
+	for _, result := range []string{"hit", "miss"} {
+		faqRetrievals.WithLabelValues(result)
+		faqCache.WithLabelValues(result)
+		bobAPICache.WithLabelValues(result)
+	}
+	for _, result := range []string{"cache", "rules", "model", "llm"} {
+		intentFastPath.WithLabelValues(result)
+	}
+	for _, reason := range []string{"llm_error", "parse_error"} {
+		scoringFailures.WithLabelValues(reason)
+	}
+}
+
+// MetricsMiddleware mide cada request con la ruta registrada en gin (ej. /api/leads/:sessionId),
+// no la URL concreta, para no crear una serie por sesión
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := ctx.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler sirve el Registry para GET /metrics
+func MetricsHandler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// ObserveLLMCall registra una llamada al LLM; los tokens vienen del usage de la respuesta
+func ObserveLLMCall(agent string, elapsed time.Duration, err error, promptTokens, completionTokens int) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	llmCalls.WithLabelValues(agent, status).Inc()
+	llmDuration.WithLabelValues(agent).Observe(elapsed.Seconds())
+	if promptTokens > 0 {
+		llmTokens.WithLabelValues(agent, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		llmTokens.WithLabelValues(agent, "completion").Add(float64(completionTokens))
+	}
+}
+
+// CountLLMRetry suma un reintento de llamada al LLM
+func CountLLMRetry(agent string) {
+	llmRetries.WithLabelValues(agent).Inc()
+}
+
+// CountLLMFallback suma una llamada que pasó al modelo de respaldo
+func CountLLMFallback(agent, model string) {
+	llmFallbacks.WithLabelValues(agent, model).Inc()
+}
+
+// SetLLMCircuitState publica el estado del circuit breaker de un modelo
+// (0 cerrado, 1 abierto, 2 semiabierto)
+func SetLLMCircuitState(model string, state int) {
+	llmCircuit.WithLabelValues(model).Set(float64(state))
+}
+
+// CountLLMDegraded suma una respuesta armada sin LLM
+func CountLLMDegraded(agent string) {
+	llmDegraded.WithLabelValues(agent).Inc()
+}
+
+// CountIntent suma una decisión del orchestrator
+func CountIntent(intent string) {
+	intents.WithLabelValues(intent).Inc()
+}
+
+// CountIntentFastPath suma un mensaje según quién decidió su intención
+func CountIntentFastPath(result string) {
+	intentFastPath.WithLabelValues(result).Inc()
+}
+
+// CountScoringFailure suma un scoring que no se pudo calcular
+func CountScoringFailure(reason string) {
+	scoringFailures.WithLabelValues(reason).Inc()
+}
+
+// CountFAQRetrieval suma una búsqueda de FAQs, con o sin resultados
+func CountFAQRetrieval(hit bool) {
+	faqRetrievals.WithLabelValues(hitLabel(hit)).Inc()
+}
+
+// CountFAQCache suma una consulta al cache de respuestas de FAQs
+func CountFAQCache(hit bool) {
+	faqCache.WithLabelValues(hitLabel(hit)).Inc()
+}
+
+// CountBOBAPICache suma una consulta al cache de vehículos
+func CountBOBAPICache(hit bool) {
+	bobAPICache.WithLabelValues(hitLabel(hit)).Inc()
+}
+
+// CountGuardrail suma una regla de guardrail disparada
+func CountGuardrail(direction, rule, action string) {
+	guardrails.WithLabelValues(direction, rule, action).Inc()
+}
+
+// CountAuth suma un request a una ruta protegida
+func CountAuth(method, result string) {
+	authRequests.WithLabelValues(method, result).Inc()
+}
+
+// CountRateLimited suma un mensaje rechazado por el límite de mensajes
+func CountRateLimited(scope string) {
+	rateLimited.WithLabelValues(scope).Inc()
+}
+
+// CountPII suma un dato personal encontrado en un mensaje
+func CountPII(kind string) {
+	piiDetections.WithLabelValues(kind).Inc()
+}
+
+func hitLabel(hit bool) string {
+	if hit {
+		return "hit"
+	}
+	return "miss"
+}
@@ -0,0 +1,117 @@
+// Package telemetry configura OpenTelemetry para el backend: spans de HTTP (gin), agentes y
+// llamadas a Gemini, con propagación W3C (traceparent) desde el bot de WhatsApp.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exportadores soportados (OTEL_TRACES_EXPORTER)
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"   // OTLP/HTTP; destino en OTEL_EXPORTER_OTLP_ENDPOINT (por defecto localhost:4318)
+	ExporterStdout = "stdout" // spans en JSON por consola, para desarrollo
+)
+
+// Atributos comunes de los spans
+const (
+	AttrSessionID  = attribute.Key("bob.session.id")
+	AttrChannel    = attribute.Key("bob.channel")
+	AttrAgent      = attribute.Key("bob.agent.name")
+	AttrIntent     = attribute.Key("bob.intent")
+	AttrConfidence = attribute.Key("bob.confidence")
+	AttrRouteTo    = attribute.Key("bob.route_to")
+	AttrPrompt     = attribute.Key("bob.prompt")
+	AttrRetrieved  = attribute.Key("bob.retrieved")
+	AttrScore      = attribute.Key("bob.score")
+	AttrCategory   = attribute.Key("bob.category")
+	AttrModel      = attribute.Key("bob.llm.model")
+	AttrTokens     = attribute.Key("bob.llm.tokens")
+)
+
+const tracerName = "bob-hackathon"
+
+// Init registra el TracerProvider global y el propagador W3C. Devuelve la función que
+// vacía los spans pendientes al apagar el servidor. Con exporter "none" solo se propaga
+// el contexto: los spans no se graban.
+func Init(ctx context.Context, serviceName, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(strings.TrimSpace(exporter)) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("exporter de trazas inválido: %s (válidos: none, otlp, stdout)", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("🔭 OpenTelemetry activo: servicio %s, exporter %s", serviceName, exporter)
+
+	return provider.Shutdown, nil
+}
+
+// Start abre un span hijo del que venga en ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Annotate agrega atributos al span activo (ej. el span HTTP que abre otelgin)
+func Annotate(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+// RecordError marca el span como fallido
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// TraceID devuelve el trace id del span activo, o vacío si no hay uno grabándose
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
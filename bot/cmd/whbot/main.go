@@ -8,6 +8,7 @@ import (
 
 	"github.com/investigadorinexperto/bot/engine"
 	"github.com/investigadorinexperto/bot/internal/config"
+	"github.com/investigadorinexperto/bot/internal/telemetry"
 	"github.com/joho/godotenv"
 	"go.mau.fi/whatsmeow/types/events"
 )
@@ -16,6 +17,16 @@ func main() {
 	_ = godotenv.Load("/home/ivnx/labs/bob-hackathon/bot/.env")
 	cfgApp := config.Load()
 
+	// ====== Trazas: cada envelope reenviado al webhook abre una traza nueva
+	serviceName := cfgApp.OTelServiceName
+	if serviceName == "" {
+		serviceName = "whbot"
+	}
+	shutdownTelemetry, err := telemetry.Init(context.Background(), serviceName, cfgApp.OTelTracesExporter)
+	if err != nil {
+		log.Fatalf("otel init error: %v", err)
+	}
+
 	// ====== Mapear config → engine.Config
 	// Forward mode: "folder" (on) o "off" (solo webhook si está habilitado)
 	forwardMode := engine.ForwardOff
@@ -78,4 +89,5 @@ func main() {
 	if err := e.Run(context.Background(), h); err != nil {
 		log.Fatalf("engine run error: %v", err)
 	}
+	_ = shutdownTelemetry(context.Background())
 }
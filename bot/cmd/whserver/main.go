@@ -23,9 +23,13 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/investigadorinexperto/bot/internal/config"
+	"github.com/investigadorinexperto/bot/internal/telemetry"
 	"github.com/investigadorinexperto/bot/pkg/filters"
+	"github.com/investigadorinexperto/bot/pkg/pii"
 	"github.com/investigadorinexperto/bot/pkg/pipeline"
 	"github.com/investigadorinexperto/bot/pkg/rules"
 )
@@ -142,8 +146,9 @@ func (l jlog) Error(msg string, kv ...any) { l.kv("error", msg, kv...) }
 
 const maxLogText = 120
 
+// previewText recorta el texto para el log, sin datos personales
 func previewText(s string, max int) string {
-	s = strings.TrimSpace(s)
+	s = pii.Redact(strings.TrimSpace(s))
 	if max <= 0 {
 		return s
 	}
@@ -266,6 +271,7 @@ type SimpleRouter struct {
 	aggregator       *pipeline.Aggregator
 	muLast           sync.Mutex
 	lastByChat       map[string]rules.Envelope
+	lastSpanByChat   map[string]trace.SpanContext // traza del último mensaje; el flush la continúa
 	lastActiveChat   string
 	muMap            sync.Mutex
 	lastChatBySender map[string]string
@@ -303,6 +309,7 @@ func NewSimpleRouter(
 		jitterMs:         jitterMs,
 		maxWait:          maxWait,
 		lastByChat:       make(map[string]rules.Envelope),
+		lastSpanByChat:   make(map[string]trace.SpanContext),
 		lastChatBySender: make(map[string]string),
 		lastTypingAt:     make(map[string]time.Time),
 		typingDebounce:   700 * time.Millisecond,
@@ -382,6 +389,7 @@ func (r *SimpleRouter) OnMessage(ctx context.Context, e Envelope) {
 	}
 	r.muLast.Lock()
 	r.lastByChat[e.ChatJID] = env
+	r.lastSpanByChat[e.ChatJID] = trace.SpanContextFromContext(ctx)
 	r.muLast.Unlock()
 
 	// 7) Log
@@ -584,6 +592,8 @@ func loadProfileFromDisk(chatJID string) (*Profile, error) {
 	if err := json.Unmarshal(b, &p); err != nil {
 		return nil, err
 	}
+	// Perfiles guardados antes de redactar last_text
+	p.LastText = pii.Redact(p.LastText)
 	return &p, nil
 }
 func persistProfileSnapshotByChat(p *Profile, chatJID string) {
@@ -780,7 +790,7 @@ func (r *SimpleRouter) touchProfileFromInbound(e Envelope) {
 
 	p.LastConn = now
 	p.LastChat = e.ChatJID
-	p.LastText = e.Text
+	p.LastText = pii.Redact(e.Text)
 	p.Metrics.MsgIn++
 	p.Metrics.LastMsgAt = now
 	p.Metrics.LastMsgID = e.MessageID
@@ -835,9 +845,19 @@ func (r *SimpleRouter) incOutboundFor(chatKey string) {
 // =======================
 //
 
-func callBOBBackend(fromPhone string, message string, logger jlog) string {
+// callBOBBackend envía el mensaje al backend y devuelve la respuesta ya partida
+// en mensajes de WhatsApp (campo "replies"); si no viene, usa "reply" completo.
+// Si la sesión está en modo humano devuelve nil: responde el especialista.
+// El contexto de traza viaja en traceparent para que el backend cuelgue sus spans
+// (orchestrator, agentes) de la misma traza que abrió el engine.
+func callBOBBackend(ctx context.Context, fromPhone string, message string, apiKey string, logger jlog) []string {
 	sessionId := "wa-" + fromPhone
 
+	ctx, span := telemetry.Start(ctx, "bob.chat.message", trace.SpanKindClient,
+		telemetry.AttrSessionID.String(sessionId),
+	)
+	defer span.End()
+
 	payload := map[string]string{
 		"sessionId": sessionId,
 		"message":   message,
@@ -845,39 +865,74 @@ func callBOBBackend(fromPhone string, message string, logger jlog) string {
 	}
 	jsonData, _ := json.Marshal(payload)
 
-	resp, err := http.Post(
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
 		"http://localhost:3000/api/chat/message",
-		"application/json",
 		bytes.NewBuffer(jsonData),
 	)
 	if err != nil {
+		telemetry.RecordError(span, err)
+		logger.Warn("bob_backend_error", "err", err)
+		return []string{"Lo siento, hubo un error procesando tu mensaje."}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// API key de integración (rol channel) cuando el backend tiene AUTH_ENABLED=true
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	telemetry.Inject(ctx, req.Header)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		telemetry.RecordError(span, err)
 		logger.Warn("bob_backend_error", "err", err)
-		return "Lo siento, hubo un error procesando tu mensaje."
+		return []string{"Lo siento, hubo un error procesando tu mensaje."}
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	// Con 429 el backend igual manda en "reply" el aviso para el usuario
+	if resp.StatusCode == http.StatusTooManyRequests {
+		logger.Warn("bob_backend_rate_limited", "from", fromPhone, "retry_after", resp.Header.Get("Retry-After"))
+	}
 
-	var result map[string]interface{}
+	var result struct {
+		Reply     string   `json:"reply"`
+		Replies   []string `json:"replies"`
+		LeadScore int      `json:"leadScore"`
+		Category  string   `json:"category"`
+		HumanMode bool     `json:"humanMode"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		telemetry.RecordError(span, err)
 		logger.Warn("bob_backend_decode_error", "err", err)
-		return "Error procesando la respuesta."
-	}
-
-	if reply, ok := result["reply"].(string); ok {
-		// Log del lead score si viene
-		if score, ok2 := result["leadScore"].(float64); ok2 {
-			if category, ok3 := result["category"].(string); ok3 {
-				logger.Info("bob_backend_reply",
-					"from", fromPhone,
-					"score", int(score),
-					"category", category,
-					"reply_len", len([]rune(reply)),
-				)
-			}
+		return []string{"Error procesando la respuesta."}
+	}
+	if result.HumanMode {
+		logger.Info("bob_backend_human_mode", "from", fromPhone)
+		return nil
+	}
+
+	replies := make([]string, 0, len(result.Replies))
+	for _, r := range result.Replies {
+		if strings.TrimSpace(r) != "" {
+			replies = append(replies, r)
 		}
-		return reply
+	}
+	if len(replies) == 0 && strings.TrimSpace(result.Reply) != "" {
+		replies = []string{result.Reply}
+	}
+	if len(replies) == 0 {
+		return []string{"No se pudo obtener respuesta del sistema."}
 	}
 
-	return "No se pudo obtener respuesta del sistema."
+	// Log del lead score
+	logger.Info("bob_backend_reply",
+		"from", fromPhone,
+		"score", result.LeadScore,
+		"category", result.Category,
+		"parts", len(replies),
+		"reply_len", len([]rune(strings.Join(replies, ""))),
+	)
+	return replies
 }
 
 //
@@ -1003,6 +1058,17 @@ func main() {
 
 	logger := jlog{json: logJSON}
 
+	// Trazas: continúan la traza que abre el engine (traceparent en el webhook)
+	serviceName := cfg.OTelServiceName
+	if serviceName == "" {
+		serviceName = "whserver"
+	}
+	shutdownTelemetry, err := telemetry.Init(context.Background(), serviceName, cfg.OTelTracesExporter)
+	if err != nil {
+		logger.Error("otel_init_error", "err", err)
+		os.Exit(1)
+	}
+
 	if requireSig && secret == "" && !allowNoSecretDev {
 		logger.Error("WH_WEBHOOK_SECRET required (prod mode)")
 		os.Exit(1)
@@ -1057,22 +1123,39 @@ func main() {
 		// Recupera el último envelope memorizado
 		router.muLast.Lock()
 		env, ok := router.lastByChat[chat]
+		parent := router.lastSpanByChat[chat]
 		router.muLast.Unlock()
 
+		// El flush cuelga de la traza del último mensaje de la ventana
+		ctx, span := telemetry.Start(telemetry.Link(parent), "aggregator.flush", trace.SpanKindInternal,
+			telemetry.AttrChatJID.String(chat),
+			telemetry.AttrCount.Int(count),
+		)
+		defer span.End()
+
 		if ok && strings.TrimSpace(env.Text) != "" {
 			// Llamar al backend BOB de Kevin en vez del engine de reglas
-			reply := callBOBBackend(env.SenderJID, env.Text, logger)
-
-			if strings.TrimSpace(reply) != "" {
-				wait := router.replyWithTyping(chat, reply)
-				logger.Info("reply_bob_backend",
-					"chat", chat,
-					"count", count,
-					"reply_len", len([]rune(reply)),
-					"reply_preview", previewText(reply, maxLogText),
-					"t_pre_delay_ms", cfg.PreReplyDelay.Milliseconds(),
-					"t_typing_ms", wait.Milliseconds(),
-				)
+			replies := callBOBBackend(ctx, env.SenderJID, env.Text, cfg.BOBBackendAPIKey, logger)
+			if replies == nil {
+				// Modo humano: el especialista responde desde el backend
+				return
+			}
+
+			if len(replies) > 0 {
+				// Cada parte se envía como un mensaje separado, con su propio "escribiendo..."
+				for i, reply := range replies {
+					wait := router.replyWithTyping(chat, reply)
+					logger.Info("reply_bob_backend",
+						"chat", chat,
+						"count", count,
+						"part", i+1,
+						"parts", len(replies),
+						"reply_len", len([]rune(reply)),
+						"reply_preview", previewText(reply, maxLogText),
+						"t_pre_delay_ms", cfg.PreReplyDelay.Milliseconds(),
+						"t_typing_ms", wait.Milliseconds(),
+					)
+				}
 				return
 			}
 		}
@@ -1174,6 +1257,15 @@ func main() {
 			return
 		}
 
+		// Continúa la traza del engine (traceparent viaja junto a X-Whatsbot-*)
+		ctx, span := telemetry.Start(telemetry.Extract(r.Context(), r.Header), "webhook.receive", trace.SpanKindConsumer,
+			telemetry.AttrEventType.String(strings.TrimSpace(env.EventType)),
+			telemetry.AttrDirection.String(strings.TrimSpace(env.Direction)),
+			telemetry.AttrChatJID.String(env.ChatJID),
+			telemetry.AttrMessageID.String(strings.TrimSpace(env.MessageID)),
+		)
+		defer span.End()
+
 		// Log básico del evento
 		logger.Info(
 			"event",
@@ -1186,6 +1278,7 @@ func main() {
 
 		// Dedupe por message_id (solo para mensajes reales)
 		if env.EventType == "message" && ded.Seen(env.MessageID) {
+			span.SetAttributes(attribute.Bool("whatsapp.duplicate", true))
 			w.WriteHeader(http.StatusOK)
 			_, _ = w.Write([]byte(`{"ok":true,"dup":true}`))
 			return
@@ -1196,8 +1289,14 @@ func main() {
 		_, _ = w.Write([]byte(`{"ok":true}`))
 
 		// Procesamiento async
-		go func(e Envelope) {
-			ctx := context.Background()
+		go func(e Envelope, parent trace.SpanContext) {
+			// El request ya respondió: se sigue la traza sin heredar su cancelación
+			ctx, span := telemetry.Start(telemetry.Link(parent), "webhook.process", trace.SpanKindInternal,
+				telemetry.AttrEventType.String(strings.TrimSpace(e.EventType)),
+				telemetry.AttrChatJID.String(e.ChatJID),
+			)
+			defer span.End()
+
 			if e.EventType == "message" && !strings.EqualFold(e.Direction, "out") && strings.TrimSpace(e.MessageID) != "" {
 				mrStart := time.Now()
 				var err error
@@ -1221,7 +1320,7 @@ func main() {
 			default:
 				router.OnAny(ctx, e)
 			}
-		}(env)
+		}(env, trace.SpanContextFromContext(ctx))
 	})
 
 	// Server + graceful shutdown
@@ -1248,5 +1347,6 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_ = srv.Shutdown(ctx)
+	_ = shutdownTelemetry(ctx)
 	logger.Info("graceful shutdown complete")
 }
@@ -29,9 +29,13 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/mdp/qrterminal"
 
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/term"
 	"golang.org/x/time/rate"
 
+	"github.com/investigadorinexperto/bot/internal/telemetry"
+	"github.com/investigadorinexperto/bot/pkg/pii"
+
 	// WhatsMeow core
 	wm "go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/binary/proto"
@@ -625,6 +629,8 @@ func (e *Engine) postJSONWithRetry(ctx context.Context, url string, secret strin
 		if sig := e.signBodyHMACSHA256(secret, body); sig != "" {
 			req.Header.Set("X-Whatsbot-Signature", sig)
 		}
+		// traceparent: whserver continúa la traza de este envelope
+		telemetry.Inject(ctx, req.Header)
 
 		resp, err := client.Do(req)
 		if err != nil {
@@ -651,20 +657,33 @@ func (e *Engine) sendEnvelopeToWebhook(_ context.Context, env *ForwardEnvelope)
 		return
 	}
 	go func(en ForwardEnvelope) {
+		// Raíz de la traza del mensaje: engine -> whserver -> backend BOB
+		ctx, span := telemetry.Start(context.Background(), "webhook.forward", trace.SpanKindProducer,
+			telemetry.AttrEventType.String(en.EventType),
+			telemetry.AttrDirection.String(en.Direction),
+			telemetry.AttrChatJID.String(en.ChatJID),
+			telemetry.AttrMessageID.String(en.MessageID),
+		)
+		defer span.End()
+
 		b, err := e.marshalEnvelopeForIO(&en)
 		if err != nil {
+			telemetry.RecordError(span, err)
 			if e.logger != nil {
 				e.logger.Warnf("marshal envelope failed: %v", err)
 			}
 			return
 		}
-		if err := e.postJSONWithRetry(context.Background(),
+		if err := e.postJSONWithRetry(ctx,
 			e.cfg.Forward.Webhook.URL,
 			e.cfg.Forward.Webhook.Secret,
 			e.cfg.Forward.Webhook.Headers,
 			json.RawMessage(b),
-		); err != nil && e.logger != nil {
-			e.logger.Warnf("webhook post failed: %v", err)
+		); err != nil {
+			telemetry.RecordError(span, err)
+			if e.logger != nil {
+				e.logger.Warnf("webhook post failed: %v", err)
+			}
 		}
 	}(*env)
 }
@@ -971,11 +990,11 @@ func (e *Engine) RunEventLoop(ctx context.Context, h Handlers) {
 				} else if mt != "" {
 					e.humanInfof(prefix+"[%s] Chat:%s | De:%s | ID:%s | MEDIA:%s | Caption:\"%s\"",
 						k, colorize(ansiBold, env.ChatJID), colorize(ansiBold, who),
-						env.MessageID, mt, short(txt, 60))
+						env.MessageID, mt, short(pii.Redact(txt), 60))
 				} else {
 					e.humanInfof(prefix+"[%s] Chat:%s | De:%s | ID:%s | Texto:\"%s\"",
 						k, colorize(ansiBold, env.ChatJID), colorize(ansiBold, who),
-						env.MessageID, short(txt, 80))
+						env.MessageID, short(pii.Redact(txt), 80))
 				}
 			}
 
@@ -1147,7 +1166,7 @@ func (e *Engine) SendText(ctx context.Context, to types.JID, text string) (strin
 		// Log OUT
 		k := kindOfChat(to)
 		prefix := colorize(ansiOUT, "[OUT]") + " "
-		e.humanInfof(prefix+"[%s] To:%s | ID:%s | Texto:\"%s\"", k, colorize(ansiBold, to.String()), id, short(text, 80))
+		e.humanInfof(prefix+"[%s] To:%s | ID:%s | Texto:\"%s\"", k, colorize(ansiBold, to.String()), id, short(pii.Redact(text), 80))
 
 		// Persistencia OUT
 		if e.msgStore != nil {
@@ -1251,7 +1270,7 @@ func (e *Engine) SendMedia(ctx context.Context, to types.JID, in MediaInput) (st
 		// Log OUT
 		k := kindOfChat(to)
 		prefix := colorize(ansiOUT, "[OUT]") + " "
-		e.humanInfof(prefix+"[%s] To:%s | ID:%s | MEDIA:%s | Caption:\"%s\"", k, colorize(ansiBold, to.String()), id, mt, short(in.Caption, 60))
+		e.humanInfof(prefix+"[%s] To:%s | ID:%s | MEDIA:%s | Caption:\"%s\"", k, colorize(ansiBold, to.String()), id, mt, short(pii.Redact(in.Caption), 60))
 
 		// Persistencia OUT
 		if e.msgStore != nil {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -55,6 +56,9 @@ type AppConfig struct {
 	ServerEngineTypingURL   string // WH_ENGINE_TYPING_URL
 	ServerEngineMarkReadURL string // WH_ENGINE_MARKREAD_URL   <-- NUEVO
 
+	// API key de integración (rol channel) para el backend con AUTH_ENABLED=true
+	BOBBackendAPIKey string // BOB_BACKEND_API_KEY
+
 	// ===== Reply typing wait (tunable por .env) =====
 	ReplyBaseWait  time.Duration
 	ReplyPerCharMs int
@@ -62,6 +66,10 @@ type AppConfig struct {
 	ReplyMaxWait   time.Duration
 	PreReplyDelay  time.Duration
 	AggWindow      time.Duration
+
+	// ===== Trazas (OpenTelemetry) =====
+	OTelServiceName    string // OTEL_SERVICE_NAME; vacío = nombre del binario (whbot / whserver)
+	OTelTracesExporter string // OTEL_TRACES_EXPORTER: none, otlp o stdout
 }
 
 // ---------- helpers ----------
@@ -156,6 +164,9 @@ func Load() *AppConfig {
 		ServerEngineTypingURL:   getenv("WH_ENGINE_TYPING_URL", base+"/api/typing"),
 		ServerEngineMarkReadURL: getenv("WH_ENGINE_MARKREAD_URL", base+"/api/markread"),
 
+		// Backend de BOB
+		BOBBackendAPIKey: strings.TrimSpace(getenv("BOB_BACKEND_API_KEY", "")),
+
 		// ===== Reply typing wait =====
 		ReplyBaseWait:  getenvDur("WH_REPLY_BASE_WAIT", "400ms"),
 		ReplyPerCharMs: getenvInt("WH_REPLY_PER_CHAR_MS", 35),
@@ -163,5 +174,9 @@ func Load() *AppConfig {
 		ReplyMaxWait:   getenvDur("WH_REPLY_MAX_WAIT", "4s"),
 		PreReplyDelay:  getenvDur("WH_PRE_REPLY_DELAY", "900ms"),
 		AggWindow:      getenvDur("WH_AGGREGATOR_WINDOW", "2s"),
+
+		// ===== Trazas =====
+		OTelServiceName:    getenv("OTEL_SERVICE_NAME", ""),
+		OTelTracesExporter: getenv("OTEL_TRACES_EXPORTER", "none"),
 	}
 }
@@ -0,0 +1,119 @@
+// internal/telemetry/telemetry.go
+//
+// Trazas OpenTelemetry del bot: el engine (whbot) abre un span por cada envelope que
+// reenvía al webhook y propaga el contexto W3C (traceparent) junto a los headers
+// X-Whatsbot-*; whserver continúa esa traza hasta la llamada al backend de BOB.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exportadores soportados (OTEL_TRACES_EXPORTER)
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"   // OTLP/HTTP; destino en OTEL_EXPORTER_OTLP_ENDPOINT (por defecto localhost:4318)
+	ExporterStdout = "stdout" // spans en JSON por consola, para desarrollo
+)
+
+// Atributos comunes (mismas claves que el backend para poder cruzar trazas)
+const (
+	AttrSessionID = attribute.Key("bob.session.id")
+	AttrChatJID   = attribute.Key("whatsapp.chat.jid")
+	AttrMessageID = attribute.Key("whatsapp.message.id")
+	AttrEventType = attribute.Key("whatsapp.event.type")
+	AttrDirection = attribute.Key("whatsapp.direction")
+	AttrCount     = attribute.Key("whatsapp.aggregated.count")
+)
+
+const tracerName = "github.com/investigadorinexperto/bot"
+
+// Init registra el TracerProvider global y el propagador W3C. Devuelve la función que
+// vacía los spans pendientes al apagar. Con exporter "none" solo se propaga el contexto.
+func Init(ctx context.Context, serviceName, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(strings.TrimSpace(exporter)) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("invalid OTEL_TRACES_EXPORTER %q (none, otlp, stdout)", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("🔭 OpenTelemetry: service=%s exporter=%s", serviceName, exporter)
+
+	return provider.Shutdown, nil
+}
+
+// Start abre un span hijo del que venga en ctx
+func Start(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// Inject escribe traceparent/tracestate en los headers salientes
+func Inject(ctx context.Context, h http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// Extract recupera el contexto de traza que viene en los headers entrantes
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// Link devuelve un contexto sin cancelación que continúa el span de parent. Sirve para
+// seguir la traza desde goroutines que sobreviven al request (aggregator, async handlers).
+func Link(parent trace.SpanContext) context.Context {
+	if !parent.IsValid() {
+		return context.Background()
+	}
+	return trace.ContextWithSpanContext(context.Background(), parent)
+}
+
+// RecordError marca el span como fallido
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
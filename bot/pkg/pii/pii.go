@@ -0,0 +1,101 @@
+// Package pii redacta datos personales de Perú (DNI, RUC, teléfonos +51, emails, placas,
+// tarjetas) en los textos que el bot deja en logs y perfiles. Usa los mismos formatos que
+// el detector del backend.
+package pii
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type match struct {
+	kind       string
+	start, end int
+}
+
+// Los detectores van en orden de prioridad: un texto ya tomado no se evalúa con los siguientes
+var detectors = []struct {
+	kind  string
+	regex *regexp.Regexp
+}{
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{"tarjeta", regexp.MustCompile(`\b(?:\d{4}[ -]?){3}\d{1,7}\b|\b\d{13,19}\b`)},
+	{"ruc", regexp.MustCompile(`\b(?:10|15|16|17|20)\d{9}\b`)},
+	{"telefono", regexp.MustCompile(`(?:\+51[\s.-]?|\b51[\s.-]?|\b)9\d{2}[\s.-]?\d{3}[\s.-]?\d{3}\b|\+51[\s.-]?\(?0?1\)?[\s.-]?\d{3}[\s.-]?\d{4}\b|\(0\d{1,2}\)[\s.-]?\d{3}[\s.-]?\d{3,4}\b`)},
+	{"dni", regexp.MustCompile(`\b\d{8}\b`)},
+	{"placa", regexp.MustCompile(`(?i)\b[a-z][a-z0-9]{2}-?\d{3}\b`)},
+}
+
+var (
+	nonDigits        = regexp.MustCompile(`\D`)
+	notPlatePrefixes = []string{"usd", "pen", "sol", "eur", "kms"}
+)
+
+// Redact reemplaza cada dato personal por su tipo: "mi dni es 12345678" → "mi dni es [dni]"
+func Redact(text string) string {
+	var matches []match
+	for _, d := range detectors {
+		for _, loc := range d.regex.FindAllStringIndex(text, -1) {
+			if overlaps(matches, loc[0], loc[1]) || !valid(d.kind, text[loc[0]:loc[1]]) {
+				continue
+			}
+			matches = append(matches, match{d.kind, loc[0], loc[1]})
+		}
+	}
+	if len(matches) == 0 {
+		return text
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.start])
+		b.WriteString("[" + m.kind + "]")
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func overlaps(matches []match, start, end int) bool {
+	for _, m := range matches {
+		if start < m.end && end > m.start {
+			return true
+		}
+	}
+	return false
+}
+
+func valid(kind, value string) bool {
+	switch kind {
+	case "tarjeta":
+		return luhn(nonDigits.ReplaceAllString(value, ""))
+	case "placa":
+		lower := strings.ToLower(value)
+		for _, prefix := range notPlatePrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func luhn(digits string) bool {
+	if len(digits) < 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,32 @@
+package pii
+
+import "testing"
+
+func TestRedactFormats(t *testing.T) {
+	cases := []struct {
+		name, text, want string
+	}{
+		{"celular", "llamame al 987654321", "llamame al [telefono]"},
+		{"celular con espacios", "mi cel 987 654 321", "mi cel [telefono]"},
+		{"+51 con espacio", "escribe al +51 987654321", "escribe al [telefono]"},
+		{"+51 pegado (E.164)", "escribe al +51987654321", "escribe al [telefono]"},
+		{"51 pegado (whatsapp)", "mi numero es 51987654321", "mi numero es [telefono]"},
+		{"fijo de lima con +51", "oficina +51 1 234 5678", "oficina [telefono]"},
+		{"fijo con codigo de ciudad", "fijo (01) 234-5678", "fijo [telefono]"},
+		{"email", "correo ana@mail.com", "correo [email]"},
+		{"dni", "mi dni es 12345678", "mi dni es [dni]"},
+		{"ruc", "ruc 20123456789", "ruc [ruc]"},
+		{"placa", "la placa es ABC-123", "la placa es [placa]"},
+		{"tarjeta", "pago con 4111 1111 1111 1111", "pago con [tarjeta]"},
+		{"tarjeta que no pasa luhn", "codigo 4111 1111 1111 1112", "codigo 4111 1111 1111 1112"},
+		{"monto que parece placa", "tengo usd500", "tengo usd500"},
+		{"sin datos", "hola, quiero ofertar", "hola, quiero ofertar"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Redact(c.text); got != c.want {
+				t.Errorf("Redact(%q) = %q, se esperaba %q", c.text, got, c.want)
+			}
+		})
+	}
+}
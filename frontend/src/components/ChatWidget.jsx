@@ -14,6 +14,7 @@ function ChatWidget() {
   const [sessionId, setSessionId] = useState(null)
   const [leadScore, setLeadScore] = useState(0)
   const [category, setCategory] = useState('cold')
+  const [humanMode, setHumanMode] = useState(false)
   const messagesEndRef = useRef(null)
 
   const scrollToBottom = () => {
@@ -24,6 +25,33 @@ function ChatWidget() {
     scrollToBottom()
   }, [messages])
 
+  // En modo humano las respuestas del especialista llegan por el historial
+  useEffect(() => {
+    if (!humanMode || !sessionId) return
+
+    const interval = setInterval(async () => {
+      try {
+        const response = await fetch(`/api/chat/history/${sessionId}`)
+        const data = await response.json()
+        if (!data.success) return
+
+        setHumanMode(Boolean(data.session?.handoff?.active))
+        setMessages(prev => [
+          prev[0],
+          ...data.messages.map(msg => ({
+            role: msg.role === 'specialist' ? 'assistant' : msg.role,
+            content: msg.content,
+            timestamp: msg.timestamp
+          }))
+        ])
+      } catch (error) {
+        console.error('Error fetching history:', error)
+      }
+    }, 5000)
+
+    return () => clearInterval(interval)
+  }, [humanMode, sessionId])
+
   const sendMessage = async () => {
     if (!inputMessage.trim() || isLoading) return
 
@@ -64,6 +92,10 @@ function ChatWidget() {
         setLeadScore(data.leadScore || 0)
         setCategory(data.category || 'cold')
 
+        // Con un especialista a cargo no hay respuesta inmediata
+        setHumanMode(Boolean(data.humanMode))
+        if (data.humanMode) return
+
         // Agregar respuesta del asistente
         const assistantMessage = {
           role: 'assistant',
@@ -71,6 +103,14 @@ function ChatWidget() {
           timestamp: data.timestamp
         }
         setMessages(prev => [...prev, assistantMessage])
+      } else if (response.status === 429) {
+        // Límite de mensajes: el backend indica cuánto esperar
+        setMessages(prev => [...prev, {
+          role: 'assistant',
+          content: data.reply,
+          timestamp: new Date().toISOString(),
+          isError: true
+        }])
       } else {
         throw new Error(data.error || 'Error desconocido')
       }
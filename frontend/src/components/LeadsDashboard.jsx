@@ -6,8 +6,13 @@ function LeadsDashboard() {
   const [stats, setStats] = useState(null)
   const [isLoading, setIsLoading] = useState(true)
   const [filter, setFilter] = useState('all')
+  const [token, setToken] = useState(localStorage.getItem('bobToken') || '')
+  const [needsLogin, setNeedsLogin] = useState(false)
+  const [credentials, setCredentials] = useState({ username: '', password: '' })
+  const [loginError, setLoginError] = useState('')
 
   useEffect(() => {
+    if (needsLogin) return
     fetchLeads()
     fetchStats()
     const interval = setInterval(() => {
@@ -16,12 +21,50 @@ function LeadsDashboard() {
     }, 5000) // Actualizar cada 5 segundos
 
     return () => clearInterval(interval)
-  }, [filter])
+  }, [filter, token, needsLogin])
+
+  // Con AUTH_ENABLED=true el backend pide el JWT del login; un 401 vuelve al formulario
+  const authFetch = async (url) => {
+    const headers = token ? { Authorization: `Bearer ${token}` } : {}
+    const response = await fetch(url, { headers })
+    if (response.status === 401) {
+      localStorage.removeItem('bobToken')
+      setToken('')
+      setNeedsLogin(true)
+      setIsLoading(false)
+    }
+    return response
+  }
+
+  const login = async (e) => {
+    e.preventDefault()
+    setLoginError('')
+    try {
+      const response = await fetch('/api/auth/login', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(credentials)
+      })
+      const data = await response.json()
+
+      if (data.success) {
+        localStorage.setItem('bobToken', data.token)
+        setToken(data.token)
+        setCredentials({ username: '', password: '' })
+        setNeedsLogin(false)
+        setIsLoading(true)
+      } else {
+        setLoginError(data.error)
+      }
+    } catch (error) {
+      setLoginError('No se pudo conectar con el servidor')
+    }
+  }
 
   const fetchLeads = async () => {
     try {
       const queryParams = filter !== 'all' ? `?category=${filter}` : ''
-      const response = await fetch(`/api/leads${queryParams}`)
+      const response = await authFetch(`/api/leads${queryParams}`)
       const data = await response.json()
 
       if (data.success) {
@@ -36,7 +79,7 @@ function LeadsDashboard() {
 
   const fetchStats = async () => {
     try {
-      const response = await fetch('/api/leads/stats')
+      const response = await authFetch('/api/leads/stats')
       const data = await response.json()
 
       if (data.success) {
@@ -73,6 +116,28 @@ function LeadsDashboard() {
     }
   }
 
+  if (needsLogin) {
+    return (
+      <form className="login-form" onSubmit={login}>
+        <h3>Ingresar al dashboard</h3>
+        <input
+          type="text"
+          placeholder="Usuario"
+          value={credentials.username}
+          onChange={(e) => setCredentials({ ...credentials, username: e.target.value })}
+        />
+        <input
+          type="password"
+          placeholder="Contraseña"
+          value={credentials.password}
+          onChange={(e) => setCredentials({ ...credentials, password: e.target.value })}
+        />
+        {loginError && <div className="login-error">{loginError}</div>}
+        <button type="submit" className="filter-btn active">Ingresar</button>
+      </form>
+    )
+  }
+
   if (isLoading) {
     return <div className="loading">Cargando leads...</div>
   }
@@ -158,6 +223,18 @@ function LeadsDashboard() {
                     {lead.category.toUpperCase()}
                   </span>
                 </div>
+                <div className="detail-item">
+                  <span className="detail-label">Etapa:</span>
+                  <span className="detail-value">{lead.stage || 'greeting'}</span>
+                </div>
+                <div className="detail-item">
+                  <span className="detail-label">Estado:</span>
+                  <span className="detail-value">{lead.status || 'new'}</span>
+                </div>
+                <div className="detail-item">
+                  <span className="detail-label">Asignado:</span>
+                  <span className="detail-value">{lead.assignedTo || 'Sin asignar'}</span>
+                </div>
                 <div className="detail-item">
                   <span className="detail-label">Urgencia:</span>
                   <span className="detail-value">{lead.urgency || 'unknown'}</span>